@@ -0,0 +1,30 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdmin lets requests through that carry token as a bearer token.
+// Without a token, admin operations are disabled.
+func requireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			writeProblem(c, newProblem(c, NewError(http.StatusForbidden, "admin operations are disabled")))
+			c.Abort()
+			return
+		}
+
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			writeProblem(c, newProblem(c, NewError(http.StatusUnauthorized, "missing or invalid admin token")))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
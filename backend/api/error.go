@@ -1,12 +1,17 @@
 package api
 
+// Error is returned by handlers to produce a non-500 response. It is rendered
+// as a problem details object, see writeProblem.
 type Error struct {
-	Status int    `json:"status"`
-	Err    string `json:"error"`
+	Type   string
+	Status int
+	Err    string
+	Errors []FieldError
 }
 
 func NewError(status int, err string) Error {
 	return Error{
+		Type:   problemTypeDefault,
 		Status: status,
 		Err:    err,
 	}
@@ -1,7 +1,7 @@
 package api
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 
 	"languagequiz/quiz/exercise"
@@ -21,11 +21,13 @@ func mapExerciseToDTO(e exercise.Exercise) (any, error) {
 }
 
 type exerciseDTOBase struct {
+	ID   string `json:"id"`
 	Type string `json:"type"`
 }
 
-func newExerciseDTOBase(exerciseType string) exerciseDTOBase {
+func newExerciseDTOBase(id, exerciseType string) exerciseDTOBase {
 	return exerciseDTOBase{
+		ID:   id,
 		Type: exerciseType,
 	}
 }
@@ -38,7 +40,7 @@ type multipleChoiceExerciseDTO struct {
 
 func newMultipleChoiceExerciseDTO(id, question string, choices []string) multipleChoiceExerciseDTO {
 	return multipleChoiceExerciseDTO{
-		exerciseDTOBase: newExerciseDTOBase(exercise.TypeMultipleChoice),
+		exerciseDTOBase: newExerciseDTOBase(id, exercise.TypeMultipleChoice),
 		Question:        question,
 		Choices:         choices,
 	}
@@ -55,7 +57,7 @@ type fillInTheBlankExerciseDTO struct {
 
 func newFillInTheBlankExerciseDTO(id, question string) fillInTheBlankExerciseDTO {
 	return fillInTheBlankExerciseDTO{
-		exerciseDTOBase: newExerciseDTOBase(exercise.TypeFillInTheBlank),
+		exerciseDTOBase: newExerciseDTOBase(id, exercise.TypeFillInTheBlank),
 		Question:        question,
 	}
 }
@@ -71,7 +73,7 @@ type sentenceCorrectionExerciseDTO struct {
 
 func newSentenceCorrectionExerciseDTO(id, sentence string) sentenceCorrectionExerciseDTO {
 	return sentenceCorrectionExerciseDTO{
-		exerciseDTOBase: newExerciseDTOBase(exercise.TypeSentenceCorrection),
+		exerciseDTOBase: newExerciseDTOBase(id, exercise.TypeSentenceCorrection),
 		Sentence:        sentence,
 	}
 }
@@ -80,6 +82,43 @@ func mapSentenceCorrectionExerciseToDTO(e exercise.SentenceCorrectionExercise) s
 	return newSentenceCorrectionExerciseDTO(e.ID, e.Sentence)
 }
 
+type createExerciseRequest interface {
+	validate(pointer string) []FieldError
+	toCommand() (exercise.CreateExerciseCommand, error)
+}
+
+func decodeCreateExerciseRequest(raw json.RawMessage) (createExerciseRequest, error) {
+	var base createExerciseRequestBase
+	if err := json.Unmarshal(raw, &base); err != nil {
+		return nil, fmt.Errorf("failed to decode exercise: %w", err)
+	}
+
+	var req createExerciseRequest
+	switch base.Type {
+	case exercise.TypeMultipleChoice:
+		req = &createMultipleChoiceExerciseRequest{}
+	case exercise.TypeFillInTheBlank:
+		req = &createFillInTheBlankExerciseRequest{}
+	case exercise.TypeSentenceCorrection:
+		req = &createSentenceCorrectionExerciseRequest{}
+	default:
+		return nil, errUnsupportedExerciseType{Type: base.Type}
+	}
+
+	if err := json.Unmarshal(raw, req); err != nil {
+		return nil, fmt.Errorf("failed to decode exercise: %w", err)
+	}
+	return req, nil
+}
+
+type errUnsupportedExerciseType struct {
+	Type string
+}
+
+func (e errUnsupportedExerciseType) Error() string {
+	return fmt.Sprintf("unsupported exercise type: %q", e.Type)
+}
+
 type createExerciseRequestBase struct {
 	Type     string  `json:"type"`
 	Feedback *string `json:"feedback"`
@@ -92,26 +131,31 @@ type createMultipleChoiceExerciseRequest struct {
 	Answer   string   `json:"answer"`
 }
 
-func (r *createMultipleChoiceExerciseRequest) toCommand() (*exercise.CreateMultipleChoiceExerciseCommand, error) {
-	return exercise.NewCreateMultipleChoiceExerciseCommand(
+func (r *createMultipleChoiceExerciseRequest) toCommand() (exercise.CreateExerciseCommand, error) {
+	cmd, err := exercise.NewCreateMultipleChoiceExerciseCommand(
 		r.Question,
 		r.Choices,
 		r.Answer,
 		r.Feedback,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
 }
 
-func (r *createMultipleChoiceExerciseRequest) Validate() error {
+func (r *createMultipleChoiceExerciseRequest) validate(ptr string) []FieldError {
+	errs := make([]FieldError, 0)
 	if r.Question == "" {
-		return errors.New("required field is missing: question")
+		errs = append(errs, newRequiredFieldError(pointer(ptr, "question")))
 	}
 	if r.Choices == nil {
-		return errors.New("required field is missing: choices")
+		errs = append(errs, newRequiredFieldError(pointer(ptr, "choices")))
 	}
 	if r.Answer == "" {
-		return errors.New("required field is missing: answer")
+		errs = append(errs, newRequiredFieldError(pointer(ptr, "answer")))
 	}
-	return nil
+	return errs
 }
 
 type createFillInTheBlankExerciseRequest struct {
@@ -120,22 +164,27 @@ type createFillInTheBlankExerciseRequest struct {
 	Answer   string `json:"answer"`
 }
 
-func (r *createFillInTheBlankExerciseRequest) toCommand() (*exercise.CreateFillInTheBlankExerciseCommand, error) {
-	return exercise.NewCreateFillInTheBlankExerciseCommand(
+func (r *createFillInTheBlankExerciseRequest) toCommand() (exercise.CreateExerciseCommand, error) {
+	cmd, err := exercise.NewCreateFillInTheBlankExerciseCommand(
 		r.Question,
 		r.Answer,
 		r.Feedback,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
 }
 
-func (r *createFillInTheBlankExerciseRequest) Validate() error {
+func (r *createFillInTheBlankExerciseRequest) validate(ptr string) []FieldError {
+	errs := make([]FieldError, 0)
 	if r.Question == "" {
-		return errors.New("required field is missing: question")
+		errs = append(errs, newRequiredFieldError(pointer(ptr, "question")))
 	}
 	if r.Answer == "" {
-		return errors.New("required field is missing: answer")
+		errs = append(errs, newRequiredFieldError(pointer(ptr, "answer")))
 	}
-	return nil
+	return errs
 }
 
 type createSentenceCorrectionExerciseRequest struct {
@@ -144,20 +193,25 @@ type createSentenceCorrectionExerciseRequest struct {
 	CorrectedSentence string `json:"correctedSentence"`
 }
 
-func (r *createSentenceCorrectionExerciseRequest) toCommand() (*exercise.CreateSentenceCorrectionExerciseCommand, error) {
-	return exercise.NewCreateSentenceCorrectionExerciseCommand(
+func (r *createSentenceCorrectionExerciseRequest) toCommand() (exercise.CreateExerciseCommand, error) {
+	cmd, err := exercise.NewCreateSentenceCorrectionExerciseCommand(
 		r.Sentence,
 		r.CorrectedSentence,
 		r.Feedback,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
 }
 
-func (r *createSentenceCorrectionExerciseRequest) Validate() error {
+func (r *createSentenceCorrectionExerciseRequest) validate(ptr string) []FieldError {
+	errs := make([]FieldError, 0)
 	if r.Sentence == "" {
-		return errors.New("required field is missing: sentence")
+		errs = append(errs, newRequiredFieldError(pointer(ptr, "sentence")))
 	}
 	if r.CorrectedSentence == "" {
-		return errors.New("required field is missing: correctedSentence")
+		errs = append(errs, newRequiredFieldError(pointer(ptr, "correctedSentence")))
 	}
-	return nil
+	return errs
 }
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"languagequiz/quiz/markdown"
+	"languagequiz/quiz/moodle"
+	"languagequiz/quiz/portable"
+	"languagequiz/quiz/qti"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mimePlainText = "text/plain; charset=utf-8"
+	mimeZip       = "application/zip"
+)
+
+// ExportQuiz serves a quiz as a portable JSON or YAML document, a Moodle GIFT
+// or Aiken file, or a Markdown quiz document. The format is taken from the
+// format query parameter, or else negotiated from Accept. Drafts are not
+// found, as with GetQuiz.
+func (h *QuizHandler) ExportQuiz(c *gin.Context) error {
+	format := c.Query("format")
+	if format == "" {
+		format = "json"
+		if c.NegotiateFormat(gin.MIMEJSON, mimeYAML) == mimeYAML {
+			format = "yaml"
+		}
+	}
+
+	quiz, err := h.findPublicQuiz(c, c.Param("id"))
+	if err != nil {
+		return quizError(fmt.Errorf("failed to find quiz: %w", err))
+	}
+
+	var b bytes.Buffer
+	var contentType, extension string
+	switch format {
+	case "json", "yaml":
+		portableFormat, _ := portable.ParseFormat(format)
+		doc, err := portable.Export(*quiz)
+		if err != nil {
+			return fmt.Errorf("failed to export quiz: %w", err)
+		}
+		if err := portable.Encode(&b, *doc, portableFormat); err != nil {
+			return fmt.Errorf("failed to encode quiz: %w", err)
+		}
+		contentType, extension = gin.MIMEJSON, format
+		if portableFormat == portable.FormatYAML {
+			contentType = mimeYAML
+		}
+	case "gift":
+		if err := moodle.WriteGIFT(&b, *quiz); err != nil {
+			return fmt.Errorf("failed to write gift: %w", err)
+		}
+		contentType, extension = mimePlainText, "gift.txt"
+	case "aiken":
+		if err := moodle.WriteAiken(&b, *quiz); err != nil {
+			if errors.Is(err, moodle.ErrUnsupportedExercise) {
+				return NewError(http.StatusUnprocessableEntity, err.Error())
+			}
+			return fmt.Errorf("failed to write aiken: %w", err)
+		}
+		contentType, extension = mimePlainText, "aiken.txt"
+	case "markdown":
+		if err := markdown.Render(&b, *quiz); err != nil {
+			return fmt.Errorf("failed to render markdown: %w", err)
+		}
+		contentType, extension = mimeMarkdown+"; charset=utf-8", "md"
+	default:
+		return NewError(http.StatusBadRequest, fmt.Sprintf("unsupported format: %q", format))
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="quiz-%s.%s"`, quiz.ID, extension))
+	c.Data(http.StatusOK, contentType, b.Bytes())
+	return nil
+}
+
+// ExportQuizQTI serves a quiz as a zipped IMS QTI content package. The QTI
+// version is taken from the version query parameter and defaults to 2.1.
+func (h *QuizHandler) ExportQuizQTI(c *gin.Context) error {
+	version, err := qti.ParseVersion(c.DefaultQuery("version", string(qti.Version21)))
+	if err != nil {
+		return NewError(http.StatusBadRequest, err.Error())
+	}
+
+	quiz, err := h.findPublicQuiz(c, c.Param("id"))
+	if err != nil {
+		return quizError(fmt.Errorf("failed to find quiz: %w", err))
+	}
+
+	var b bytes.Buffer
+	if err := qti.Export(&b, *quiz, version); err != nil {
+		return fmt.Errorf("failed to export quiz as qti: %w", err)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="quiz-%s-qti.zip"`, quiz.ID))
+	c.Data(http.StatusOK, mimeZip, b.Bytes())
+	return nil
+}
@@ -1,39 +1,81 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"languagequiz/feedback"
+	"languagequiz/metrics"
+	"languagequiz/pow"
 
-	"github.com/bwmarrin/discordgo"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const maxFeedbackTextLength = 5000
+
 type FeedbackHandler struct {
-	DiscordBotToken          string
-	DiscordFeedbackChannelID string
+	storage feedback.Storage
+	// notifiers is nil when feedback is not sent anywhere.
+	notifiers *feedback.Fanout
+	// pow is nil when anonymous feedback needs no proof of work.
+	pow *pow.Verifier
 }
 
-func NewFeedbackHandler(discordBotToken, discordFeedbackChannelID string) *FeedbackHandler {
-	return &FeedbackHandler{
-		DiscordBotToken:          discordBotToken,
-		DiscordFeedbackChannelID: discordFeedbackChannelID,
-	}
+func NewFeedbackHandler(storage feedback.Storage, notifiers *feedback.Fanout, verifier *pow.Verifier) *FeedbackHandler {
+	return &FeedbackHandler{storage: storage, notifiers: notifiers, pow: verifier}
 }
 
 type submitFeedbackRequest struct {
 	Text     string `json:"text"`
 	PagePath string `json:"pagePath"`
+	// UserID identifies the user when they are signed in or left a contact.
+	UserID     *string `json:"userId,omitempty"`
+	QuizID     *string `json:"quizId,omitempty"`
+	ExerciseID *string `json:"exerciseId,omitempty"`
+	// Website is a honeypot: forms hide it from people, so only bots fill it
+	// in.
+	Website string `json:"website,omitempty"`
+	// ProofOfWork is required when the server asks for it, see
+	// /v1/feedback/challenge. A user ID does not spare it, as anyone can send
+	// one.
+	ProofOfWork *proofOfWork `json:"proofOfWork,omitempty"`
+}
+
+type proofOfWork struct {
+	Challenge string `json:"challenge"`
+	Nonce     string `json:"nonce"`
 }
 
-func (r *submitFeedbackRequest) validate() error {
+func (r *submitFeedbackRequest) validate() []FieldError {
+	errs := make([]FieldError, 0)
 	if r.Text == "" {
-		return errors.New("field 'text' is missing")
+		errs = append(errs, newRequiredFieldError("/text"))
+	} else if utf8.RuneCountInString(r.Text) > maxFeedbackTextLength {
+		errs = append(errs, newFieldError("/text", validationCodeInvalid,
+			fmt.Sprintf("must be at most %d characters", maxFeedbackTextLength)))
 	}
 	if r.PagePath == "" {
-		return errors.New("field 'pagePath' is missing")
+		errs = append(errs, newRequiredFieldError("/pagePath"))
 	}
-	return nil
+	if r.QuizID != nil {
+		if _, err := uuid.Parse(*r.QuizID); err != nil {
+			errs = append(errs, newFieldError("/quizId", validationCodeInvalid, "must be a UUID"))
+		}
+	}
+	if r.ExerciseID != nil {
+		if _, err := uuid.Parse(*r.ExerciseID); err != nil {
+			errs = append(errs, newFieldError("/exerciseId", validationCodeInvalid, "must be a UUID"))
+		}
+	}
+	return errs
 }
 
 func (h *FeedbackHandler) SubmitFeedback(c *gin.Context) error {
@@ -43,21 +85,97 @@ func (h *FeedbackHandler) SubmitFeedback(c *gin.Context) error {
 		return fmt.Errorf("failed to decode request body: %w", err)
 	}
 
-	if err := req.validate(); err != nil {
-		return NewError(http.StatusBadRequest, err.Error())
+	if errs := req.validate(); len(errs) > 0 {
+		return NewValidationError(errs)
+	}
+
+	// Bots are told that their feedback was received, so that they do not
+	// learn what gave them away.
+	if req.Website != "" {
+		metrics.FeedbackRejected("honeypot")
+		slog.InfoContext(c.Request.Context(), "dropped feedback caught by the honeypot")
+		return nil
+	}
+	if h.pow != nil {
+		if err := h.verifyProofOfWork(req.ProofOfWork); err != nil {
+			metrics.FeedbackRejected("proofOfWork")
+			return err
+		}
 	}
 
-	discord, err := discordgo.New("Bot " + h.DiscordBotToken)
+	// Feedback is stored together with a message for each notifier in the
+	// outbox, so it is sent in the background and kept even when every
+	// notifier fails.
+	cmd := feedback.CreateFeedbackCommand{
+		Text:       req.Text,
+		PagePath:   req.PagePath,
+		UserAgent:  c.Request.UserAgent(),
+		UserID:     req.UserID,
+		QuizID:     req.QuizID,
+		ExerciseID: req.ExerciseID,
+	}
+	if h.notifiers != nil {
+		cmd.Notifiers = h.notifiers.Names()
+	}
+	fb, err := h.storage.CreateFeedback(c.Request.Context(), cmd)
+	if errors.Is(err, feedback.ErrUnknownReference) {
+		return NewError(http.StatusBadRequest, err.Error())
+	}
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to store feedback: %w", err)
 	}
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("feedback.id", fb.ID))
+	metrics.FeedbackSubmitted()
+	return nil
+}
 
-	msg := "Received feedback\nText: " + req.Text + "\nPage: " + req.PagePath
+func (h *FeedbackHandler) verifyProofOfWork(proof *proofOfWork) error {
+	if proof == nil {
+		return NewValidationError([]FieldError{newRequiredFieldError("/proofOfWork")})
+	}
+	if err := h.pow.Verify(proof.Challenge, proof.Nonce); err != nil {
+		return NewValidationError([]FieldError{newFieldError("/proofOfWork", validationCodeInvalid, err.Error())})
+	}
+	return nil
+}
 
-	_, err = discord.ChannelMessageSend(h.DiscordFeedbackChannelID, msg)
+type challengeResponse struct {
+	// Required is false when anonymous feedback needs no proof of work.
+	Required   bool       `json:"required"`
+	Challenge  string     `json:"challenge,omitempty"`
+	Difficulty int        `json:"difficulty,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+// GetChallenge hands out a proof of work challenge. A client solves it by
+// finding a nonce such that the SHA-256 hash of challenge:nonce starts with
+// difficulty zero bits.
+func (h *FeedbackHandler) GetChallenge(c *gin.Context) error {
+	if h.pow == nil {
+		c.JSON(http.StatusOK, challengeResponse{})
+		return nil
+	}
+
+	challenge, err := h.pow.NewChallenge()
 	if err != nil {
 		return err
 	}
-
+	c.JSON(http.StatusOK, challengeResponse{
+		Required:   true,
+		Challenge:  challenge.Value,
+		Difficulty: challenge.Difficulty,
+		ExpiresAt:  &challenge.ExpiresAt,
+	})
 	return nil
 }
+
+// FeedbackCheck reports whether feedback can be delivered, as far as the
+// notifiers can tell. Feedback is optional, so the check is not critical.
+func (h *FeedbackHandler) FeedbackCheck() HealthCheck {
+	return HealthCheck{Name: "feedback", Check: func(ctx context.Context) error {
+		if h.notifiers == nil {
+			return ErrCheckDisabled
+		}
+		return h.notifiers.Check(ctx)
+	}}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"languagequiz/feedback"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+	maxTagLength     = 50
+)
+
+type FeedbackDTO struct {
+	ID         string     `json:"id"`
+	Text       string     `json:"text"`
+	PagePath   string     `json:"pagePath"`
+	UserAgent  string     `json:"userAgent"`
+	UserID     *string    `json:"userId"`
+	QuizID     *string    `json:"quizId"`
+	ExerciseID *string    `json:"exerciseId"`
+	Status     string     `json:"status" enum:"open,resolved"`
+	Tags       []string   `json:"tags"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ResolvedAt *time.Time `json:"resolvedAt"`
+}
+
+func newFeedbackDTO(fb feedback.Feedback) FeedbackDTO {
+	tags := fb.Tags
+	if tags == nil {
+		tags = make([]string, 0)
+	}
+	return FeedbackDTO{
+		ID:         fb.ID,
+		Text:       fb.Text,
+		PagePath:   fb.PagePath,
+		UserAgent:  fb.UserAgent,
+		UserID:     fb.UserID,
+		QuizID:     fb.QuizID,
+		ExerciseID: fb.ExerciseID,
+		Status:     string(fb.Status),
+		Tags:       tags,
+		CreatedAt:  fb.ReceivedAt,
+		ResolvedAt: fb.ResolvedAt,
+	}
+}
+
+// ListFeedback lists feedback newest first, filtered by the query parameters.
+func (h *FeedbackHandler) ListFeedback(c *gin.Context) error {
+	filter := feedback.Filter{
+		Status:     feedback.Status(c.Query("status")),
+		Tag:        c.Query("tag"),
+		QuizID:     c.Query("quizId"),
+		ExerciseID: c.Query("exerciseId"),
+		PagePath:   c.Query("pagePath"),
+	}
+	if filter.Status != "" && filter.Status != feedback.StatusOpen && filter.Status != feedback.StatusResolved {
+		return NewError(http.StatusBadRequest, fmt.Sprintf("status must be %s or %s", feedback.StatusOpen, feedback.StatusResolved))
+	}
+	var err error
+	filter.Limit, filter.Offset, err = parsePage(c)
+	if err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(filter.QuizID); filter.QuizID != "" && err != nil {
+		return NewError(http.StatusBadRequest, "quizId must be a UUID")
+	}
+	if _, err := uuid.Parse(filter.ExerciseID); filter.ExerciseID != "" && err != nil {
+		return NewError(http.StatusBadRequest, "exerciseId must be a UUID")
+	}
+
+	items, err := h.storage.FindFeedback(c.Request.Context(), filter)
+	if err != nil {
+		return fmt.Errorf("failed to find feedback: %w", err)
+	}
+
+	dtos := make([]FeedbackDTO, 0, len(items))
+	for _, fb := range items {
+		dtos = append(dtos, newFeedbackDTO(fb))
+	}
+	c.JSON(http.StatusOK, dtos)
+	return nil
+}
+
+// parsePage parses the limit and offset query parameters of a list.
+func parsePage(c *gin.Context) (limit, offset int, err error) {
+	limit = defaultPageLimit
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, NewError(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, NewError(http.StatusBadRequest, "offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+func (h *FeedbackHandler) GetFeedbackByID(c *gin.Context) error {
+	fb, err := h.storage.FindFeedbackByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return feedbackError(err)
+	}
+	c.JSON(http.StatusOK, newFeedbackDTO(*fb))
+	return nil
+}
+
+type tagFeedbackRequest struct {
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+func (r *tagFeedbackRequest) validate() []FieldError {
+	errs := make([]FieldError, 0)
+	if len(r.Add) == 0 && len(r.Remove) == 0 {
+		errs = append(errs, newFieldError("", validationCodeEmpty, "add or remove at least one tag"))
+	}
+	errs = append(errs, validateTags("/add", r.Add)...)
+	errs = append(errs, validateTags("/remove", r.Remove)...)
+	return errs
+}
+
+func validateTags(ptr string, tags []string) []FieldError {
+	errs := make([]FieldError, 0)
+	for i, tag := range tags {
+		if tag == "" || len(tag) > maxTagLength || strings.TrimSpace(tag) != tag {
+			errs = append(errs, newFieldError(pointer(ptr, i), validationCodeInvalid,
+				fmt.Sprintf("tags must be 1 to %d characters without surrounding spaces", maxTagLength)))
+		}
+	}
+	return errs
+}
+
+// TagFeedback adds and removes tags.
+func (h *FeedbackHandler) TagFeedback(c *gin.Context) error {
+	var req tagFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return fmt.Errorf("failed to decode request body: %w", err)
+	}
+	if errs := req.validate(); len(errs) > 0 {
+		return NewValidationError(errs)
+	}
+
+	add, remove := req.Add, req.Remove
+	if add == nil {
+		add = make([]string, 0)
+	}
+	if remove == nil {
+		remove = make([]string, 0)
+	}
+	fb, err := h.storage.UpdateTags(c.Request.Context(), c.Param("id"), add, remove)
+	if err != nil {
+		return feedbackError(err)
+	}
+	c.JSON(http.StatusOK, newFeedbackDTO(*fb))
+	return nil
+}
+
+// ResolveFeedback marks feedback as resolved.
+func (h *FeedbackHandler) ResolveFeedback(c *gin.Context) error {
+	return h.setStatus(c, feedback.StatusResolved)
+}
+
+// ReopenFeedback marks resolved feedback as open again.
+func (h *FeedbackHandler) ReopenFeedback(c *gin.Context) error {
+	return h.setStatus(c, feedback.StatusOpen)
+}
+
+func (h *FeedbackHandler) setStatus(c *gin.Context, status feedback.Status) error {
+	fb, err := h.storage.SetStatus(c.Request.Context(), c.Param("id"), status)
+	if err != nil {
+		return feedbackError(err)
+	}
+	c.JSON(http.StatusOK, newFeedbackDTO(*fb))
+	return nil
+}
+
+func feedbackError(err error) error {
+	if errors.Is(err, feedback.ErrNotFound) {
+		return NewError(http.StatusNotFound, err.Error())
+	}
+	return err
+}
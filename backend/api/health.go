@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+const (
+	checkStatusOK       = "ok"
+	checkStatusFailing  = "failing"
+	checkStatusDisabled = "disabled"
+)
+
+// ErrCheckDisabled is returned by a health check whose dependency is not
+// configured.
+var ErrCheckDisabled = errors.New("check disabled")
+
+// HealthCheck checks a dependency of the service. The service is not ready
+// while a critical check fails; other checks are only reported.
+type HealthCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	checks []HealthCheck
+}
+
+func NewHealthHandler(checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks}
+}
+
+// PingCheck fails when the database does not answer a ping.
+func PingCheck(name string, db interface{ Ping(context.Context) error }) HealthCheck {
+	return HealthCheck{Name: name, Critical: true, Check: db.Ping}
+}
+
+// MigrationCheck fails when the database schema is dirty or not at the latest
+// migration. current reads the applied version.
+func MigrationCheck(current func(ctx context.Context) (version uint, dirty bool, err error), latest uint) HealthCheck {
+	return HealthCheck{
+		Name:     "migrations",
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			version, dirty, err := current(ctx)
+			if err != nil {
+				return err
+			}
+			if dirty {
+				return fmt.Errorf("migration %d is dirty", version)
+			}
+			if version != latest {
+				return fmt.Errorf("schema is at version %d, expected %d", version, latest)
+			}
+			return nil
+		},
+	}
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+type checkResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// GetHealth reports that the process is alive. It does not look at any
+// dependency, so a failing database does not get the process restarted.
+func (h *HealthHandler) GetHealth(c *gin.Context) error {
+	c.JSON(http.StatusOK, healthResponse{Status: checkStatusOK})
+	return nil
+}
+
+// GetReadiness runs all checks concurrently and responds with 503 when a
+// critical check fails.
+func (h *HealthHandler) GetReadiness(c *gin.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+
+	results := make([]checkResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	resp := readinessResponse{
+		Status: checkStatusOK,
+		Checks: make(map[string]checkResult, len(h.checks)),
+	}
+	status := http.StatusOK
+	for i, check := range h.checks {
+		resp.Checks[check.Name] = results[i]
+		if check.Critical && results[i].Status == checkStatusFailing {
+			resp.Status = checkStatusFailing
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	c.JSON(status, resp)
+	return nil
+}
+
+func runCheck(ctx context.Context, check HealthCheck) checkResult {
+	err := check.Check(ctx)
+	switch {
+	case err == nil:
+		return checkResult{Status: checkStatusOK, Critical: check.Critical}
+	case errors.Is(err, ErrCheckDisabled):
+		return checkResult{Status: checkStatusDisabled, Critical: check.Critical}
+	default:
+		return checkResult{Status: checkStatusFailing, Critical: check.Critical, Error: err.Error()}
+	}
+}
+
+type versionResponse struct {
+	Module    string `json:"module"`
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified"`
+}
+
+// GetVersion reports the build of the running binary.
+func (h *HealthHandler) GetVersion(c *gin.Context) error {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return errors.New("build info is not available")
+	}
+
+	resp := versionResponse{
+		Module:    info.Main.Path,
+		Version:   info.Main.Version,
+		GoVersion: info.GoVersion,
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			resp.Revision = setting.Value
+		case "vcs.time":
+			resp.Time = setting.Value
+		case "vcs.modified":
+			resp.Modified = setting.Value == "true"
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+	return nil
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"languagequiz/metrics"
+	"languagequiz/quiz"
+	"languagequiz/quiz/anki"
+	"languagequiz/quiz/csvimport"
+	"languagequiz/quiz/markdown"
+	"languagequiz/quiz/moodle"
+	"languagequiz/quiz/portable"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+const (
+	mimeCSV      = "text/csv"
+	mimeTSV      = "text/tab-separated-values"
+	mimeYAML     = "application/yaml"
+	mimeAPKG     = "application/apkg"
+	mimeMarkdown = "text/markdown"
+)
+
+// ImportQuiz creates a quiz from the file sent as the request body. The
+// format query parameter selects the format, or else the content type does:
+// a portable JSON or YAML document, a CSV or TSV spreadsheet, a Moodle GIFT
+// or Aiken file, an Anki deck package, or a Markdown quiz document. The quiz
+// is published like one created with CreateQuiz.
+func (h *QuizHandler) ImportQuiz(c *gin.Context) error {
+	format := c.Query("format")
+	if format == "" {
+		switch c.ContentType() {
+		case gin.MIMEJSON:
+			format = "json"
+		case mimeYAML, "application/x-yaml", "text/yaml":
+			format = "yaml"
+		case mimeCSV:
+			format = "csv"
+		case mimeTSV:
+			format = "tsv"
+		case mimeAPKG:
+			format = "apkg"
+		case mimeMarkdown:
+			format = "markdown"
+		default:
+			return NewError(http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported content type: %q", c.ContentType()))
+		}
+	}
+
+	var cmd *quiz.CreateQuizCommand
+	var err error
+	switch format {
+	case "json":
+		cmd, err = importPortable(c, portable.FormatJSON)
+	case "yaml":
+		cmd, err = importPortable(c, portable.FormatYAML)
+	case "csv", "tsv":
+		cmd, err = importCSV(c, format)
+	case "gift", "aiken":
+		cmd, err = importMoodle(c, format)
+	case "apkg":
+		cmd, err = importAnki(c)
+	case "markdown":
+		cmd, err = importMarkdown(c)
+	default:
+		return NewError(http.StatusBadRequest, fmt.Sprintf("unsupported format: %q", format))
+	}
+	if err != nil {
+		return err
+	}
+
+	quiz, err := h.quizStorage.CreateQuiz(c.Request.Context(), *cmd, quiz.StatusPublished)
+	if err != nil {
+		return quizError(fmt.Errorf("failed to create quiz: %w", err))
+	}
+	metrics.QuizCreated(format)
+
+	dto, err := mapToQuizDTO(*quiz)
+	if err != nil {
+		return fmt.Errorf("failed to map quiz to dto: %w", err)
+	}
+
+	c.JSON(http.StatusCreated, *dto)
+	return nil
+}
+
+func importPortable(c *gin.Context, format portable.Format) (*quiz.CreateQuizCommand, error) {
+	doc, err := portable.Decode(c.Request.Body, format)
+	if err != nil {
+		return nil, NewError(http.StatusBadRequest, err.Error())
+	}
+
+	cmd, err := doc.ToCommand()
+	if err != nil {
+		var docErrs portable.Errors
+		if errors.As(err, &docErrs) {
+			fieldErrors := make([]FieldError, 0)
+			for _, docErr := range docErrs {
+				fieldErrors = append(fieldErrors, newFieldError(docErr.Pointer, validationCodeInvalid, docErr.Err.Error()))
+			}
+			return nil, NewValidationError(fieldErrors)
+		}
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// parseImportTarget reads the name and language of the quiz to create from
+// the query, for formats that do not carry them.
+func parseImportTarget(c *gin.Context) (string, language.Tag, error) {
+	name := c.Query("name")
+	if name == "" {
+		return "", language.Tag{}, NewError(http.StatusBadRequest, "query parameter 'name' is missing")
+	}
+	languageTag, err := language.Parse(c.Query("languageTag"))
+	if err != nil {
+		return "", language.Tag{}, NewError(http.StatusBadRequest, fmt.Sprintf("query parameter 'languageTag' is invalid: %s", err))
+	}
+	return name, languageTag, nil
+}
+
+func importCSV(c *gin.Context, format string) (*quiz.CreateQuizCommand, error) {
+	name, languageTag, err := parseImportTarget(c)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := csvimport.ParseColumnMapping(c.Query("columns"))
+	if err != nil {
+		return nil, NewError(http.StatusBadRequest, err.Error())
+	}
+
+	opts := csvimport.NewOptions(name, languageTag, columns)
+	if format == "tsv" {
+		opts.Comma = '\t'
+	} else {
+		opts.Comma = ','
+	}
+	if section := c.Query("defaultSection"); section != "" {
+		opts.DefaultSection = section
+	}
+
+	cmd, err := csvimport.Import(c.Request.Body, opts)
+	if err != nil {
+		var rowErrs csvimport.Errors
+		if errors.As(err, &rowErrs) {
+			return nil, NewValidationError(mapRowErrorsToFieldErrors(rowErrs))
+		}
+		return nil, NewError(http.StatusBadRequest, err.Error())
+	}
+	return cmd, nil
+}
+
+func mapRowErrorsToFieldErrors(rowErrs csvimport.Errors) []FieldError {
+	fieldErrors := make([]FieldError, 0)
+	for _, rowErr := range rowErrs {
+		ptr := pointer("/rows", rowErr.Row)
+		if rowErr.Column != "" {
+			ptr = pointer(ptr, rowErr.Column)
+		}
+		fieldErrors = append(fieldErrors, newFieldError(ptr, validationCodeInvalid, rowErr.Err.Error()))
+	}
+	return fieldErrors
+}
+
+func importMoodle(c *gin.Context, format string) (*quiz.CreateQuizCommand, error) {
+	name, languageTag, err := parseImportTarget(c)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := moodle.NewOptions(name, languageTag)
+	if section := c.Query("defaultSection"); section != "" {
+		opts.DefaultSection = section
+	}
+
+	var cmd *quiz.CreateQuizCommand
+	if format == "gift" {
+		var warnings []moodle.Issue
+		cmd, warnings, err = moodle.ParseGIFT(c.Request.Body, opts)
+		for _, warning := range warnings {
+			c.Writer.Header().Add("Warning", fmt.Sprintf("299 - %q", warning.String()))
+		}
+	} else {
+		cmd, err = moodle.ParseAiken(c.Request.Body, opts)
+	}
+	if err != nil {
+		var issues moodle.Errors
+		if errors.As(err, &issues) {
+			fieldErrors := make([]FieldError, 0)
+			for _, issue := range issues {
+				fieldErrors = append(fieldErrors, newFieldError(pointer("/lines", issue.Line), validationCodeInvalid, issue.Message))
+			}
+			return nil, NewValidationError(fieldErrors)
+		}
+		return nil, NewError(http.StatusBadRequest, err.Error())
+	}
+	return cmd, nil
+}
+
+func importAnki(c *gin.Context) (*quiz.CreateQuizCommand, error) {
+	name, languageTag, err := parseImportTarget(c)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := anki.NewOptions(name, languageTag)
+	if mode := c.Query("mode"); mode != "" {
+		opts.Mode = mode
+	}
+	opts.FrontField = c.Query("frontField")
+	opts.BackField = c.Query("backField")
+	opts.SkipInvalid = c.Query("skipInvalid") == "true"
+	if seed := c.Query("seed"); seed != "" {
+		opts.Seed, err = strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			return nil, NewError(http.StatusBadRequest, "query parameter 'seed' is not an integer")
+		}
+	}
+
+	// The package is a zip file, which needs random access.
+	file, err := os.CreateTemp("", "import-*.apkg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	size, err := io.Copy(file, c.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	cmd, warnings, err := anki.Import(file, size, opts)
+	for _, warning := range warnings {
+		c.Writer.Header().Add("Warning", fmt.Sprintf("299 - %q", warning.String()))
+	}
+	if err != nil {
+		var issues anki.Errors
+		if errors.As(err, &issues) {
+			fieldErrors := make([]FieldError, 0)
+			for _, issue := range issues {
+				fieldErrors = append(fieldErrors, newFieldError(pointer("/notes", strconv.FormatInt(issue.NoteID, 10)), validationCodeInvalid, issue.Message))
+			}
+			return nil, NewValidationError(fieldErrors)
+		}
+		if errors.Is(err, anki.ErrCollectionTooLarge) {
+			return nil, NewError(http.StatusRequestEntityTooLarge, err.Error())
+		}
+		return nil, NewError(http.StatusBadRequest, err.Error())
+	}
+	return cmd, nil
+}
+
+// importMarkdown reads a Markdown quiz document. The name and language in the
+// document take precedence over the query.
+func importMarkdown(c *gin.Context) (*quiz.CreateQuizCommand, error) {
+	opts := markdown.Options{Name: c.Query("name")}
+	if tag := c.Query("languageTag"); tag != "" {
+		languageTag, err := language.Parse(tag)
+		if err != nil {
+			return nil, NewError(http.StatusBadRequest, fmt.Sprintf("query parameter 'languageTag' is invalid: %s", err))
+		}
+		opts.LanguageTag = languageTag
+	}
+
+	cmd, err := markdown.Parse(c.Request.Body, opts)
+	if err != nil {
+		var issues markdown.Errors
+		if errors.As(err, &issues) {
+			fieldErrors := make([]FieldError, 0)
+			for _, issue := range issues {
+				fieldErrors = append(fieldErrors, newFieldError(pointer("/lines", issue.Line), validationCodeInvalid, issue.Message))
+			}
+			return nil, NewValidationError(fieldErrors)
+		}
+		return nil, NewError(http.StatusBadRequest, err.Error())
+	}
+	return cmd, nil
+}
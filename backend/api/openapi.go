@@ -0,0 +1,445 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"languagequiz/quiz/exercise"
+
+	"github.com/gin-gonic/gin"
+)
+
+const openAPIVersion = "3.1.0"
+
+var pathParamRegex = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// route couples a Gin route to its OpenAPI operation so that the document
+// served at /v1/openapi.json is generated from the same table that registers
+// the routes.
+type route struct {
+	method    string
+	path      string
+	handler   func(c *gin.Context) error
+	operation *operation
+}
+
+type operation struct {
+	ID              string
+	Summary         string
+	QueryParameters []queryParameter
+	RequestBodies   map[string]content
+	Responses       map[int]map[string]content
+	// Upload lets the request body grow to the upload size limit instead of
+	// the body size limit.
+	Upload bool
+	// Admin requires the admin token.
+	Admin bool
+	// MaxBodyBytes lowers the body size limit of the operation when it is
+	// not zero.
+	MaxBodyBytes int64
+	// RateLimit names the rate limit rule of the operation, if it has one.
+	RateLimit string
+}
+
+type queryParameter struct {
+	Name        string
+	Required    bool
+	Description string
+}
+
+// content is either a Go value whose type describes the body, or an explicit
+// schema.
+type content struct {
+	Body   any
+	Schema *jsonSchema
+}
+
+func newOperation(id, summary string) *operation {
+	return &operation{
+		ID:            id,
+		Summary:       summary,
+		RequestBodies: make(map[string]content),
+		Responses:     make(map[int]map[string]content),
+	}
+}
+
+func (o *operation) withQueryParameter(name string, required bool, description string) *operation {
+	o.QueryParameters = append(o.QueryParameters, queryParameter{Name: name, Required: required, Description: description})
+	return o
+}
+
+// withRequestBody documents a JSON request body shaped like body.
+func (o *operation) withRequestBody(body any) *operation {
+	o.RequestBodies[gin.MIMEJSON] = content{Body: body}
+	return o
+}
+
+// withRequestContent documents a non-JSON request body.
+func (o *operation) withRequestContent(contentType string, schema *jsonSchema) *operation {
+	o.RequestBodies[contentType] = content{Schema: schema}
+	return o
+}
+
+// withUpload marks the request body as an uploaded file.
+func (o *operation) withUpload() *operation {
+	o.Upload = true
+	return o
+}
+
+// withMaxBody limits the request body to maxBytes.
+func (o *operation) withMaxBody(maxBytes int64) *operation {
+	o.MaxBodyBytes = maxBytes
+	return o
+}
+
+// withRateLimit limits how often a client may call the operation by the
+// rule with name.
+func (o *operation) withRateLimit(rule string) *operation {
+	o.RateLimit = rule
+	return o
+}
+
+// withAdmin restricts the operation to callers with the admin token.
+func (o *operation) withAdmin() *operation {
+	o.Admin = true
+	return o
+}
+
+// withResponse documents a JSON response shaped like body. A nil body
+// documents a response without content.
+func (o *operation) withResponse(status int, body any) *operation {
+	if o.Responses[status] == nil {
+		o.Responses[status] = make(map[string]content)
+	}
+	if body != nil {
+		o.Responses[status][gin.MIMEJSON] = content{Body: body}
+	}
+	return o
+}
+
+// withContent documents a non-JSON response.
+func (o *operation) withContent(status int, contentType string, schema *jsonSchema) *operation {
+	if o.Responses[status] == nil {
+		o.Responses[status] = make(map[string]content)
+	}
+	o.Responses[status][contentType] = content{Schema: schema}
+	return o
+}
+
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 any                    `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Const                any                    `json:"const,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	OneOf                []*jsonSchema          `json:"oneOf,omitempty"`
+	Discriminator        *discriminator         `json:"discriminator,omitempty"`
+}
+
+type discriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping"`
+}
+
+// polymorphicFieldSchema returns the schema of struct fields whose Go type
+// ([]any, json.RawMessage) does not describe their content.
+func (g *schemaGenerator) polymorphicFieldSchema(t reflect.Type, name string) (*jsonSchema, bool) {
+	switch {
+	case t == reflect.TypeOf(QuizSectionDTO{}) && name == "exercises":
+		return arrayOf(g.discriminated("type", map[string]any{
+			exercise.TypeMultipleChoice:     multipleChoiceExerciseDTO{},
+			exercise.TypeFillInTheBlank:     fillInTheBlankExerciseDTO{},
+			exercise.TypeSentenceCorrection: sentenceCorrectionExerciseDTO{},
+		})), true
+	case t == reflect.TypeOf(createQuizSectionRequest{}) && name == "exercises":
+		return arrayOf(g.discriminated("type", map[string]any{
+			exercise.TypeMultipleChoice:     createMultipleChoiceExerciseRequest{},
+			exercise.TypeFillInTheBlank:     createFillInTheBlankExerciseRequest{},
+			exercise.TypeSentenceCorrection: createSentenceCorrectionExerciseRequest{},
+		})), true
+	case t == reflect.TypeOf(submitAnswersRequest{}) && name == "userAnswers":
+		return arrayOf(&jsonSchema{Type: "string"}), true
+	case t == reflect.TypeOf(submitAnswerResult{}) && name == "answer":
+		return &jsonSchema{Type: "string"}, true
+	default:
+		return nil, false
+	}
+}
+
+type schemaGenerator struct {
+	components map[string]*jsonSchema
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{components: make(map[string]*jsonSchema)}
+}
+
+func arrayOf(items *jsonSchema) *jsonSchema {
+	return &jsonSchema{Type: "array", Items: items}
+}
+
+func componentRef(name string) string {
+	return "#/components/schemas/" + name
+}
+
+func componentName(t reflect.Type) string {
+	name := t.Name()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func (g *schemaGenerator) schemaOf(v any) *jsonSchema {
+	return g.schemaFor(reflect.TypeOf(v))
+}
+
+// discriminated returns a oneOf schema over the given variants, each of which
+// gets its discriminator property pinned to its key.
+func (g *schemaGenerator) discriminated(propertyName string, variants map[string]any) *jsonSchema {
+	values := make([]string, 0)
+	for value := range variants {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	schema := &jsonSchema{Discriminator: &discriminator{PropertyName: propertyName, Mapping: make(map[string]string)}}
+	for _, value := range values {
+		t := reflect.TypeOf(variants[value])
+		ref := g.schemaFor(t)
+		g.components[componentName(t)].Properties[propertyName] = &jsonSchema{Type: "string", Const: value}
+		schema.OneOf = append(schema.OneOf, ref)
+		schema.Discriminator.Mapping[value] = ref.Ref
+	}
+	return schema
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) *jsonSchema {
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	case reflect.TypeOf(json.RawMessage{}):
+		return &jsonSchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := g.schemaFor(t.Elem())
+		if typ, ok := schema.Type.(string); ok {
+			schema.Type = []string{typ, "null"}
+		}
+		return schema
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return arrayOf(g.schemaFor(t.Elem()))
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		name := componentName(t)
+		if _, ok := g.components[name]; !ok {
+			schema := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema)}
+			g.components[name] = schema
+			g.addProperties(schema, t)
+		}
+		return &jsonSchema{Ref: componentRef(name)}
+	default:
+		return &jsonSchema{}
+	}
+}
+
+func (g *schemaGenerator) addProperties(schema *jsonSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			g.addProperties(schema, field.Type)
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" || tag == "" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if override, ok := g.polymorphicFieldSchema(t, name); ok {
+			schema.Properties[name] = override
+		} else {
+			schema.Properties[name] = g.schemaFor(field.Type)
+		}
+		// An enum tag lists the values a string field may take.
+		if enum := field.Tag.Get("enum"); enum != "" {
+			schema.Properties[name].Enum = strings.Split(enum, ",")
+		}
+
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       openAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components openAPIComponents                      `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required"`
+	Schema      *jsonSchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*jsonSchema           `json:"schemas"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type openAPISecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+const adminSecurityScheme = "adminToken"
+
+func (g *schemaGenerator) mediaTypes(contents map[string]content) map[string]openAPIMediaType {
+	mediaTypes := make(map[string]openAPIMediaType)
+	for contentType, c := range contents {
+		schema := c.Schema
+		if c.Body != nil {
+			schema = g.schemaOf(c.Body)
+		}
+		mediaTypes[contentType] = openAPIMediaType{Schema: schema}
+	}
+	return mediaTypes
+}
+
+func toOpenAPIPath(ginPath string) string {
+	return pathParamRegex.ReplaceAllString(ginPath, "{$1}")
+}
+
+func newOpenAPIDocument(routes []route) openAPIDocument {
+	g := newSchemaGenerator()
+	problemSchema := g.schemaOf(Problem{})
+
+	paths := make(map[string]map[string]openAPIOperation)
+	for _, route := range routes {
+		op := route.operation
+
+		parameters := make([]openAPIParameter, 0)
+		for _, match := range pathParamRegex.FindAllStringSubmatch(route.path, -1) {
+			parameters = append(parameters, openAPIParameter{
+				Name:     match[1],
+				In:       "path",
+				Required: true,
+				Schema:   &jsonSchema{Type: "string"},
+			})
+		}
+
+		for _, param := range op.QueryParameters {
+			parameters = append(parameters, openAPIParameter{
+				Name:        param.Name,
+				In:          "query",
+				Description: param.Description,
+				Required:    param.Required,
+				Schema:      &jsonSchema{Type: "string"},
+			})
+		}
+
+		var requestBody *openAPIRequestBody
+		if len(op.RequestBodies) > 0 {
+			requestBody = &openAPIRequestBody{Required: true, Content: g.mediaTypes(op.RequestBodies)}
+		}
+
+		responses := make(map[string]openAPIResponse)
+		for status, contents := range op.Responses {
+			r := openAPIResponse{Description: http.StatusText(status)}
+			if len(contents) > 0 {
+				r.Content = g.mediaTypes(contents)
+			}
+			responses[fmt.Sprint(status)] = r
+		}
+		responses["default"] = openAPIResponse{
+			Description: "Error",
+			Content: map[string]openAPIMediaType{
+				problemContentType: {Schema: problemSchema},
+				gin.MIMEJSON:       {Schema: g.schemaOf(legacyError{})},
+			},
+		}
+
+		path := toOpenAPIPath(route.path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]openAPIOperation)
+		}
+		var security []map[string][]string
+		if op.Admin {
+			security = []map[string][]string{{adminSecurityScheme: {}}}
+		}
+
+		paths[path][strings.ToLower(route.method)] = openAPIOperation{
+			OperationID: op.ID,
+			Summary:     op.Summary,
+			Parameters:  parameters,
+			RequestBody: requestBody,
+			Responses:   responses,
+			Security:    security,
+		}
+	}
+
+	return openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    openAPIInfo{Title: "languagequiz", Version: "1"},
+		Paths:   paths,
+		Components: openAPIComponents{
+			Schemas: g.components,
+			SecuritySchemes: map[string]openAPISecurityScheme{
+				adminSecurityScheme: {Type: "http", Scheme: "bearer"},
+			},
+		},
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestOpenAPIDocument compares the generated document with the committed one,
+// so that changes to routes and DTOs show up in review. Run the tests with
+// -update after such a change.
+func TestOpenAPIDocument(t *testing.T) {
+	s := NewServer(NewHandlers(nil, nil, nil, nil), ServerOptions{})
+	got, err := json.MarshalIndent(s.openAPI, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal document: %v", err)
+	}
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "openapi.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", golden, err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", golden, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("openapi document differs from %s; run go test ./api -update and review the diff", golden)
+	}
+}
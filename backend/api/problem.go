@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	problemContentType = "application/problem+json"
+
+	problemTypeDefault        = "about:blank"
+	problemTypeValidation     = "/problems/validation-error"
+	problemTypeNotPublishable = "/problems/not-publishable"
+)
+
+var problemTitles = map[string]string{
+	problemTypeValidation:     "Your request is not valid.",
+	problemTypeNotPublishable: "The quiz is not ready to be published.",
+}
+
+// Problem is an RFC 7807 problem details object. RequestID and Errors are
+// extension members.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+func newProblem(c *gin.Context, err Error) Problem {
+	title, ok := problemTitles[err.Type]
+	if !ok {
+		title = http.StatusText(err.Status)
+	}
+	return Problem{
+		Type:      err.Type,
+		Title:     title,
+		Status:    err.Status,
+		Detail:    err.Err,
+		Instance:  c.Request.URL.Path,
+		RequestID: getRequestID(c),
+		Errors:    err.Errors,
+	}
+}
+
+// legacyError is the error body served to clients that do not ask for
+// application/problem+json.
+type legacyError struct {
+	Status    int          `json:"status"`
+	Err       string       `json:"error"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+func newLegacyError(p Problem) legacyError {
+	err := p.Detail
+	if err == "" {
+		err = p.Title
+	}
+	return legacyError{
+		Status:    p.Status,
+		Err:       err,
+		RequestID: p.RequestID,
+		Errors:    p.Errors,
+	}
+}
+
+func writeProblem(c *gin.Context, p Problem) {
+	switch c.NegotiateFormat(gin.MIMEJSON, problemContentType) {
+	case problemContentType:
+		c.Render(p.Status, problemRender{problem: p})
+	default:
+		c.JSON(p.Status, newLegacyError(p))
+	}
+	c.Abort()
+}
+
+type problemRender struct {
+	problem Problem
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.problem)
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", problemContentType)
+}
@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"languagequiz/metrics"
 	"languagequiz/quiz"
 	"languagequiz/quiz/exercise"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,12 +24,13 @@ func NewQuizHandler(quizStorage quiz.Storage) *QuizHandler {
 	return &QuizHandler{quizStorage: quizStorage}
 }
 
+// GetQuizByID serves a published or archived quiz. Drafts are not found.
 func (h *QuizHandler) GetQuizByID(c *gin.Context) error {
 	id := c.Param("id")
 
-	quiz, err := h.quizStorage.FindByID(id)
+	quiz, err := h.findPublicQuiz(c, id)
 	if err != nil {
-		return fmt.Errorf("failed to find quiz: %w", err)
+		return quizError(fmt.Errorf("failed to find quiz: %w", err))
 	}
 
 	dto, err := mapToQuizDTO(*quiz)
@@ -38,8 +42,26 @@ func (h *QuizHandler) GetQuizByID(c *gin.Context) error {
 	return nil
 }
 
+// findPublicQuiz finds the quiz with id unless it is a draft. Drafts are
+// reported as not found, so that their IDs cannot be probed.
+func (h *QuizHandler) findPublicQuiz(c *gin.Context, id string) (*quiz.Quiz, error) {
+	q, err := h.quizStorage.FindByID(c.Request.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if !q.Status.Public() {
+		return nil, quiz.ErrNotFound
+	}
+	return q, nil
+}
+
+// GetQuizzes lists the published quizzes.
 func (h *QuizHandler) GetQuizzes(c *gin.Context) error {
-	quizzes, err := h.quizStorage.FindQuizzes()
+	return h.writeQuizzes(c, quiz.Filter{Status: quiz.StatusPublished})
+}
+
+func (h *QuizHandler) writeQuizzes(c *gin.Context, filter quiz.Filter) error {
+	quizzes, err := h.quizStorage.FindQuizzes(c.Request.Context(), filter)
 	if err != nil {
 		return fmt.Errorf("failed to find quizzes: %w", err)
 	}
@@ -53,6 +75,9 @@ func (h *QuizHandler) GetQuizzes(c *gin.Context) error {
 	return nil
 }
 
+// CreateQuiz creates a quiz and publishes it right away: authors cannot edit
+// or publish drafts through the api yet, so a draft would be lost to them.
+// Drafts are created with the command line.
 func (h *QuizHandler) CreateQuiz(c *gin.Context) error {
 	var req createQuizRequest
 	err := c.ShouldBindJSON(&req)
@@ -60,8 +85,8 @@ func (h *QuizHandler) CreateQuiz(c *gin.Context) error {
 		return fmt.Errorf("failed to decode request body: %w", err)
 	}
 
-	if err := req.validate(); err != nil {
-		return NewError(http.StatusBadRequest, err.Error())
+	if errs := req.validate(); len(errs) > 0 {
+		return NewValidationError(errs)
 	}
 
 	cmd, err := req.toCommand()
@@ -69,10 +94,11 @@ func (h *QuizHandler) CreateQuiz(c *gin.Context) error {
 		return err
 	}
 
-	quiz, err := h.quizStorage.CreateQuiz(*cmd)
+	quiz, err := h.quizStorage.CreateQuiz(c.Request.Context(), *cmd, quiz.StatusPublished)
 	if err != nil {
-		return fmt.Errorf("failed to create quiz: %w", err)
+		return quizError(fmt.Errorf("failed to create quiz: %w", err))
 	}
+	metrics.QuizCreated("api")
 
 	dto, err := mapToQuizDTO(*quiz)
 	if err != nil {
@@ -83,31 +109,84 @@ func (h *QuizHandler) CreateQuiz(c *gin.Context) error {
 	return nil
 }
 
+// CloneQuiz copies a quiz, with the sections and exercises it shows to
+// learners, and publishes the copy like CreateQuiz.
+func (h *QuizHandler) CloneQuiz(c *gin.Context) error {
+	var req cloneQuizRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to decode request body: %w", err)
+	}
+
+	if errs := req.validate(); len(errs) > 0 {
+		return NewValidationError(errs)
+	}
+
+	cmd := quiz.CloneQuizCommand{QuizID: c.Param("id"), Name: req.Name}
+	if req.LanguageTag != "" {
+		cmd.LanguageTag = language.MustParse(req.LanguageTag)
+	}
+
+	q, err := h.quizStorage.CloneQuiz(c.Request.Context(), cmd, quiz.StatusPublished)
+	if err != nil {
+		return quizError(fmt.Errorf("failed to clone quiz: %w", err))
+	}
+	metrics.QuizCreated("clone")
+
+	dto, err := mapToQuizDTO(*q)
+	if err != nil {
+		return fmt.Errorf("failed to map quiz to dto: %w", err)
+	}
+
+	c.JSON(http.StatusCreated, *dto)
+	return nil
+}
+
+// cloneQuizRequest overrides the name and the language tag of a copy. The
+// body may be left out to keep both.
+type cloneQuizRequest struct {
+	Name        string `json:"name,omitempty"`
+	LanguageTag string `json:"languageTag,omitempty"`
+}
+
+func (r *cloneQuizRequest) validate() []FieldError {
+	errs := make([]FieldError, 0)
+	if r.Name != "" && strings.TrimSpace(r.Name) == "" {
+		errs = append(errs, newEmptyFieldError("/name"))
+	}
+	if r.LanguageTag != "" {
+		if _, err := language.Parse(r.LanguageTag); err != nil {
+			errs = append(errs, newFieldError("/languageTag", validationCodeInvalid, err.Error()))
+		}
+	}
+	return errs
+}
+
 type createQuizRequest struct {
 	Name        string                     `json:"name"`
 	LanguageTag string                     `json:"languageTag"`
 	Sections    []createQuizSectionRequest `json:"sections"`
 }
 
-func (r *createQuizRequest) validate() error {
+func (r *createQuizRequest) validate() []FieldError {
+	errs := make([]FieldError, 0)
 	if r.Name == "" {
-		return errors.New("field 'name' is missing")
+		errs = append(errs, newRequiredFieldError("/name"))
 	}
 	if r.LanguageTag == "" {
-		return errors.New("field 'languageTag' is missing")
+		errs = append(errs, newRequiredFieldError("/languageTag"))
+	} else if _, err := language.Parse(r.LanguageTag); err != nil {
+		errs = append(errs, newFieldError("/languageTag", validationCodeInvalid, err.Error()))
 	}
 	if r.Sections == nil {
-		return errors.New("field 'sections' is missing")
+		errs = append(errs, newRequiredFieldError("/sections"))
+	} else if len(r.Sections) == 0 {
+		errs = append(errs, newEmptyFieldError("/sections"))
 	}
-	if len(r.Sections) == 0 {
-		return errors.New("field 'sections' is empty")
-	}
-	for _, createSectionRequest := range r.Sections {
-		if err := createSectionRequest.validate(); err != nil {
-			return fmt.Errorf("section validation error: %w", err)
-		}
+	for i, createSectionRequest := range r.Sections {
+		errs = append(errs, createSectionRequest.validate(pointer("/sections", i))...)
 	}
-	return nil
+	return errs
 }
 
 func (r *createQuizRequest) toCommand() (*quiz.CreateQuizCommand, error) {
@@ -134,106 +213,70 @@ type createQuizSectionRequest struct {
 	Exercises []json.RawMessage `json:"exercises"`
 }
 
-func (r *createQuizSectionRequest) validate() error {
+func (r *createQuizSectionRequest) validate(ptr string) []FieldError {
+	errs := make([]FieldError, 0)
 	if r.Name == "" {
-		return errors.New("field 'name' is missing")
+		errs = append(errs, newRequiredFieldError(pointer(ptr, "name")))
 	}
 	if r.Exercises == nil {
-		return errors.New("field 'exercises' is missing")
-	}
-	if len(r.Exercises) == 0 {
-		return errors.New("field 'exercises' is empty")
+		errs = append(errs, newRequiredFieldError(pointer(ptr, "exercises")))
+	} else if len(r.Exercises) == 0 {
+		errs = append(errs, newEmptyFieldError(pointer(ptr, "exercises")))
 	}
-	for _, createExerciseRequestRaw := range r.Exercises {
-		var createExerciseRequestJson map[string]any
-		if err := json.Unmarshal(createExerciseRequestRaw, &createExerciseRequestJson); err != nil {
-			return fmt.Errorf("failed to decode exercise: %w", err)
-		}
 
-		switch createExerciseRequestJson["type"] {
-		case exercise.TypeMultipleChoice:
-			var createExerciseRequest createMultipleChoiceExerciseRequest
-			if err := json.Unmarshal(createExerciseRequestRaw, &createExerciseRequest); err != nil {
-				return fmt.Errorf("failed to decode exercise: %w", err)
-			}
+	createExerciseCommands := make([]exercise.CreateExerciseCommand, 0)
+	for i, createExerciseRequestRaw := range r.Exercises {
+		exercisePtr := pointer(ptr, "exercises", i)
 
-			if err := createExerciseRequest.Validate(); err != nil {
-				return fmt.Errorf("exercise validation error: %w", err)
-			}
-		case exercise.TypeFillInTheBlank:
-			var createExerciseRequest createFillInTheBlankExerciseRequest
-			if err := json.Unmarshal(createExerciseRequestRaw, &createExerciseRequest); err != nil {
-				return fmt.Errorf("failed to decode exercise: %w", err)
+		createExerciseRequest, err := decodeCreateExerciseRequest(createExerciseRequestRaw)
+		if err != nil {
+			var unsupportedTypeErr errUnsupportedExerciseType
+			if errors.As(err, &unsupportedTypeErr) {
+				errs = append(errs, newFieldError(pointer(exercisePtr, "type"), validationCodeUnsupported, err.Error()))
+			} else {
+				errs = append(errs, newFieldError(exercisePtr, validationCodeInvalid, err.Error()))
 			}
+			continue
+		}
 
-			if err := createExerciseRequest.Validate(); err != nil {
-				return fmt.Errorf("exercise validation error: %w", err)
-			}
-		case exercise.TypeSentenceCorrection:
-			var createExerciseRequest createSentenceCorrectionExerciseRequest
-			if err := json.Unmarshal(createExerciseRequestRaw, &createExerciseRequest); err != nil {
-				return fmt.Errorf("failed to decode exercise: %w", err)
-			}
+		if exerciseErrs := createExerciseRequest.validate(exercisePtr); len(exerciseErrs) > 0 {
+			errs = append(errs, exerciseErrs...)
+			continue
+		}
 
-			if err := createExerciseRequest.Validate(); err != nil {
-				return fmt.Errorf("exercise validation error: %w", err)
+		createExerciseCommand, err := createExerciseRequest.toCommand()
+		if err != nil {
+			var fieldErr exercise.FieldError
+			if errors.As(err, &fieldErr) {
+				errs = append(errs, newFieldError(pointer(exercisePtr, fieldErr.Field), validationCodeInvalid, err.Error()))
+			} else {
+				errs = append(errs, newFieldError(exercisePtr, validationCodeInvalid, err.Error()))
 			}
-		default:
-			return fmt.Errorf("unsupported exercise type: %v", createExerciseRequestJson["type"])
+			continue
 		}
+		createExerciseCommands = append(createExerciseCommands, createExerciseCommand)
+	}
 
+	if _, err := quiz.NewCreateSectionCommand(r.Name, createExerciseCommands); err != nil {
+		errs = append(errs, newFieldError(pointer(ptr, "exercises"), validationCodeInvalid, err.Error()))
 	}
-	return nil
+	return errs
 }
 
 func (r *createQuizSectionRequest) toCommand() (*quiz.CreateSectionCommand, error) {
 	createExerciseCommands := make([]exercise.CreateExerciseCommand, 0)
 	for _, createExerciseRequestRaw := range r.Exercises {
-		var createExerciseRequestJson map[string]any
-		if err := json.Unmarshal(createExerciseRequestRaw, &createExerciseRequestJson); err != nil {
-			return nil, fmt.Errorf("failed to decode exercise: %w", err)
+		createExerciseRequest, err := decodeCreateExerciseRequest(createExerciseRequestRaw)
+		if err != nil {
+			return nil, NewError(http.StatusBadRequest, err.Error())
 		}
 
-		switch createExerciseRequestJson["type"] {
-		case exercise.TypeMultipleChoice:
-			var createExerciseRequest createMultipleChoiceExerciseRequest
-			if err := json.Unmarshal(createExerciseRequestRaw, &createExerciseRequest); err != nil {
-				return nil, fmt.Errorf("failed to decode exercise: %w", err)
-			}
-
-			createExerciseCommand, err := createExerciseRequest.toCommand()
-			if err != nil {
-				return nil, NewError(http.StatusBadRequest, err.Error())
-			}
-
-			createExerciseCommands = append(createExerciseCommands, createExerciseCommand)
-		case exercise.TypeFillInTheBlank:
-			var createExerciseRequest createFillInTheBlankExerciseRequest
-			if err := json.Unmarshal(createExerciseRequestRaw, &createExerciseRequest); err != nil {
-				return nil, fmt.Errorf("failed to decode exercise: %w", err)
-			}
-
-			createExerciseCommand, err := createExerciseRequest.toCommand()
-			if err != nil {
-				return nil, NewError(http.StatusBadRequest, err.Error())
-			}
-
-			createExerciseCommands = append(createExerciseCommands, createExerciseCommand)
-		case exercise.TypeSentenceCorrection:
-			var createExerciseRequest createSentenceCorrectionExerciseRequest
-			if err := json.Unmarshal(createExerciseRequestRaw, &createExerciseRequest); err != nil {
-				return nil, fmt.Errorf("failed to decode exercise: %w", err)
-			}
-
-			createExerciseCommand, err := createExerciseRequest.toCommand()
-			if err != nil {
-				return nil, NewError(http.StatusBadRequest, err.Error())
-			}
-
-			createExerciseCommands = append(createExerciseCommands, createExerciseCommand)
-		default:
-			return nil, NewError(http.StatusBadRequest, fmt.Sprintf("unsupported exercise type: %v", createExerciseRequestJson["type"]))
+		createExerciseCommand, err := createExerciseRequest.toCommand()
+		if err != nil {
+			return nil, NewError(http.StatusBadRequest, err.Error())
 		}
+
+		createExerciseCommands = append(createExerciseCommands, createExerciseCommand)
 	}
 
 	createSectionCommand, err := quiz.NewCreateSectionCommand(r.Name, createExerciseCommands)
@@ -248,7 +291,11 @@ func mapToQuizDTO(q quiz.Quiz) (*QuizDTO, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to map quiz sections to dtos: %w", err)
 	}
-	quizDTO := newQuizDTO(q.ID, q.CreatedAt, q.Name, q.LanguageTag.String(), quizSectionDTOs)
+	var forkedFrom *string
+	if q.ForkedFrom != "" {
+		forkedFrom = &q.ForkedFrom
+	}
+	quizDTO := newQuizDTO(q.ID, q.CreatedAt, q.Name, q.LanguageTag.String(), string(q.Status), q.Revision, forkedFrom, quizSectionDTOs)
 	return &quizDTO, nil
 }
 
@@ -288,20 +335,30 @@ func mapToExerciseDTOs(exercises []exercise.Exercise) ([]any, error) {
 	return dtos, nil
 }
 
+// QuizDTO is a quiz as it is shown to learners. Revision is the number of the
+// revision the sections come from, or 0 for a draft; answers are graded
+// against it when it is sent back. ForkedFrom is the ID of the quiz this one
+// was cloned from.
 type QuizDTO struct {
 	ID          string           `json:"id"`
 	CreatedAt   time.Time        `json:"createdAt"`
 	Name        string           `json:"name"`
 	LanguageTag string           `json:"languageTag"`
+	Status      string           `json:"status" enum:"draft,published,archived"`
+	Revision    int              `json:"revision"`
+	ForkedFrom  *string          `json:"forkedFrom"`
 	Sections    []QuizSectionDTO `json:"sections"`
 }
 
-func newQuizDTO(id string, createdAt time.Time, name, languageTag string, sections []QuizSectionDTO) QuizDTO {
+func newQuizDTO(id string, createdAt time.Time, name, languageTag, status string, revision int, forkedFrom *string, sections []QuizSectionDTO) QuizDTO {
 	return QuizDTO{
 		ID:          id,
 		CreatedAt:   createdAt,
 		Name:        name,
 		LanguageTag: languageTag,
+		Status:      status,
+		Revision:    revision,
+		ForkedFrom:  forkedFrom,
 		Sections:    sections,
 	}
 }
@@ -320,22 +377,32 @@ func newQuizSectionDTO(name string, exercises []any) QuizSectionDTO {
 
 type submitAnswersRequest struct {
 	UserAnswers []any `json:"userAnswers"`
+	// Revision is the revision of the quiz the answers were given to. The
+	// current revision is used when it is omitted.
+	Revision int `json:"revision,omitempty"`
 }
 
 func (r *submitAnswersRequest) validate() error {
 	if r.UserAnswers == nil {
 		return errors.New("field 'userAnswers' is missing")
 	}
+	if r.Revision < 0 {
+		return errors.New("field 'revision' must be positive")
+	}
 	return nil
 }
 
 type submitAnswersResponse struct {
-	Results []submitAnswerResult `json:"results"`
+	AttemptID string               `json:"attemptId"`
+	Revision  int                  `json:"revision"`
+	Results   []submitAnswerResult `json:"results"`
 }
 
-func newSubmitAnswersResponse(results []submitAnswerResult) submitAnswersResponse {
+func newSubmitAnswersResponse(attemptID string, revision int, results []submitAnswerResult) submitAnswersResponse {
 	return submitAnswersResponse{
-		Results: results,
+		AttemptID: attemptID,
+		Revision:  revision,
+		Results:   results,
 	}
 }
 
@@ -366,19 +433,58 @@ func (h *QuizHandler) SubmitAnswers(c *gin.Context) error {
 		return NewError(http.StatusBadRequest, err.Error())
 	}
 
-	quiz, err := h.quizStorage.FindByID(id)
+	q, err := h.findGradedQuiz(c, id, req.Revision)
 	if err != nil {
-		return fmt.Errorf("failed to find quiz by id: %s, cause: %w", id, err)
+		return err
 	}
 
-	exercises := quiz.GetExercises()
+	exercises := q.GetExercises()
+	if len(req.UserAnswers) > len(exercises) {
+		return NewError(http.StatusBadRequest, fmt.Sprintf("quiz has %d exercises, found %d answers", len(exercises), len(req.UserAnswers)))
+	}
 	results := make([]submitAnswerResult, 0)
+	correctCount := 0
 	for i, userAnswer := range req.UserAnswers {
-		exercise := exercises[i]
-		correct := exercise.CheckAnswer(userAnswer)
-		results = append(results, newSubmitAnswerResult(correct, exercise.Answer(), exercise.Feedback()))
+		e := exercises[i]
+		correct := e.CheckAnswer(userAnswer)
+		if correct {
+			correctCount++
+		}
+		metrics.AnswerGraded(exercise.TypeOf(e), correct)
+		results = append(results, newSubmitAnswerResult(correct, e.Answer(), e.Feedback()))
 	}
+	metrics.SubmissionGraded(correctCount, len(results))
 
-	c.JSON(http.StatusOK, newSubmitAnswersResponse(results))
+	attempt, err := h.quizStorage.CreateAttempt(c.Request.Context(), quiz.CreateAttemptCommand{
+		QuizID:   q.ID,
+		Revision: q.Revision,
+		Correct:  correctCount,
+		Total:    len(results),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create attempt: %w", err)
+	}
+
+	c.JSON(http.StatusOK, newSubmitAnswersResponse(attempt.ID, attempt.Revision, results))
 	return nil
 }
+
+// findGradedQuiz returns the quiz that answers are graded against: the
+// revision the learner was shown when it is known, and the quiz as it is
+// served now otherwise. Drafts cannot be answered, not even their earlier
+// revisions.
+func (h *QuizHandler) findGradedQuiz(c *gin.Context, id string, revision int) (*quiz.Quiz, error) {
+	q, err := h.findPublicQuiz(c, id)
+	if err != nil {
+		return nil, quizError(fmt.Errorf("failed to find quiz by id: %s, cause: %w", id, err))
+	}
+	if revision == 0 {
+		return q, nil
+	}
+
+	r, err := h.quizStorage.FindRevision(c.Request.Context(), id, revision)
+	if err != nil {
+		return nil, quizError(fmt.Errorf("failed to find revision %d of quiz %s: %w", revision, id, err))
+	}
+	return &r.Quiz, nil
+}
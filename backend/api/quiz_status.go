@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"languagequiz/quiz"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAllQuizzes lists quizzes whatever their status, or those with the
+// status query parameter, for the people who look after them.
+func (h *QuizHandler) ListAllQuizzes(c *gin.Context) error {
+	filter := quiz.Filter{Status: quiz.Status(c.Query("status"))}
+	if filter.Status != "" && !filter.Status.Valid() {
+		return NewError(http.StatusBadRequest, fmt.Sprintf("status must be %s, %s or %s",
+			quiz.StatusDraft, quiz.StatusPublished, quiz.StatusArchived))
+	}
+	return h.writeQuizzes(c, filter)
+}
+
+// PublishQuiz lists a quiz, once it has sections and each of them has
+// exercises.
+func (h *QuizHandler) PublishQuiz(c *gin.Context) error {
+	return h.setStatus(c, quiz.StatusPublished)
+}
+
+// UnpublishQuiz turns a published or archived quiz back into a draft.
+func (h *QuizHandler) UnpublishQuiz(c *gin.Context) error {
+	return h.setStatus(c, quiz.StatusDraft)
+}
+
+// ArchiveQuiz retires a quiz. It is no longer listed but is kept, so that it
+// can still be opened by ID.
+func (h *QuizHandler) ArchiveQuiz(c *gin.Context) error {
+	return h.setStatus(c, quiz.StatusArchived)
+}
+
+func (h *QuizHandler) setStatus(c *gin.Context, status quiz.Status) error {
+	q, err := h.quizStorage.SetStatus(c.Request.Context(), c.Param("id"), status)
+	if err != nil {
+		return quizError(err)
+	}
+
+	dto, err := mapToQuizDTO(*q)
+	if err != nil {
+		return fmt.Errorf("failed to map quiz to dto: %w", err)
+	}
+
+	c.JSON(http.StatusOK, *dto)
+	return nil
+}
+
+func quizError(err error) error {
+	if errors.Is(err, quiz.ErrNotFound) {
+		return NewError(http.StatusNotFound, quiz.ErrNotFound.Error())
+	}
+	if errors.Is(err, quiz.ErrRevisionNotFound) {
+		return NewError(http.StatusNotFound, quiz.ErrRevisionNotFound.Error())
+	}
+	if errors.Is(err, quiz.ErrNotPublished) {
+		return NewError(http.StatusConflict, "only a published quiz can be rolled back")
+	}
+	var transitionErr *quiz.TransitionError
+	if errors.As(err, &transitionErr) {
+		return NewError(http.StatusConflict, transitionErr.Error())
+	}
+	var publishErr *quiz.PublishError
+	if errors.As(err, &publishErr) {
+		return newNotPublishableError(publishErr)
+	}
+	return err
+}
+
+// newNotPublishableError lists the problems of a quiz that cannot be
+// published. The pointers point into the quiz rather than the request.
+func newNotPublishableError(publishErr *quiz.PublishError) Error {
+	fieldErrors := make([]FieldError, 0, len(publishErr.Problems))
+	for _, p := range publishErr.Problems {
+		if p.Section < 0 {
+			fieldErrors = append(fieldErrors, newFieldError("/sections", validationCodeEmpty, p.Message))
+		} else {
+			fieldErrors = append(fieldErrors, newFieldError(pointer("/sections", p.Section, "exercises"), validationCodeEmpty, p.Message))
+		}
+	}
+	err := NewError(http.StatusUnprocessableEntity, publishErr.Error())
+	err.Type = problemTypeNotPublishable
+	err.Errors = fieldErrors
+	return err
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCreateQuizRequestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		// want are the pointer and code of each field error.
+		want [][2]string
+	}{
+		{
+			name: "valid",
+			body: `{"name": "Animals", "languageTag": "de", "sections": [{"name": "Pets", "exercises": [
+				{"type": "fillInTheBlank", "question": "Der ______ bellt.", "answer": "Hund"}
+			]}]}`,
+			want: [][2]string{},
+		},
+		{
+			name: "missing fields",
+			body: `{"sections": []}`,
+			want: [][2]string{{"/name", "required"}, {"/languageTag", "required"}, {"/sections", "empty"}},
+		},
+		{
+			name: "invalid language tag",
+			body: `{"name": "Animals", "languageTag": "not a tag", "sections": [{"name": "Pets", "exercises": [
+				{"type": "fillInTheBlank", "question": "Der ______ bellt.", "answer": "Hund"}
+			]}]}`,
+			want: [][2]string{{"/languageTag", "invalid"}},
+		},
+		{
+			name: "empty section",
+			body: `{"name": "Animals", "languageTag": "de", "sections": [{"exercises": []}]}`,
+			want: [][2]string{{"/sections/0/name", "required"}, {"/sections/0/exercises", "empty"}},
+		},
+		{
+			name: "unsupported type",
+			body: `{"name": "Animals", "languageTag": "de", "sections": [{"name": "Pets", "exercises": [
+				{"type": "essay"}
+			]}]}`,
+			want: [][2]string{{"/sections/0/exercises/0/type", "unsupported"}},
+		},
+		{
+			name: "missing exercise fields",
+			body: `{"name": "Animals", "languageTag": "de", "sections": [{"name": "Pets", "exercises": [
+				{"type": "multipleChoice"}
+			]}]}`,
+			want: [][2]string{
+				{"/sections/0/exercises/0/question", "required"},
+				{"/sections/0/exercises/0/choices", "required"},
+				{"/sections/0/exercises/0/answer", "required"},
+			},
+		},
+		{
+			name: "wrong choice count",
+			body: `{"name": "Animals", "languageTag": "de", "sections": [{"name": "Pets", "exercises": [
+				{"type": "multipleChoice", "question": "What is red?", "choices": ["rot", "blau"], "answer": "rot"}
+			]}]}`,
+			want: [][2]string{{"/sections/0/exercises/0/choices", "invalid"}},
+		},
+		{
+			name: "duplicate choice",
+			body: `{"name": "Animals", "languageTag": "de", "sections": [{"name": "Pets", "exercises": [
+				{"type": "multipleChoice", "question": "What is red?", "choices": ["rot", "blau", "rot", "gelb"], "answer": "rot"}
+			]}]}`,
+			want: [][2]string{{"/sections/0/exercises/0/choices", "invalid"}},
+		},
+		{
+			name: "answer not a choice",
+			body: `{"name": "Animals", "languageTag": "de", "sections": [{"name": "Pets", "exercises": [
+				{"type": "multipleChoice", "question": "What is red?", "choices": ["rot", "blau", "grün", "gelb"], "answer": "schwarz"}
+			]}]}`,
+			want: [][2]string{{"/sections/0/exercises/0/answer", "invalid"}},
+		},
+		{
+			name: "no blank",
+			body: `{"name": "Animals", "languageTag": "de", "sections": [{"name": "Pets", "exercises": [
+				{"type": "fillInTheBlank", "question": "Der Hund bellt.", "answer": "Hund"}
+			]}]}`,
+			want: [][2]string{{"/sections/0/exercises/0/question", "invalid"}},
+		},
+		{
+			name: "nothing to correct",
+			body: `{"name": "Animals", "languageTag": "de", "sections": [{"name": "Pets", "exercises": [
+				{"type": "sentenceCorrection", "sentence": "Der Hund bellt.", "correctedSentence": "Der Hund bellt."}
+			]}]}`,
+			want: [][2]string{{"/sections/0/exercises/0/correctedSentence", "invalid"}},
+		},
+		{
+			name: "section with more than one type",
+			body: `{"name": "Animals", "languageTag": "de", "sections": [{"name": "Pets", "exercises": [
+				{"type": "fillInTheBlank", "question": "Der ______ bellt.", "answer": "Hund"},
+				{"type": "sentenceCorrection", "sentence": "Der Hund bellen.", "correctedSentence": "Der Hund bellt."}
+			]}]}`,
+			want: [][2]string{{"/sections/0/exercises", "invalid"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req createQuizRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("failed to decode request: %v", err)
+			}
+
+			got := make([][2]string, 0)
+			for _, fieldErr := range req.validate() {
+				got = append(got, [2]string{fieldErr.Pointer, fieldErr.Code})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("field errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"languagequiz/metrics"
+	"languagequiz/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rate limit rules of the operations that anyone can call and that write.
+const (
+	RateLimitQuizzes  = "quizzes"
+	RateLimitAnswers  = "answers"
+	RateLimitFeedback = "feedback"
+)
+
+const rateLimitRemainingHeader = "RateLimit-Remaining"
+
+// limitRate refuses requests over the limit of rule for the client IP
+// address. Without a limiter, every request is let through.
+func limitRate(limiter *ratelimit.Limiter, rule string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		decision := limiter.Allow(c.Request.Context(), rule, c.ClientIP())
+		if decision.Remaining >= 0 {
+			c.Header(rateLimitRemainingHeader, strconv.Itoa(decision.Remaining))
+		}
+		if !decision.Allowed {
+			seconds := int(math.Ceil(decision.RetryAfter.Seconds()))
+			metrics.RateLimited(rule)
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			writeProblem(c, newProblem(c, NewError(http.StatusTooManyRequests,
+				fmt.Sprintf("too many requests, retry in %d seconds", seconds))))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"languagequiz/metrics"
+	"languagequiz/report"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxReportCommentLength = 2000
+
+type ReportHandler struct {
+	storage report.Storage
+	// flagThreshold is the number of open reports that flags an exercise.
+	flagThreshold int
+}
+
+func NewReportHandler(storage report.Storage, flagThreshold int) *ReportHandler {
+	return &ReportHandler{storage: storage, flagThreshold: flagThreshold}
+}
+
+type ReportDTO struct {
+	ID         string     `json:"id"`
+	QuizID     string     `json:"quizId"`
+	ExerciseID string     `json:"exerciseId"`
+	Reason     string     `json:"reason" enum:"wrongAnswer,typo,ambiguous,offensive"`
+	Comment    string     `json:"comment"`
+	Status     string     `json:"status" enum:"open,resolved"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ResolvedAt *time.Time `json:"resolvedAt"`
+}
+
+func newReportDTO(r report.Report) ReportDTO {
+	return ReportDTO{
+		ID:         r.ID,
+		QuizID:     r.QuizID,
+		ExerciseID: r.ExerciseID,
+		Reason:     string(r.Reason),
+		Comment:    r.Comment,
+		Status:     string(r.Status),
+		CreatedAt:  r.CreatedAt,
+		ResolvedAt: r.ResolvedAt,
+	}
+}
+
+// ExerciseReportsDTO is an entry of the report inbox: the reports on one
+// exercise.
+type ExerciseReportsDTO struct {
+	QuizID     string      `json:"quizId"`
+	ExerciseID string      `json:"exerciseId"`
+	Flagged    bool        `json:"flagged"`
+	FlaggedAt  *time.Time  `json:"flaggedAt"`
+	OpenCount  int         `json:"openCount"`
+	Reports    []ReportDTO `json:"reports"`
+}
+
+func newExerciseReportsDTO(e report.ExerciseReports) ExerciseReportsDTO {
+	reports := make([]ReportDTO, 0, len(e.Reports))
+	for _, r := range e.Reports {
+		reports = append(reports, newReportDTO(r))
+	}
+	return ExerciseReportsDTO{
+		QuizID:     e.QuizID,
+		ExerciseID: e.ExerciseID,
+		Flagged:    e.FlaggedAt != nil,
+		FlaggedAt:  e.FlaggedAt,
+		OpenCount:  e.OpenCount(),
+		Reports:    reports,
+	}
+}
+
+type reportExerciseRequest struct {
+	Reason  string `json:"reason" enum:"wrongAnswer,typo,ambiguous,offensive"`
+	Comment string `json:"comment,omitempty"`
+}
+
+func (r *reportExerciseRequest) validate() []FieldError {
+	errs := make([]FieldError, 0)
+	if r.Reason == "" {
+		errs = append(errs, newRequiredFieldError("/reason"))
+	} else if !report.Reason(r.Reason).Valid() {
+		reasons := make([]string, 0, len(report.Reasons))
+		for _, reason := range report.Reasons {
+			reasons = append(reasons, string(reason))
+		}
+		errs = append(errs, newFieldError("/reason", validationCodeUnsupported,
+			"must be one of "+strings.Join(reasons, ", ")))
+	}
+	if utf8.RuneCountInString(r.Comment) > maxReportCommentLength {
+		errs = append(errs, newFieldError("/comment", validationCodeInvalid,
+			fmt.Sprintf("must be at most %d characters", maxReportCommentLength)))
+	}
+	return errs
+}
+
+// ReportExercise stores a problem a learner found in an exercise.
+func (h *ReportHandler) ReportExercise(c *gin.Context) error {
+	var req reportExerciseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return fmt.Errorf("failed to decode request body: %w", err)
+	}
+	if errs := req.validate(); len(errs) > 0 {
+		return NewValidationError(errs)
+	}
+
+	r, flagged, err := h.storage.CreateReport(c.Request.Context(), report.CreateReportCommand{
+		QuizID:     c.Param("id"),
+		ExerciseID: c.Param("exerciseId"),
+		Reason:     report.Reason(req.Reason),
+		Comment:    strings.TrimSpace(req.Comment),
+		UserAgent:  c.Request.UserAgent(),
+	}, h.flagThreshold)
+	if err != nil {
+		return reportError(err)
+	}
+	metrics.ExerciseReported(string(r.Reason))
+	if flagged {
+		metrics.ExerciseFlagged()
+		slog.WarnContext(c.Request.Context(), "flagged exercise", "quiz_id", r.QuizID, "exercise_id", r.ExerciseID, "threshold", h.flagThreshold)
+	}
+
+	c.JSON(http.StatusCreated, newReportDTO(*r))
+	return nil
+}
+
+// ListReportInbox lists the exercises with reports, the most recently
+// reported first.
+func (h *ReportHandler) ListReportInbox(c *gin.Context) error {
+	filter := report.Filter{
+		Status: report.StatusOpen,
+		QuizID: c.Query("quizId"),
+	}
+	switch status := c.Query("status"); status {
+	case "":
+	case "all":
+		filter.Status = ""
+	case string(report.StatusOpen), string(report.StatusResolved):
+		filter.Status = report.Status(status)
+	default:
+		return NewError(http.StatusBadRequest, fmt.Sprintf("status must be %s, %s or all", report.StatusOpen, report.StatusResolved))
+	}
+	switch flagged := c.Query("flagged"); flagged {
+	case "", "false":
+	case "true":
+		filter.FlaggedOnly = true
+	default:
+		return NewError(http.StatusBadRequest, "flagged must be true or false")
+	}
+	if _, err := uuid.Parse(filter.QuizID); filter.QuizID != "" && err != nil {
+		return NewError(http.StatusBadRequest, "quizId must be a UUID")
+	}
+	var err error
+	filter.Limit, filter.Offset, err = parsePage(c)
+	if err != nil {
+		return err
+	}
+
+	inbox, err := h.storage.FindInbox(c.Request.Context(), filter)
+	if err != nil {
+		return fmt.Errorf("failed to find exercise reports: %w", err)
+	}
+
+	dtos := make([]ExerciseReportsDTO, 0, len(inbox))
+	for _, e := range inbox {
+		dtos = append(dtos, newExerciseReportsDTO(e))
+	}
+	c.JSON(http.StatusOK, dtos)
+	return nil
+}
+
+// ResolveExerciseReports resolves the open reports on an exercise, once its
+// author has dealt with them, and clears its flag.
+func (h *ReportHandler) ResolveExerciseReports(c *gin.Context) error {
+	e, err := h.storage.ResolveReports(c.Request.Context(), c.Param("exerciseId"))
+	if err != nil {
+		return reportError(err)
+	}
+	c.JSON(http.StatusOK, newExerciseReportsDTO(*e))
+	return nil
+}
+
+func reportError(err error) error {
+	if errors.Is(err, report.ErrNotFound) {
+		return NewError(http.StatusNotFound, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"languagequiz/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "requestID"
+)
+
+// requestID reuses the request ID sent by the client or a proxy, or generates
+// a new one, and echoes it in the response. The ID is also put in the request
+// context, so that everything logged with that context carries it.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+func getRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"languagequiz/quiz"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RevisionDTO struct {
+	Number    int       `json:"number"`
+	CreatedAt time.Time `json:"createdAt"`
+	// RolledBackFrom is the revision this one is a copy of, when it was
+	// created by a rollback.
+	RolledBackFrom *int   `json:"rolledBackFrom"`
+	Name           string `json:"name"`
+	LanguageTag    string `json:"languageTag"`
+	SectionCount   int    `json:"sectionCount"`
+	ExerciseCount  int    `json:"exerciseCount"`
+}
+
+func newRevisionDTO(r quiz.Revision) RevisionDTO {
+	dto := RevisionDTO{
+		Number:        r.Number,
+		CreatedAt:     r.CreatedAt,
+		Name:          r.Quiz.Name,
+		LanguageTag:   r.Quiz.LanguageTag.String(),
+		SectionCount:  len(r.Quiz.Sections),
+		ExerciseCount: len(r.Quiz.GetExercises()),
+	}
+	if r.RolledBackFrom > 0 {
+		dto.RolledBackFrom = &r.RolledBackFrom
+	}
+	return dto
+}
+
+type RevisionDiffDTO struct {
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Changes []ChangeDTO `json:"changes"`
+}
+
+// ChangeDTO is a difference between two revisions. Section is null for a
+// change to the quiz itself, and ExerciseID is empty unless the change is
+// about an exercise. Old and New are the values of Field, or the name of a
+// section that was removed or added.
+type ChangeDTO struct {
+	Kind       string `json:"kind" enum:"added,removed,changed"`
+	Section    *int   `json:"section"`
+	ExerciseID string `json:"exerciseId,omitempty"`
+	Field      string `json:"field,omitempty"`
+	Old        any    `json:"old"`
+	New        any    `json:"new"`
+}
+
+func newChangeDTO(c quiz.Change) ChangeDTO {
+	dto := ChangeDTO{
+		Kind:       string(c.Kind),
+		ExerciseID: c.ExerciseID,
+		Field:      c.Field,
+		Old:        c.Old,
+		New:        c.New,
+	}
+	if c.Section >= 0 {
+		dto.Section = &c.Section
+	}
+	return dto
+}
+
+// ListRevisions lists the revisions of a quiz, newest first.
+func (h *QuizHandler) ListRevisions(c *gin.Context) error {
+	revisions, err := h.quizStorage.FindRevisions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return quizError(err)
+	}
+
+	dtos := make([]RevisionDTO, 0, len(revisions))
+	for _, r := range revisions {
+		dtos = append(dtos, newRevisionDTO(r))
+	}
+	c.JSON(http.StatusOK, dtos)
+	return nil
+}
+
+// GetRevision serves a revision of a quiz as the quiz it was when it was
+// published.
+func (h *QuizHandler) GetRevision(c *gin.Context) error {
+	number, err := parseRevisionNumber(c.Param("number"), "revision number")
+	if err != nil {
+		return err
+	}
+	r, err := h.quizStorage.FindRevision(c.Request.Context(), c.Param("id"), number)
+	if err != nil {
+		return quizError(err)
+	}
+
+	dto, err := mapToQuizDTO(r.Quiz)
+	if err != nil {
+		return fmt.Errorf("failed to map quiz to dto: %w", err)
+	}
+	c.JSON(http.StatusOK, *dto)
+	return nil
+}
+
+// DiffRevisions lists the changes from the revision in the from query
+// parameter, the previous one by default, to the revision in the path.
+func (h *QuizHandler) DiffRevisions(c *gin.Context) error {
+	to, err := parseRevisionNumber(c.Param("number"), "revision number")
+	if err != nil {
+		return err
+	}
+	from := to - 1
+	if value := c.Query("from"); value != "" {
+		from, err = parseRevisionNumber(value, "from")
+		if err != nil {
+			return err
+		}
+	} else if from < 1 {
+		return NewError(http.StatusBadRequest, "revision 1 has no previous revision, pass from to compare it with another one")
+	}
+
+	fromRevision, err := h.quizStorage.FindRevision(c.Request.Context(), c.Param("id"), from)
+	if err != nil {
+		return quizError(err)
+	}
+	toRevision, err := h.quizStorage.FindRevision(c.Request.Context(), c.Param("id"), to)
+	if err != nil {
+		return quizError(err)
+	}
+
+	changes := quiz.Diff(fromRevision.Quiz, toRevision.Quiz)
+	dtos := make([]ChangeDTO, 0, len(changes))
+	for _, change := range changes {
+		dtos = append(dtos, newChangeDTO(change))
+	}
+	c.JSON(http.StatusOK, RevisionDiffDTO{From: from, To: to, Changes: dtos})
+	return nil
+}
+
+// RollbackQuiz shows learners an earlier revision of a published quiz again.
+// The revision is copied into a new one, so the history is kept.
+func (h *QuizHandler) RollbackQuiz(c *gin.Context) error {
+	number, err := parseRevisionNumber(c.Param("number"), "revision number")
+	if err != nil {
+		return err
+	}
+	q, err := h.quizStorage.Rollback(c.Request.Context(), c.Param("id"), number)
+	if err != nil {
+		return quizError(err)
+	}
+
+	dto, err := mapToQuizDTO(*q)
+	if err != nil {
+		return fmt.Errorf("failed to map quiz to dto: %w", err)
+	}
+	c.JSON(http.StatusOK, *dto)
+	return nil
+}
+
+func parseRevisionNumber(value, name string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		return 0, NewError(http.StatusBadRequest, name+" must be a positive integer")
+	}
+	return number, nil
+}
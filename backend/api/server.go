@@ -1,52 +1,429 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
 	"strconv"
+	"time"
+
+	"languagequiz/metrics"
+	"languagequiz/quiz"
+	"languagequiz/quiz/portable"
+	"languagequiz/ratelimit"
+	"languagequiz/tracing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	cors "github.com/rs/cors/wrapper/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Server struct {
 	handlers *Handlers
+	options  ServerOptions
+	openAPI  openAPIDocument
+}
+
+type ServerOptions struct {
+	AllowedOrigins    []string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout is how long in-flight requests get to finish once the
+	// server is asked to stop.
+	ShutdownTimeout time.Duration
+	MaxBodyBytes    int64
+	MaxUploadBytes  int64
+	// MaxFeedbackBytes is the body size limit of feedback and reports,
+	// which are small.
+	MaxFeedbackBytes int64
+	// AdminToken is the bearer token of admin operations. They are disabled
+	// when it is empty.
+	AdminToken string
+	// RateLimiter limits the operations that anyone can call and that write.
+	// They are not limited when it is nil.
+	RateLimiter *ratelimit.Limiter
+	// TrustedProxies are the addresses or networks of reverse proxies whose
+	// X-Forwarded-For header gives the client IP address for rate limiting.
+	TrustedProxies []string
 }
 
-func NewServer(handlers *Handlers) *Server {
-	return &Server{
+func NewServer(handlers *Handlers, options ServerOptions) *Server {
+	s := &Server{
 		handlers: handlers,
+		options:  options,
+	}
+	s.openAPI = newOpenAPIDocument(s.routes())
+	return s
+}
+
+func (s *Server) routes() []route {
+	return []route{
+		{http.MethodGet, "/v1/quizzes", s.handlers.quiz.GetQuizzes,
+			newOperation("getQuizzes", "List published quizzes").
+				withResponse(http.StatusOK, []QuizDTO{})},
+		{http.MethodGet, "/v1/quizzes/:id", s.handlers.quiz.GetQuizByID,
+			newOperation("getQuizByID", "Get a quiz").
+				withResponse(http.StatusOK, QuizDTO{})},
+		{http.MethodPost, "/v1/quizzes", s.handlers.quiz.CreateQuiz,
+			newOperation("createQuiz", "Create and publish a quiz").
+				withRateLimit(RateLimitQuizzes).
+				withRequestBody(createQuizRequest{}).
+				withResponse(http.StatusCreated, QuizDTO{})},
+		{http.MethodPost, "/v1/quizzes/:id/clone", s.handlers.quiz.CloneQuiz,
+			newOperation("cloneQuiz", "Copy and publish a quiz").
+				withRateLimit(RateLimitQuizzes).
+				withRequestBody(cloneQuizRequest{}).
+				withResponse(http.StatusCreated, QuizDTO{})},
+		{http.MethodGet, "/v1/quizzes/:id/export", s.handlers.quiz.ExportQuiz,
+			newOperation("exportQuiz", "Export a quiz as a portable document").
+				withQueryParameter("format", false, "json (default), yaml, gift, aiken or markdown").
+				withResponse(http.StatusOK, portable.Document{}).
+				withContent(http.StatusOK, mimeYAML, &jsonSchema{Ref: componentRef("Document")}).
+				withContent(http.StatusOK, mimePlainText, &jsonSchema{Type: "string"}).
+				withContent(http.StatusOK, mimeMarkdown, &jsonSchema{Type: "string"})},
+		{http.MethodGet, "/v1/quizzes/:id/export/qti", s.handlers.quiz.ExportQuizQTI,
+			newOperation("exportQuizQTI", "Export a quiz as an IMS QTI content package").
+				withQueryParameter("version", false, "QTI version: 2.1 (default) or 3.0").
+				withContent(http.StatusOK, mimeZip, &jsonSchema{Type: "string", Format: "binary"})},
+		{http.MethodPost, "/v1/quizzes/import", s.handlers.quiz.ImportQuiz,
+			newOperation("importQuiz", "Create a quiz from a portable document, a spreadsheet or a Moodle question file").
+				withUpload().
+				withRateLimit(RateLimitQuizzes).
+				withQueryParameter("format", false, "json, yaml, csv, tsv, gift, aiken, apkg or markdown; defaults to the content type").
+				withQueryParameter("name", false, "Name of the quiz, required unless the document has one").
+				withQueryParameter("languageTag", false, "BCP 47 language tag of the quiz, required unless the document has one").
+				withQueryParameter("columns", false, "Column mapping overrides, e.g. question:Front,answer:Back").
+				withQueryParameter("defaultSection", false, "Section for rows without one, defaults to the quiz name").
+				withQueryParameter("mode", false, "Anki only: fillInTheBlank (default) or multipleChoice").
+				withQueryParameter("frontField", false, "Anki only: note field used as the question").
+				withQueryParameter("backField", false, "Anki only: note field used as the answer").
+				withQueryParameter("seed", false, "Anki only: seed for picking multiple choice distractors").
+				withQueryParameter("skipInvalid", false, "Anki only: skip notes that cannot be imported").
+				withRequestBody(portable.Document{}).
+				withRequestContent(mimeYAML, &jsonSchema{Ref: componentRef("Document")}).
+				withRequestContent(mimeCSV, &jsonSchema{Type: "string"}).
+				withRequestContent(mimeTSV, &jsonSchema{Type: "string"}).
+				withRequestContent(mimePlainText, &jsonSchema{Type: "string"}).
+				withRequestContent(mimeAPKG, &jsonSchema{Type: "string", Format: "binary"}).
+				withRequestContent(mimeMarkdown, &jsonSchema{Type: "string"}).
+				withResponse(http.StatusCreated, QuizDTO{})},
+		{http.MethodPost, "/v1/quizzes/:id/answers", s.handlers.quiz.SubmitAnswers,
+			newOperation("submitAnswers", "Grade answers to a quiz").
+				withRateLimit(RateLimitAnswers).
+				withRequestBody(submitAnswersRequest{}).
+				withResponse(http.StatusOK, submitAnswersResponse{})},
+		{http.MethodPost, "/v1/quizzes/:id/exercises/:exerciseId/reports", s.handlers.report.ReportExercise,
+			newOperation("reportExercise", "Report a problem with an exercise").
+				withRateLimit(RateLimitFeedback).
+				withMaxBody(s.options.MaxFeedbackBytes).
+				withRequestBody(reportExerciseRequest{}).
+				withResponse(http.StatusCreated, ReportDTO{})},
+		{http.MethodPost, "/v1/feedback", s.handlers.feedback.SubmitFeedback,
+			newOperation("submitFeedback", "Submit feedback").
+				withRateLimit(RateLimitFeedback).
+				withMaxBody(s.options.MaxFeedbackBytes).
+				withRequestBody(submitFeedbackRequest{}).
+				withResponse(http.StatusOK, nil)},
+		{http.MethodGet, "/v1/feedback/challenge", s.handlers.feedback.GetChallenge,
+			newOperation("getFeedbackChallenge", "Get a proof of work challenge for anonymous feedback").
+				withResponse(http.StatusOK, challengeResponse{})},
+		{http.MethodGet, "/v1/admin/feedback", s.handlers.feedback.ListFeedback,
+			newOperation("listFeedback", "List feedback, newest first").
+				withAdmin().
+				withQueryParameter("status", false, "open or resolved").
+				withQueryParameter("tag", false, "Only feedback with this tag").
+				withQueryParameter("quizId", false, "Only feedback about this quiz").
+				withQueryParameter("exerciseId", false, "Only feedback about this exercise").
+				withQueryParameter("pagePath", false, "Only feedback about this page").
+				withQueryParameter("limit", false, "Maximum number of items, 50 by default and at most 200").
+				withQueryParameter("offset", false, "Number of items to skip").
+				withResponse(http.StatusOK, []FeedbackDTO{})},
+		{http.MethodGet, "/v1/admin/feedback/:id", s.handlers.feedback.GetFeedbackByID,
+			newOperation("getFeedbackByID", "Get feedback").
+				withAdmin().
+				withResponse(http.StatusOK, FeedbackDTO{})},
+		{http.MethodPost, "/v1/admin/feedback/:id/tags", s.handlers.feedback.TagFeedback,
+			newOperation("tagFeedback", "Add and remove tags of feedback").
+				withAdmin().
+				withRequestBody(tagFeedbackRequest{}).
+				withResponse(http.StatusOK, FeedbackDTO{})},
+		{http.MethodPost, "/v1/admin/feedback/:id/resolve", s.handlers.feedback.ResolveFeedback,
+			newOperation("resolveFeedback", "Mark feedback as resolved").
+				withAdmin().
+				withResponse(http.StatusOK, FeedbackDTO{})},
+		{http.MethodPost, "/v1/admin/feedback/:id/reopen", s.handlers.feedback.ReopenFeedback,
+			newOperation("reopenFeedback", "Mark resolved feedback as open again").
+				withAdmin().
+				withResponse(http.StatusOK, FeedbackDTO{})},
+		{http.MethodGet, "/v1/admin/quizzes", s.handlers.quiz.ListAllQuizzes,
+			newOperation("listAllQuizzes", "List quizzes whatever their status").
+				withAdmin().
+				withQueryParameter("status", false, "Only quizzes with this status: draft, published or archived").
+				withResponse(http.StatusOK, []QuizDTO{})},
+		{http.MethodPost, "/v1/admin/quizzes/:id/publish", s.handlers.quiz.PublishQuiz,
+			newOperation("publishQuiz", "Publish a draft quiz as a new revision, once no section is empty").
+				withAdmin().
+				withResponse(http.StatusOK, QuizDTO{})},
+		{http.MethodPost, "/v1/admin/quizzes/:id/unpublish", s.handlers.quiz.UnpublishQuiz,
+			newOperation("unpublishQuiz", "Turn a published or archived quiz back into a draft").
+				withAdmin().
+				withResponse(http.StatusOK, QuizDTO{})},
+		{http.MethodPost, "/v1/admin/quizzes/:id/archive", s.handlers.quiz.ArchiveQuiz,
+			newOperation("archiveQuiz", "Archive a quiz so that it is no longer listed").
+				withAdmin().
+				withResponse(http.StatusOK, QuizDTO{})},
+		{http.MethodGet, "/v1/admin/quizzes/:id/revisions", s.handlers.quiz.ListRevisions,
+			newOperation("listQuizRevisions", "List the revisions of a quiz, newest first").
+				withAdmin().
+				withResponse(http.StatusOK, []RevisionDTO{})},
+		{http.MethodGet, "/v1/admin/quizzes/:id/revisions/:number", s.handlers.quiz.GetRevision,
+			newOperation("getQuizRevision", "Get a quiz as it was in a revision").
+				withAdmin().
+				withResponse(http.StatusOK, QuizDTO{})},
+		{http.MethodGet, "/v1/admin/quizzes/:id/revisions/:number/diff", s.handlers.quiz.DiffRevisions,
+			newOperation("diffQuizRevisions", "List the changes between two revisions of a quiz").
+				withAdmin().
+				withQueryParameter("from", false, "Revision to compare with, the previous one by default").
+				withResponse(http.StatusOK, RevisionDiffDTO{})},
+		{http.MethodPost, "/v1/admin/quizzes/:id/revisions/:number/rollback", s.handlers.quiz.RollbackQuiz,
+			newOperation("rollbackQuiz", "Publish a copy of an earlier revision of a published quiz").
+				withAdmin().
+				withResponse(http.StatusOK, QuizDTO{})},
+		{http.MethodGet, "/v1/admin/reports", s.handlers.report.ListReportInbox,
+			newOperation("listReportInbox", "List exercises with reports, most recently reported first").
+				withAdmin().
+				withQueryParameter("status", false, "Only reports with this status: open (default), resolved or all").
+				withQueryParameter("flagged", false, "true to list only flagged exercises").
+				withQueryParameter("quizId", false, "Only exercises of this quiz").
+				withQueryParameter("limit", false, "Maximum number of exercises, 50 by default and at most 200").
+				withQueryParameter("offset", false, "Number of exercises to skip").
+				withResponse(http.StatusOK, []ExerciseReportsDTO{})},
+		{http.MethodPost, "/v1/admin/reports/:exerciseId/resolve", s.handlers.report.ResolveExerciseReports,
+			newOperation("resolveExerciseReports", "Resolve the open reports on an exercise and clear its flag").
+				withAdmin().
+				withResponse(http.StatusOK, ExerciseReportsDTO{})},
+		{http.MethodGet, "/healthz", s.handlers.health.GetHealth,
+			newOperation("getHealth", "Check that the server is alive").
+				withResponse(http.StatusOK, healthResponse{})},
+		{http.MethodGet, "/readyz", s.handlers.health.GetReadiness,
+			newOperation("getReadiness", "Check that the server and its dependencies are ready").
+				withResponse(http.StatusOK, readinessResponse{}).
+				withResponse(http.StatusServiceUnavailable, readinessResponse{})},
+		{http.MethodGet, "/version", s.handlers.health.GetVersion,
+			newOperation("getVersion", "Get the build of the server").
+				withResponse(http.StatusOK, versionResponse{})},
+		{http.MethodGet, "/metrics", getMetrics,
+			newOperation("getMetrics", "Get metrics in the Prometheus text format").
+				withContent(http.StatusOK, mimePlainText, &jsonSchema{Type: "string"})},
+		{http.MethodGet, "/v1/openapi.json", s.getOpenAPIDocument,
+			newOperation("getOpenAPIDocument", "Get this OpenAPI document").
+				withContent(http.StatusOK, gin.MIMEJSON, &jsonSchema{Type: "object"})},
 	}
 }
 
-func (s *Server) Start(port int) error {
-	r := gin.Default()
+func (s *Server) getOpenAPIDocument(c *gin.Context) error {
+	c.JSON(http.StatusOK, s.openAPI)
+	return nil
+}
 
+// Start serves the api until ctx is done, then stops accepting connections and
+// waits for in-flight requests to finish.
+func (s *Server) Start(ctx context.Context, port int) error {
+	r := gin.New()
+	if err := r.SetTrustedProxies(s.options.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	r.Use(otelgin.Middleware(tracing.ServiceName))
+	r.Use(observeRequests())
+	r.Use(requestID())
+	r.Use(annotateSpan())
+	r.Use(logRequests())
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, recoverPanic))
 	r.Use(cors.New(cors.Options{
-		AllowedOrigins: []string{"http://localhost:3000", "http://lucianos-macbook-pro.local:3000"},
+		AllowedOrigins: s.options.AllowedOrigins,
 		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-Requested-With", requestIDHeader},
+		ExposedHeaders: []string{requestIDHeader, rateLimitRemainingHeader, "Retry-After"},
+	}))
+
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(createHandlerFunc(func(c *gin.Context) error {
+		return NewError(http.StatusNotFound, "route not found")
+	}))
+	r.NoMethod(createHandlerFunc(func(c *gin.Context) error {
+		return NewError(http.StatusMethodNotAllowed, "method not allowed")
 	}))
 
-	r.GET("/v1/quizzes", createHandlerFunc(s.handlers.quiz.GetQuizzes))
-	r.GET("/v1/quizzes/:id", createHandlerFunc(s.handlers.quiz.GetQuizByID))
-	r.POST("/v1/quizzes", createHandlerFunc(s.handlers.quiz.CreateQuiz))
-	r.POST("/v1/quizzes/:id/answers", createHandlerFunc(s.handlers.quiz.SubmitAnswers))
-	r.POST("/v1/feedback", createHandlerFunc(s.handlers.feedback.SubmitFeedback))
+	for _, route := range s.routes() {
+		maxBytes := s.options.MaxBodyBytes
+		if route.operation.Upload {
+			maxBytes = s.options.MaxUploadBytes
+		}
+		if route.operation.MaxBodyBytes > 0 {
+			maxBytes = route.operation.MaxBodyBytes
+		}
+		handlers := make([]gin.HandlerFunc, 0)
+		if route.operation.RateLimit != "" {
+			handlers = append(handlers, limitRate(s.options.RateLimiter, route.operation.RateLimit))
+		}
+		handlers = append(handlers, limitBody(maxBytes))
+		if route.operation.Admin {
+			handlers = append(handlers, requireAdmin(s.options.AdminToken))
+		}
+		handlers = append(handlers, createHandlerFunc(route.handler))
+		r.Handle(route.method, route.path, handlers...)
+	}
+
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(port),
+		Handler:           r,
+		ReadHeaderTimeout: s.options.ReadHeaderTimeout,
+		ReadTimeout:       s.options.ReadTimeout,
+		WriteTimeout:      s.options.WriteTimeout,
+		IdleTimeout:       s.options.IdleTimeout,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.options.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down gracefully: %w", err)
+	}
+	return nil
+}
+
+// observeRequests records the count and latency of requests by route pattern.
+// Requests that match no route share a single series.
+func observeRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, strconv.Itoa(c.Writer.Status()), time.Since(start).Seconds())
+	}
+}
+
+var metricsHandler = promhttp.Handler()
+
+func getMetrics(c *gin.Context) error {
+	metricsHandler.ServeHTTP(c.Writer, c.Request)
+	return nil
+}
 
-	return r.Run(":" + strconv.Itoa(port))
+// limitBody fails requests whose body is larger than maxBytes. Requests that
+// announce a larger Content-Length are refused before reading the body.
+func limitBody(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			writeProblem(c, newProblem(c, newRequestTooLargeError(maxBytes)))
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+func newRequestTooLargeError(maxBytes int64) Error {
+	return NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is larger than %d bytes", maxBytes))
+}
+
+// annotateSpan adds the request ID and the quiz ID of the route to the span
+// of the request.
+func annotateSpan() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		span := trace.SpanFromContext(c.Request.Context())
+		span.SetAttributes(attribute.String("request.id", getRequestID(c)))
+		if id := c.Param("id"); id != "" {
+			span.SetAttributes(attribute.String("quiz.id", id))
+		}
+		c.Next()
+	}
+}
+
+// logRequests logs every request once it is handled.
+func logRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		slog.InfoContext(c.Request.Context(), "request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+func recoverPanic(c *gin.Context, recovered any) {
+	slog.ErrorContext(c.Request.Context(), "panic while handling request",
+		"panic", fmt.Sprint(recovered),
+		"stack", string(debug.Stack()),
+	)
+	writeProblem(c, newProblem(c, NewError(http.StatusInternalServerError, "")))
+	c.Abort()
+}
+
+// logServerError logs an error that is not the client's fault. Storage errors
+// are logged with the quiz and exercise they concern.
+func logServerError(c *gin.Context, err error) {
+	attrs := []any{"error", err.Error(), "route", c.FullPath()}
+	var storageErr *quiz.StorageError
+	if errors.As(err, &storageErr) {
+		if storageErr.QuizID != "" {
+			attrs = append(attrs, "quiz_id", storageErr.QuizID)
+		}
+		if storageErr.ExerciseID != "" {
+			attrs = append(attrs, "exercise_id", storageErr.ExerciseID)
+		}
+	}
+	slog.ErrorContext(c.Request.Context(), "server error", attrs...)
 }
 
 func createHandlerFunc(f func(c *gin.Context) error) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if err := f(c); err != nil {
-			if err, ok := err.(Error); ok {
-				c.JSON(err.Status, err)
+			var apiErr Error
+			if errors.As(err, &apiErr) {
+				writeProblem(c, newProblem(c, apiErr))
+				return
+			}
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeProblem(c, newProblem(c, newRequestTooLargeError(maxBytesErr.Limit)))
 				return
 			}
 
-			fmt.Printf("server error: %s\n", err.Error())
+			logServerError(c, err)
 			status := http.StatusInternalServerError
-			c.JSON(status, NewError(status, http.StatusText(status)))
+			writeProblem(c, newProblem(c, NewError(status, "")))
 		}
 	}
 }
@@ -54,11 +431,15 @@ func createHandlerFunc(f func(c *gin.Context) error) gin.HandlerFunc {
 type Handlers struct {
 	quiz     *QuizHandler
 	feedback *FeedbackHandler
+	report   *ReportHandler
+	health   *HealthHandler
 }
 
-func NewHandlers(quizHandler *QuizHandler, feedbackHandler *FeedbackHandler) *Handlers {
+func NewHandlers(quizHandler *QuizHandler, feedbackHandler *FeedbackHandler, reportHandler *ReportHandler, healthHandler *HealthHandler) *Handlers {
 	return &Handlers{
 		quiz:     quizHandler,
 		feedback: feedbackHandler,
+		report:   reportHandler,
+		health:   healthHandler,
 	}
 }
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	validationCodeRequired    = "required"
+	validationCodeEmpty       = "empty"
+	validationCodeInvalid     = "invalid"
+	validationCodeUnsupported = "unsupported"
+)
+
+// FieldError describes a single invalid field in a request body. The pointer
+// is a JSON pointer (RFC 6901) into the request, e.g. /sections/2/exercises/5/answer.
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newFieldError(pointer, code, message string) FieldError {
+	return FieldError{
+		Pointer: pointer,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func newRequiredFieldError(pointer string) FieldError {
+	return newFieldError(pointer, validationCodeRequired, "required field is missing")
+}
+
+func newEmptyFieldError(pointer string) FieldError {
+	return newFieldError(pointer, validationCodeEmpty, "field is empty")
+}
+
+func (e FieldError) String() string {
+	return e.Pointer + ": " + e.Message
+}
+
+func NewValidationError(fieldErrors []FieldError) Error {
+	messages := make([]string, 0)
+	for _, fieldError := range fieldErrors {
+		messages = append(messages, fieldError.String())
+	}
+	err := NewError(http.StatusBadRequest, fmt.Sprintf("validation failed: %s", strings.Join(messages, "; ")))
+	err.Type = problemTypeValidation
+	err.Errors = fieldErrors
+	return err
+}
+
+// pointer joins the given reference tokens into a JSON pointer, escaping
+// '~' and '/' as required by RFC 6901.
+func pointer(base string, tokens ...any) string {
+	var b strings.Builder
+	b.WriteString(base)
+	for _, token := range tokens {
+		b.WriteByte('/')
+		switch token := token.(type) {
+		case int:
+			b.WriteString(strconv.Itoa(token))
+		case string:
+			b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+		default:
+			b.WriteString(fmt.Sprint(token))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"languagequiz/config"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// loadDatabaseConfig reads the database settings the same way the server
+// does, from .env, $CONFIG_FILE and the environment.
+func loadDatabaseConfig() (*config.Database, error) {
+	cfg, _, err := config.Load(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg.Database, nil
+}
+
+func connect() (*pgxpool.Pool, error) {
+	databaseConfig, err := loadDatabaseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig, err := databaseConfig.PoolConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	dbpool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+
+	if err := dbpool.Ping(context.Background()); err != nil {
+		dbpool.Close()
+		return nil, fmt.Errorf("failed to ping the database: %w", err)
+	}
+	return dbpool, nil
+}
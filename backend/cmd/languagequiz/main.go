@@ -0,0 +1,73 @@
+// Command languagequiz is the admin tool for the languagequiz service.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"languagequiz/quiz"
+)
+
+const usage = `Usage: languagequiz <command> [arguments]
+
+Commands:
+  quiz list          List all quizzes with their status
+  quiz show          Print a quiz with its sections and exercises
+  quiz import        Create a quiz from a file
+  quiz export        Write a quiz to a file
+  quiz delete        Delete a quiz
+  quiz publish       Publish a draft quiz
+  quiz unpublish     Turn a published or archived quiz back into a draft
+  quiz archive       Archive a quiz so that it is no longer listed
+  migrate up         Apply all or the next n pending migrations
+  migrate down       Revert the last n migrations, 1 by default, or -all
+  migrate status     Print the applied and pending migrations
+  migrate force      Set the migration version to clear a dirty state
+  stats              Print the number of quizzes and exercises
+
+Run 'languagequiz <command> -h' for the flags of a command.
+`
+
+type command struct {
+	name string
+	run  func(args []string) error
+}
+
+var commands = []command{
+	{"quiz list", runQuizList},
+	{"quiz show", runQuizShow},
+	{"quiz import", runQuizImport},
+	{"quiz export", runQuizExport},
+	{"quiz delete", runQuizDelete},
+	{"quiz publish", runQuizSetStatus("publish", quiz.StatusPublished)},
+	{"quiz unpublish", runQuizSetStatus("unpublish", quiz.StatusDraft)},
+	{"quiz archive", runQuizSetStatus("archive", quiz.StatusArchived)},
+	{"migrate up", runMigrate("up")},
+	{"migrate down", runMigrate("down")},
+	{"migrate status", runMigrate("status")},
+	{"migrate force", runMigrate("force")},
+	{"stats", runStats},
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd.run(args[len(words):])
+		}
+	}
+
+	fmt.Fprint(os.Stderr, usage)
+	if len(args) == 0 {
+		return fmt.Errorf("missing command")
+	}
+	return fmt.Errorf("unknown command: %s", strings.Join(args, " "))
+}
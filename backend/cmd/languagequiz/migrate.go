@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+
+	"languagequiz/migrations"
+)
+
+func runMigrate(command string) func(args []string) error {
+	return func(args []string) error {
+		databaseConfig, err := loadDatabaseConfig()
+		if err != nil {
+			return err
+		}
+		m, err := migrations.New(databaseConfig.ConnString)
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+
+		return migrations.RunCommand(m, append([]string{command}, args...), os.Stdout)
+	}
+}
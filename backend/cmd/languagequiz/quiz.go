@@ -0,0 +1,443 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"languagequiz/postgres"
+	"languagequiz/quiz"
+	"languagequiz/quiz/anki"
+	"languagequiz/quiz/csvimport"
+	"languagequiz/quiz/markdown"
+	"languagequiz/quiz/moodle"
+	"languagequiz/quiz/portable"
+	"languagequiz/quiz/qti"
+
+	"golang.org/x/text/language"
+)
+
+// formatsByExtension maps file extensions to the format of quiz import.
+var formatsByExtension = map[string]string{
+	".csv":      "csv",
+	".tsv":      "tsv",
+	".tab":      "tsv",
+	".json":     "json",
+	".yaml":     "yaml",
+	".yml":      "yaml",
+	".gift":     "gift",
+	".aiken":    "aiken",
+	".apkg":     "apkg",
+	".md":       "markdown",
+	".markdown": "markdown",
+}
+
+func runQuizList(args []string) error {
+	flags := flag.NewFlagSet("quiz list", flag.ContinueOnError)
+	status := flags.String("status", "", "only quizzes with this status: draft, published or archived")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	filter := quiz.Filter{Status: quiz.Status(*status)}
+	if filter.Status != "" && !filter.Status.Valid() {
+		return fmt.Errorf("unknown status: %q", *status)
+	}
+
+	dbpool, err := connect()
+	if err != nil {
+		return err
+	}
+	defer dbpool.Close()
+
+	quizzes, err := postgres.NewQuizStorage(dbpool).FindQuizzes(context.Background(), filter)
+	if err != nil {
+		return fmt.Errorf("failed to find quizzes: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tLANGUAGE\tSECTIONS\tEXERCISES\tUPDATED\tNAME")
+	for _, q := range quizzes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+			q.ID, q.Status, q.LanguageTag, len(q.Sections), len(q.GetExercises()), q.UpdatedAt.Format("2006-01-02 15:04"), q.Name)
+	}
+	return w.Flush()
+}
+
+// runQuizShow prints a quiz in the Markdown quiz syntax, which shows every
+// exercise with its answer and feedback.
+func runQuizShow(args []string) error {
+	flags := flag.NewFlagSet("quiz show", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: languagequiz quiz show <id>")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one quiz id")
+	}
+
+	dbpool, err := connect()
+	if err != nil {
+		return err
+	}
+	defer dbpool.Close()
+
+	q, err := postgres.NewQuizStorage(dbpool).FindByID(context.Background(), flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to find quiz: %w", err)
+	}
+	return markdown.Render(os.Stdout, *q)
+}
+
+func runQuizImport(args []string) error {
+	flags := flag.NewFlagSet("quiz import", flag.ContinueOnError)
+	format := flags.String("format", "", "csv, tsv, json, yaml, gift, aiken, apkg or markdown; defaults to the file extension")
+	name := flags.String("name", "", "name of the quiz, required unless the file has one")
+	languageTag := flags.String("lang", "", "BCP 47 language tag of the quiz, required unless the file has one")
+	columns := flags.String("columns", "", "csv and tsv: column mapping overrides, e.g. question:Front,answer:Back")
+	defaultSection := flags.String("default-section", "", "csv, tsv, gift and aiken: section for exercises without one")
+	mode := flags.String("mode", anki.ModeFillInTheBlank, "apkg: fillInTheBlank or multipleChoice")
+	frontField := flags.String("front-field", "", "apkg: note field used as the question")
+	backField := flags.String("back-field", "", "apkg: note field used as the answer")
+	seed := flags.Int64("seed", 0, "apkg: seed for picking multiple choice distractors")
+	skipInvalid := flags.Bool("skip-invalid", false, "apkg: skip notes that cannot be imported")
+	dryRun := flags.Bool("dry-run", false, "validate the file without creating the quiz")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: languagequiz quiz import [flags] <file>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one file")
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = formatsByExtension[strings.ToLower(filepath.Ext(path))]
+		if *format == "" {
+			return fmt.Errorf("cannot tell the format of %s; set -format", path)
+		}
+	}
+
+	var tag language.Tag
+	if *languageTag != "" {
+		var err error
+		tag, err = language.Parse(*languageTag)
+		if err != nil {
+			return fmt.Errorf("invalid flag -lang: %w", err)
+		}
+	}
+	requireTarget := func() error {
+		if *name == "" || *languageTag == "" {
+			return fmt.Errorf("flags -name and -lang are required for %s files", *format)
+		}
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var cmd *quiz.CreateQuizCommand
+	switch *format {
+	case "csv", "tsv":
+		if err := requireTarget(); err != nil {
+			return err
+		}
+		mapping, err := csvimport.ParseColumnMapping(*columns)
+		if err != nil {
+			return err
+		}
+		opts := csvimport.NewOptions(*name, tag, mapping)
+		opts.Comma = ','
+		if *format == "tsv" {
+			opts.Comma = '\t'
+		}
+		if *defaultSection != "" {
+			opts.DefaultSection = *defaultSection
+		}
+		cmd, err = csvimport.Import(file, opts)
+		if err != nil {
+			return printIssues(path, err)
+		}
+	case "json", "yaml":
+		portableFormat, _ := portable.ParseFormat(*format)
+		doc, err := portable.Decode(file, portableFormat)
+		if err != nil {
+			return err
+		}
+		cmd, err = doc.ToCommand()
+		if err != nil {
+			return printIssues(path, err)
+		}
+		if *name != "" {
+			cmd.Name = *name
+		}
+		if *languageTag != "" {
+			cmd.LanguageTag = tag
+		}
+	case "gift", "aiken":
+		if err := requireTarget(); err != nil {
+			return err
+		}
+		opts := moodle.NewOptions(*name, tag)
+		if *defaultSection != "" {
+			opts.DefaultSection = *defaultSection
+		}
+		if *format == "gift" {
+			var warnings []moodle.Issue
+			cmd, warnings, err = moodle.ParseGIFT(file, opts)
+			for _, warning := range warnings {
+				fmt.Fprintf(os.Stderr, "%s:%s (warning)\n", path, warning)
+			}
+		} else {
+			cmd, err = moodle.ParseAiken(file, opts)
+		}
+		if err != nil {
+			return printIssues(path, err)
+		}
+	case "apkg":
+		if err := requireTarget(); err != nil {
+			return err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		opts := anki.NewOptions(*name, tag)
+		opts.Mode = *mode
+		opts.FrontField = *frontField
+		opts.BackField = *backField
+		opts.Seed = *seed
+		opts.SkipInvalid = *skipInvalid
+		var warnings []anki.Issue
+		cmd, warnings, err = anki.Import(file, info.Size(), opts)
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "%s: %s (skipped)\n", path, warning)
+		}
+		if err != nil {
+			return printIssues(path, err)
+		}
+	case "markdown":
+		cmd, err = markdown.Parse(file, markdown.Options{Name: *name, LanguageTag: tag})
+		if err != nil {
+			return printIssues(path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported format: %q", *format)
+	}
+
+	printCreateQuizCommand(*cmd)
+	if *dryRun {
+		return nil
+	}
+
+	dbpool, err := connect()
+	if err != nil {
+		return err
+	}
+	defer dbpool.Close()
+
+	q, err := postgres.NewQuizStorage(dbpool).CreateQuiz(context.Background(), *cmd, quiz.StatusDraft)
+	if err != nil {
+		return fmt.Errorf("failed to create quiz: %w", err)
+	}
+	fmt.Println("Created draft quiz", q.ID)
+	return nil
+}
+
+// printIssues prints every problem of an import error on its own line, and
+// returns err unchanged when it is not a list of problems.
+func printIssues(path string, err error) error {
+	issues := make([]string, 0)
+	var csvErrs csvimport.Errors
+	var portableErrs portable.Errors
+	var moodleErrs moodle.Errors
+	var ankiErrs anki.Errors
+	var markdownErrs markdown.Errors
+	switch {
+	case errors.As(err, &csvErrs):
+		for _, e := range csvErrs {
+			issues = append(issues, e.Error())
+		}
+	case errors.As(err, &portableErrs):
+		for _, e := range portableErrs {
+			issues = append(issues, e.Error())
+		}
+	case errors.As(err, &moodleErrs):
+		for _, e := range moodleErrs {
+			issues = append(issues, e.String())
+		}
+	case errors.As(err, &ankiErrs):
+		for _, e := range ankiErrs {
+			issues = append(issues, e.String())
+		}
+	case errors.As(err, &markdownErrs):
+		for _, e := range markdownErrs {
+			issues = append(issues, e.String())
+		}
+	default:
+		return err
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", path, issue)
+	}
+	return fmt.Errorf("file has %d errors", len(issues))
+}
+
+func runQuizExport(args []string) error {
+	flags := flag.NewFlagSet("quiz export", flag.ContinueOnError)
+	format := flags.String("format", "json", "json, yaml, gift, aiken, markdown or qti")
+	qtiVersion := flags.String("qti-version", string(qti.Version21), "qti: 2.1 or 3.0")
+	output := flags.String("o", "", "file to write to, defaults to standard output")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: languagequiz quiz export [flags] <id>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one quiz id")
+	}
+
+	dbpool, err := connect()
+	if err != nil {
+		return err
+	}
+	defer dbpool.Close()
+
+	q, err := postgres.NewQuizStorage(dbpool).FindByID(context.Background(), flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to find quiz: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch *format {
+	case "json", "yaml":
+		portableFormat, _ := portable.ParseFormat(*format)
+		doc, err := portable.Export(*q)
+		if err != nil {
+			return fmt.Errorf("failed to export quiz: %w", err)
+		}
+		return portable.Encode(w, *doc, portableFormat)
+	case "gift":
+		return moodle.WriteGIFT(w, *q)
+	case "aiken":
+		return moodle.WriteAiken(w, *q)
+	case "markdown":
+		return markdown.Render(w, *q)
+	case "qti":
+		version, err := qti.ParseVersion(*qtiVersion)
+		if err != nil {
+			return err
+		}
+		return qti.Export(w, *q, version)
+	default:
+		return fmt.Errorf("unsupported format: %q", *format)
+	}
+}
+
+func runQuizDelete(args []string) error {
+	flags := flag.NewFlagSet("quiz delete", flag.ContinueOnError)
+	yes := flags.Bool("yes", false, "delete without asking for confirmation")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: languagequiz quiz delete [-yes] <id>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one quiz id")
+	}
+
+	dbpool, err := connect()
+	if err != nil {
+		return err
+	}
+	defer dbpool.Close()
+
+	storage := postgres.NewQuizStorage(dbpool)
+	q, err := storage.FindByID(context.Background(), flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to find quiz: %w", err)
+	}
+
+	if !*yes {
+		fmt.Printf("Delete %q with %d exercises? [y/N] ", q.Name, len(q.GetExercises()))
+		var answer string
+		fmt.Scanln(&answer)
+		if !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+			return errors.New("aborted")
+		}
+	}
+
+	if err := storage.DeleteQuiz(context.Background(), q.ID); err != nil {
+		return fmt.Errorf("failed to delete quiz: %w", err)
+	}
+	fmt.Println("Deleted quiz", q.ID)
+	return nil
+}
+
+// runQuizSetStatus returns a command that moves a quiz to status.
+func runQuizSetStatus(name string, status quiz.Status) func(args []string) error {
+	return func(args []string) error {
+		flags := flag.NewFlagSet("quiz "+name, flag.ContinueOnError)
+		flags.Usage = func() {
+			fmt.Fprintf(flags.Output(), "Usage: languagequiz quiz %s <id>\n", name)
+		}
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			flags.Usage()
+			return errors.New("expected exactly one quiz id")
+		}
+
+		dbpool, err := connect()
+		if err != nil {
+			return err
+		}
+		defer dbpool.Close()
+
+		q, err := postgres.NewQuizStorage(dbpool).SetStatus(context.Background(), flags.Arg(0), status)
+		if err != nil {
+			return fmt.Errorf("failed to %s quiz: %w", name, err)
+		}
+		fmt.Printf("Quiz %s is %s\n", q.ID, q.Status)
+		return nil
+	}
+}
+
+func printCreateQuizCommand(cmd quiz.CreateQuizCommand) {
+	fmt.Printf("%s (%s)\n", cmd.Name, cmd.LanguageTag)
+	for _, section := range cmd.Sections {
+		fmt.Printf("  %s: %d exercises\n", section.Name, len(section.Exercises))
+	}
+}
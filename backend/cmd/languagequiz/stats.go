@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"languagequiz/postgres"
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+)
+
+func runStats(args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	dbpool, err := connect()
+	if err != nil {
+		return err
+	}
+	defer dbpool.Close()
+
+	quizzes, err := postgres.NewQuizStorage(dbpool).FindQuizzes(context.Background(), quiz.Filter{})
+	if err != nil {
+		return fmt.Errorf("failed to find quizzes: %w", err)
+	}
+
+	sections := 0
+	exercisesByType := make(map[string]int)
+	quizzesByLanguage := make(map[string]int)
+	quizzesByStatus := make(map[quiz.Status]int)
+	for _, q := range quizzes {
+		sections += len(q.Sections)
+		quizzesByStatus[q.Status]++
+		quizzesByLanguage[q.LanguageTag.String()]++
+		for _, e := range q.GetExercises() {
+			exercisesByType[exercise.TypeOf(e)]++
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Quizzes\t%d\n", len(quizzes))
+	for _, status := range quiz.Statuses {
+		fmt.Fprintf(w, "Quizzes (%s)\t%d\n", status, quizzesByStatus[status])
+	}
+	fmt.Fprintf(w, "Sections\t%d\n", sections)
+	for _, t := range []string{exercise.TypeMultipleChoice, exercise.TypeFillInTheBlank, exercise.TypeSentenceCorrection} {
+		fmt.Fprintf(w, "Exercises (%s)\t%d\n", t, exercisesByType[t])
+	}
+
+	languages := make([]string, 0)
+	for l := range quizzesByLanguage {
+		languages = append(languages, l)
+	}
+	sort.Strings(languages)
+	for _, l := range languages {
+		fmt.Fprintf(w, "Quizzes (%s)\t%d\n", l, quizzesByLanguage[l])
+	}
+	return w.Flush()
+}
@@ -0,0 +1,508 @@
+// Package config loads the settings of the service.
+//
+// Settings start at their defaults and are then overridden, in this order, by
+// a YAML file, environment variables and command line flags. Variables from a
+// .env file are loaded into the environment unless they are already set. The
+// YAML file is given by -config or CONFIG_FILE. Every setting has an
+// environment variable, and a flag named after it: DB_MAX_CONNS is
+// -db-max-conns.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"languagequiz/ratelimit"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Port        int       `yaml:"port" env:"PORT" desc:"port the server listens on"`
+	AutoMigrate bool      `yaml:"autoMigrate" env:"AUTO_MIGRATE" desc:"apply pending migrations at startup"`
+	LogLevel    string    `yaml:"logLevel" env:"LOG_LEVEL" desc:"minimum level of logged lines: debug, info, warn or error"`
+	Database    Database  `yaml:"database"`
+	HTTP        HTTP      `yaml:"http"`
+	CORS        CORS      `yaml:"cors"`
+	Feedback    Feedback  `yaml:"feedback"`
+	Outbox      Outbox    `yaml:"outbox"`
+	RateLimit   RateLimit `yaml:"rateLimit"`
+	Reports     Reports   `yaml:"reports"`
+	Tracing     Tracing   `yaml:"tracing"`
+	Admin       Admin     `yaml:"admin"`
+}
+
+type Database struct {
+	ConnString      string        `yaml:"connString" env:"POSTGRES_CONN_STRING" desc:"PostgreSQL connection string"`
+	MaxConns        int32         `yaml:"maxConns" env:"DB_MAX_CONNS" desc:"maximum number of connections in the pool"`
+	MinConns        int32         `yaml:"minConns" env:"DB_MIN_CONNS" desc:"number of connections the pool keeps open"`
+	MaxConnLifetime time.Duration `yaml:"maxConnLifetime" env:"DB_MAX_CONN_LIFETIME" desc:"time after which a connection is closed"`
+	MaxConnIdleTime time.Duration `yaml:"maxConnIdleTime" env:"DB_MAX_CONN_IDLE_TIME" desc:"time after which an idle connection is closed"`
+	ConnectTimeout  time.Duration `yaml:"connectTimeout" env:"DB_CONNECT_TIMEOUT" desc:"timeout for opening a connection"`
+}
+
+// PoolConfig returns the configuration of a connection pool with these
+// settings.
+func (d Database) PoolConfig() (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(d.ConnString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+	config.MaxConns = d.MaxConns
+	config.MinConns = d.MinConns
+	config.MaxConnLifetime = d.MaxConnLifetime
+	config.MaxConnIdleTime = d.MaxConnIdleTime
+	config.ConnConfig.ConnectTimeout = d.ConnectTimeout
+	return config, nil
+}
+
+type HTTP struct {
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env:"HTTP_READ_HEADER_TIMEOUT" desc:"timeout for reading request headers"`
+	ReadTimeout       time.Duration `yaml:"readTimeout" env:"HTTP_READ_TIMEOUT" desc:"timeout for reading a request"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout" env:"HTTP_WRITE_TIMEOUT" desc:"timeout for writing a response"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout" env:"HTTP_IDLE_TIMEOUT" desc:"time a keep-alive connection may be idle"`
+	ShutdownTimeout   time.Duration `yaml:"shutdownTimeout" env:"HTTP_SHUTDOWN_TIMEOUT" desc:"time in-flight requests get to finish on shutdown"`
+	MaxBodyBytes      int64         `yaml:"maxBodyBytes" env:"HTTP_MAX_BODY_BYTES" desc:"maximum size of a request body"`
+	MaxUploadBytes    int64         `yaml:"maxUploadBytes" env:"HTTP_MAX_UPLOAD_BYTES" desc:"maximum size of an uploaded file, such as a quiz import"`
+	MaxFeedbackBytes  int64         `yaml:"maxFeedbackBytes" env:"HTTP_MAX_FEEDBACK_BYTES" desc:"maximum size of a feedback or exercise report request"`
+	TrustedProxies    []string      `yaml:"trustedProxies" env:"HTTP_TRUSTED_PROXIES" desc:"comma separated addresses or networks of reverse proxies trusted to set X-Forwarded-For"`
+}
+
+type CORS struct {
+	AllowedOrigins []string `yaml:"allowedOrigins" env:"CORS_ALLOWED_ORIGINS" desc:"comma separated origins allowed to call the api, or *"`
+}
+
+// Feedback is where submitted feedback is sent. It goes to every channel that
+// is configured, and nowhere when none is.
+type Feedback struct {
+	Discord Discord `yaml:"discord"`
+	Email   Email   `yaml:"email"`
+	Webhook Webhook `yaml:"webhook"`
+	Slack   Slack   `yaml:"slack"`
+	File    string  `yaml:"file" env:"FEEDBACK_FILE" desc:"file feedback is appended to as JSON lines"`
+	Log     bool    `yaml:"log" env:"FEEDBACK_LOG" desc:"write feedback to the log"`
+	// ProofOfWork is asked of feedback without a user ID.
+	ProofOfWork ProofOfWork `yaml:"proofOfWork"`
+}
+
+type ProofOfWork struct {
+	Difficulty int    `yaml:"difficulty" env:"FEEDBACK_POW_DIFFICULTY" desc:"leading zero bits of the proof of work asked of anonymous feedback; 0 disables it"`
+	Secret     string `yaml:"secret" env:"FEEDBACK_POW_SECRET" desc:"key challenges are signed with; random per start when empty, which only suits a single server"`
+}
+
+// Discord is configured when both fields are set.
+type Discord struct {
+	BotToken  string `yaml:"botToken" env:"DISCORD_BOT_TOKEN" desc:"token of the Discord bot that posts feedback"`
+	ChannelID string `yaml:"channelId" env:"DISCORD_FEEDBACK_CHANNEL_ID" desc:"Discord channel feedback is posted to"`
+}
+
+// Outbox configures the background delivery of notifications, such as
+// feedback.
+type Outbox struct {
+	Workers         int           `yaml:"workers" env:"OUTBOX_WORKERS" desc:"number of notifications delivered concurrently"`
+	PollInterval    time.Duration `yaml:"pollInterval" env:"OUTBOX_POLL_INTERVAL" desc:"how often the outbox is checked for notifications to deliver"`
+	MaxAttempts     int           `yaml:"maxAttempts" env:"OUTBOX_MAX_ATTEMPTS" desc:"deliveries after which a notification is dead-lettered"`
+	BaseBackoff     time.Duration `yaml:"baseBackoff" env:"OUTBOX_BASE_BACKOFF" desc:"delay before the first retry; it doubles with every retry"`
+	MaxBackoff      time.Duration `yaml:"maxBackoff" env:"OUTBOX_MAX_BACKOFF" desc:"longest delay between retries"`
+	DeliveryTimeout time.Duration `yaml:"deliveryTimeout" env:"OUTBOX_DELIVERY_TIMEOUT" desc:"timeout for delivering a notification"`
+}
+
+// RateLimit limits the operations that anyone can call and that write, per
+// client IP address. Limits are written as requests/period, such as 10/1m,
+// and an empty limit disables it.
+type RateLimit struct {
+	Store         string `yaml:"store" env:"RATE_LIMIT_STORE" desc:"where rate limit buckets are kept: memory, per server, or postgres, shared"`
+	QuizzesPerIP  string `yaml:"quizzesPerIp" env:"RATE_LIMIT_QUIZZES_PER_IP" desc:"quizzes an IP address may create or import"`
+	AnswersPerIP  string `yaml:"answersPerIp" env:"RATE_LIMIT_ANSWERS_PER_IP" desc:"answer submissions per IP address"`
+	FeedbackPerIP string `yaml:"feedbackPerIp" env:"RATE_LIMIT_FEEDBACK_PER_IP" desc:"feedback and exercise reports per IP address"`
+}
+
+// Reports configures the problems learners report on exercises.
+type Reports struct {
+	FlagThreshold int `yaml:"flagThreshold" env:"REPORT_FLAG_THRESHOLD" desc:"number of open reports that flags an exercise"`
+}
+
+// Admin configures the admin operations of the api, such as feedback triage.
+type Admin struct {
+	Token string `yaml:"token" env:"ADMIN_TOKEN" desc:"bearer token of admin operations; they are disabled when empty"`
+}
+
+// Tracing is where spans are exported. Without an exporter, spans go to the
+// OTLP endpoint when one is set, else to the file when one is set, else
+// nowhere.
+type Tracing struct {
+	Exporter     string `yaml:"exporter" env:"TRACING_EXPORTER" desc:"where spans are exported: otlp, stdout, file or none"`
+	OTLPEndpoint string `yaml:"otlpEndpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" desc:"base URL of the OTLP/HTTP collector, e.g. http://localhost:4318"`
+	File         string `yaml:"file" env:"TRACING_FILE" desc:"file spans are appended to"`
+}
+
+// Email is configured when Host is set.
+type Email struct {
+	Host     string   `yaml:"host" env:"SMTP_HOST" desc:"SMTP server feedback emails are sent through"`
+	Port     int      `yaml:"port" env:"SMTP_PORT" desc:"port of the SMTP server"`
+	Username string   `yaml:"username" env:"SMTP_USERNAME" desc:"user name for the SMTP server"`
+	Password string   `yaml:"password" env:"SMTP_PASSWORD" desc:"password for the SMTP server"`
+	From     string   `yaml:"from" env:"FEEDBACK_EMAIL_FROM" desc:"sender of feedback emails"`
+	To       []string `yaml:"to" env:"FEEDBACK_EMAIL_TO" desc:"comma separated recipients of feedback emails"`
+}
+
+type Webhook struct {
+	URL string `yaml:"url" env:"FEEDBACK_WEBHOOK_URL" desc:"URL feedback is posted to as JSON"`
+}
+
+type Slack struct {
+	WebhookURL string `yaml:"webhookUrl" env:"FEEDBACK_SLACK_WEBHOOK_URL" desc:"Slack-compatible incoming webhook feedback is posted to"`
+}
+
+func Default() Config {
+	return Config{
+		Port:        8080,
+		AutoMigrate: true,
+		LogLevel:    "info",
+		Database: Database{
+			MaxConns:        10,
+			MaxConnLifetime: time.Hour,
+			MaxConnIdleTime: 30 * time.Minute,
+			ConnectTimeout:  5 * time.Second,
+		},
+		HTTP: HTTP{
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+			MaxBodyBytes:      1 << 20,
+			MaxUploadBytes:    100 << 20,
+			MaxFeedbackBytes:  16 << 10,
+		},
+		CORS: CORS{
+			AllowedOrigins: []string{"http://localhost:3000"},
+		},
+		Feedback: Feedback{
+			Email: Email{Port: 587},
+		},
+		Outbox: Outbox{
+			Workers:         4,
+			PollInterval:    2 * time.Second,
+			MaxAttempts:     8,
+			BaseBackoff:     5 * time.Second,
+			MaxBackoff:      time.Hour,
+			DeliveryTimeout: 30 * time.Second,
+		},
+		RateLimit: RateLimit{
+			Store:         "memory",
+			QuizzesPerIP:  "20/1h",
+			AnswersPerIP:  "120/1m",
+			FeedbackPerIP: "10/10m",
+		},
+		Reports: Reports{
+			FlagThreshold: 3,
+		},
+	}
+}
+
+// Level returns the parsed LogLevel.
+func (c *Config) Level() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.LogLevel))
+	return level, err
+}
+
+// Errors collects every problem found while loading the configuration.
+type Errors []string
+
+func (e Errors) Error() string {
+	return "invalid configuration: " + strings.Join(e, "; ")
+}
+
+// Load reads the configuration from the sources described in the package
+// documentation. args are the command line arguments without the program
+// name; the arguments after the flags are returned. It returns Errors when
+// one or more settings are invalid.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+
+	flags := flag.NewFlagSet("languagequiz", flag.ContinueOnError)
+	configFile := flags.String("config", "", "YAML file with settings, defaults to $CONFIG_FILE")
+	envFile := flags.String("env-file", "", "file with environment variables, defaults to .env when it exists")
+	flagValues := make(map[string]string)
+	for _, s := range settings(&cfg) {
+		name := s.flagName()
+		flags.Func(name, fmt.Sprintf("%s (env %s)", s.desc, s.env), func(value string) error {
+			flagValues[name] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *envFile != "" {
+		if err := godotenv.Load(*envFile); err != nil {
+			return nil, nil, fmt.Errorf("failed to load %s: %w", *envFile, err)
+		}
+	} else if err := godotenv.Load(".env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("failed to load .env: %w", err)
+	}
+
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	errs := make(Errors, 0)
+	for _, s := range settings(&cfg) {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(value); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", s.env, err))
+			}
+		}
+		if value, ok := flagValues[s.flagName()]; ok {
+			if err := s.set(value); err != nil {
+				errs = append(errs, fmt.Sprintf("-%s: %s", s.flagName(), err))
+			}
+		}
+	}
+
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	return &cfg, flags.Args(), nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to decode config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) validate() []string {
+	problems := make([]string, 0)
+	if c.Port < 1 || c.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT: must be between 1 and 65535, got %d", c.Port))
+	}
+
+	if _, err := c.Level(); err != nil {
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL: %q is not one of debug, info, warn or error", c.LogLevel))
+	}
+
+	if c.Database.ConnString == "" {
+		problems = append(problems, "POSTGRES_CONN_STRING: is required")
+	}
+	if c.Database.MaxConns < 1 {
+		problems = append(problems, "DB_MAX_CONNS: must be at least 1")
+	}
+	if c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		problems = append(problems, "DB_MIN_CONNS: must be between 0 and DB_MAX_CONNS")
+	}
+
+	for _, s := range settings(c) {
+		if s.value.Type() == durationType && s.value.Int() <= 0 {
+			problems = append(problems, fmt.Sprintf("%s: must be positive", s.env))
+		}
+	}
+
+	if c.HTTP.MaxBodyBytes < 1 {
+		problems = append(problems, "HTTP_MAX_BODY_BYTES: must be at least 1")
+	}
+	if c.HTTP.MaxUploadBytes < c.HTTP.MaxBodyBytes {
+		problems = append(problems, "HTTP_MAX_UPLOAD_BYTES: must be at least HTTP_MAX_BODY_BYTES")
+	}
+	if c.HTTP.MaxFeedbackBytes < 1 || c.HTTP.MaxFeedbackBytes > c.HTTP.MaxBodyBytes {
+		problems = append(problems, "HTTP_MAX_FEEDBACK_BYTES: must be between 1 and HTTP_MAX_BODY_BYTES")
+	}
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems = append(problems, fmt.Sprintf("HTTP_TRUSTED_PROXIES: %q is not an IP address or CIDR network", proxy))
+			}
+		}
+	}
+
+	if c.RateLimit.Store != "memory" && c.RateLimit.Store != "postgres" {
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_STORE: %q is not one of memory or postgres", c.RateLimit.Store))
+	}
+	limits := []struct{ env, limit string }{
+		{"RATE_LIMIT_QUIZZES_PER_IP", c.RateLimit.QuizzesPerIP},
+		{"RATE_LIMIT_ANSWERS_PER_IP", c.RateLimit.AnswersPerIP},
+		{"RATE_LIMIT_FEEDBACK_PER_IP", c.RateLimit.FeedbackPerIP},
+	}
+	for _, l := range limits {
+		if _, err := ratelimit.ParseLimit(l.limit); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", l.env, err))
+		}
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			problems = append(problems, fmt.Sprintf("CORS_ALLOWED_ORIGINS: %q is not an origin like https://example.com", origin))
+		}
+	}
+
+	if c.Outbox.Workers < 1 {
+		problems = append(problems, "OUTBOX_WORKERS: must be at least 1")
+	}
+	if c.Outbox.MaxAttempts < 1 {
+		problems = append(problems, "OUTBOX_MAX_ATTEMPTS: must be at least 1")
+	}
+	if c.Outbox.MaxBackoff < c.Outbox.BaseBackoff {
+		problems = append(problems, "OUTBOX_MAX_BACKOFF: must be at least OUTBOX_BASE_BACKOFF")
+	}
+
+	if c.Reports.FlagThreshold < 1 {
+		problems = append(problems, "REPORT_FLAG_THRESHOLD: must be at least 1")
+	}
+
+	switch c.Tracing.Exporter {
+	case "", "stdout", "none":
+	case "otlp":
+		if c.Tracing.OTLPEndpoint == "" {
+			problems = append(problems, "OTEL_EXPORTER_OTLP_ENDPOINT: is required when TRACING_EXPORTER is otlp")
+		}
+	case "file":
+		if c.Tracing.File == "" {
+			problems = append(problems, "TRACING_FILE: is required when TRACING_EXPORTER is file")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("TRACING_EXPORTER: %q is not one of otlp, stdout, file or none", c.Tracing.Exporter))
+	}
+	if endpoint := c.Tracing.OTLPEndpoint; endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("OTEL_EXPORTER_OTLP_ENDPOINT: %q is not a URL like http://localhost:4318", endpoint))
+		}
+	}
+
+	if token := c.Admin.Token; token != "" && len(token) < 16 {
+		problems = append(problems, "ADMIN_TOKEN: must be at least 16 characters")
+	}
+
+	discord := c.Feedback.Discord
+	if (discord.BotToken == "") != (discord.ChannelID == "") {
+		problems = append(problems, "DISCORD_BOT_TOKEN and DISCORD_FEEDBACK_CHANNEL_ID: set both or neither")
+	}
+	if email := c.Feedback.Email; email.Host != "" {
+		if email.Port < 1 || email.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("SMTP_PORT: must be between 1 and 65535, got %d", email.Port))
+		}
+		if email.From == "" {
+			problems = append(problems, "FEEDBACK_EMAIL_FROM: is required when SMTP_HOST is set")
+		}
+		if len(email.To) == 0 {
+			problems = append(problems, "FEEDBACK_EMAIL_TO: is required when SMTP_HOST is set")
+		}
+	}
+	webhooks := []struct{ env, url string }{
+		{"FEEDBACK_WEBHOOK_URL", c.Feedback.Webhook.URL},
+		{"FEEDBACK_SLACK_WEBHOOK_URL", c.Feedback.Slack.WebhookURL},
+	}
+	for _, webhook := range webhooks {
+		if webhook.url == "" {
+			continue
+		}
+		if u, err := url.Parse(webhook.url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("%s: %q is not an http or https URL", webhook.env, webhook.url))
+		}
+	}
+	if pow := c.Feedback.ProofOfWork; pow.Difficulty < 0 || pow.Difficulty > 32 {
+		problems = append(problems, "FEEDBACK_POW_DIFFICULTY: must be between 0 and 32")
+	}
+	return problems
+}
+
+// setting is a field of Config that has an environment variable.
+type setting struct {
+	env   string
+	desc  string
+	value reflect.Value
+}
+
+func (s setting) flagName() string {
+	return strings.ToLower(strings.ReplaceAll(s.env, "_", "-"))
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (s setting) set(value string) error {
+	switch {
+	case s.value.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		s.value.SetInt(int64(d))
+	case s.value.Kind() == reflect.String:
+		s.value.SetString(value)
+	case s.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		s.value.SetBool(b)
+	case s.value.Kind() == reflect.Int || s.value.Kind() == reflect.Int32 || s.value.Kind() == reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, s.value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		s.value.SetInt(i)
+	case s.value.Kind() == reflect.Slice && s.value.Type().Elem().Kind() == reflect.String:
+		items := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
+
+// settings returns the fields of cfg that have an env tag, in declaration
+// order.
+func settings(cfg *Config) []setting {
+	result := make([]setting, 0)
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if env := field.Tag.Get("env"); env != "" {
+				result = append(result, setting{env: env, desc: field.Tag.Get("desc"), value: v.Field(i)})
+			} else if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem())
+	return result
+}
@@ -0,0 +1,61 @@
+package feedback
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// discordCheckInterval is how long the result of a check is reused, so that
+// frequent readiness probes do not run into Discord's rate limits.
+const discordCheckInterval = 5 * time.Minute
+
+// DiscordNotifier posts feedback to a Discord channel as a bot.
+type DiscordNotifier struct {
+	session   *discordgo.Session
+	channelID string
+
+	checkMu   sync.Mutex
+	checkedAt time.Time
+	checkErr  error
+}
+
+func NewDiscordNotifier(botToken, channelID string) (*DiscordNotifier, error) {
+	session, err := discordgo.New("Bot " + botToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Discord session: %w", err)
+	}
+	return &DiscordNotifier{session: session, channelID: channelID}, nil
+}
+
+func (n *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+// Notify posts f to the channel. discordgo cannot send a nonce, so a retried
+// delivery can post f twice.
+func (n *DiscordNotifier) Notify(ctx context.Context, f Feedback) error {
+	_, err := n.session.ChannelMessageSend(n.channelID, f.String(), discordgo.WithContext(ctx))
+	return err
+}
+
+// Check checks that the bot can see the channel.
+func (n *DiscordNotifier) Check(ctx context.Context) error {
+	n.checkMu.Lock()
+	defer n.checkMu.Unlock()
+	if !n.checkedAt.IsZero() && time.Since(n.checkedAt) < discordCheckInterval {
+		return n.checkErr
+	}
+
+	_, err := n.session.Channel(n.channelID, discordgo.WithContext(ctx))
+	if err != nil {
+		err = fmt.Errorf("failed to look up channel: %w", err)
+	}
+
+	n.checkedAt = time.Now()
+	n.checkErr = err
+	return err
+}
@@ -0,0 +1,49 @@
+package feedback
+
+import (
+	"context"
+
+	"languagequiz/outbox"
+
+	"gopkg.in/gomail.v2"
+)
+
+// EmailNotifier sends feedback by email over SMTP.
+type EmailNotifier struct {
+	dialer *gomail.Dialer
+	from   string
+	to     []string
+}
+
+func NewEmailNotifier(host string, port int, username, password, from string, to []string) *EmailNotifier {
+	return &EmailNotifier{
+		dialer: gomail.NewDialer(host, port, username, password),
+		from:   from,
+		to:     to,
+	}
+}
+
+func (n *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Notify sends an email to all recipients. gomail cannot be canceled, so ctx
+// is only checked before dialing.
+func (n *EmailNotifier) Notify(ctx context.Context, f Feedback) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", n.from)
+	msg.SetHeader("To", n.to...)
+	msg.SetHeader("Subject", "Feedback on "+f.PagePath)
+	msg.SetDateHeader("Date", f.ReceivedAt)
+	// Mail clients show a message once per Message-ID, which hides
+	// duplicates when a delivery is retried after the server accepted it.
+	if key := outbox.IdempotencyKey(ctx); key != "" {
+		msg.SetHeader("Message-ID", "<"+key+"@languagequiz>")
+	}
+	msg.SetBody("text/plain", f.String())
+	return n.dialer.DialAndSend(msg)
+}
@@ -0,0 +1,141 @@
+// Package feedback delivers feedback submitted by users to the channels the
+// maintainers read, such as a Discord channel, an email inbox or a webhook.
+// Feedback is queued in the outbox when it is stored and delivered to each
+// channel separately, so a failing channel is retried on its own.
+package feedback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"languagequiz/outbox"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("languagequiz/feedback")
+
+// Feedback is submitted by a user about a page, and optionally about a quiz
+// or an exercise on it.
+type Feedback struct {
+	ID         string     `json:"id"`
+	Text       string     `json:"text"`
+	PagePath   string     `json:"pagePath"`
+	UserAgent  string     `json:"userAgent,omitempty"`
+	UserID     *string    `json:"userId,omitempty"`
+	QuizID     *string    `json:"quizId,omitempty"`
+	ExerciseID *string    `json:"exerciseId,omitempty"`
+	Status     Status     `json:"status"`
+	Tags       []string   `json:"tags"`
+	ReceivedAt time.Time  `json:"receivedAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// Status is where feedback is in triage.
+type Status string
+
+const (
+	StatusOpen     Status = "open"
+	StatusResolved Status = "resolved"
+)
+
+// String formats f as a message for people to read.
+func (f Feedback) String() string {
+	msg := "Received feedback\nText: " + f.Text + "\nPage: " + f.PagePath
+	if f.QuizID != nil {
+		msg += "\nQuiz: " + *f.QuizID
+	}
+	if f.ExerciseID != nil {
+		msg += "\nExercise: " + *f.ExerciseID
+	}
+	return msg
+}
+
+// Notifier sends feedback to a channel.
+type Notifier interface {
+	// Name identifies the channel in errors and traces.
+	Name() string
+	Notify(ctx context.Context, f Feedback) error
+}
+
+// Checker is implemented by notifiers that can check whether their channel
+// is reachable without sending anything.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// Fanout delivers feedback queued in the outbox to the notifier named by
+// the message's destination.
+type Fanout struct {
+	notifiers []Notifier
+}
+
+func NewFanout(notifiers ...Notifier) *Fanout {
+	return &Fanout{notifiers: notifiers}
+}
+
+// Names returns the names of the notifiers, which are the destinations
+// feedback is queued for.
+func (f *Fanout) Names() []string {
+	names := make([]string, 0, len(f.notifiers))
+	for _, notifier := range f.notifiers {
+		names = append(names, notifier.Name())
+	}
+	return names
+}
+
+// Deliver sends the feedback in msg to its notifier. Messages that cannot be
+// decoded, or are meant for a notifier that is no longer configured, fail
+// permanently.
+func (f *Fanout) Deliver(ctx context.Context, msg outbox.Message) error {
+	var notifier Notifier
+	for _, n := range f.notifiers {
+		if n.Name() == msg.Destination {
+			notifier = n
+		}
+	}
+	if notifier == nil {
+		return outbox.Permanent(fmt.Errorf("notifier %q is not configured", msg.Destination))
+	}
+
+	var fb Feedback
+	if err := json.Unmarshal(msg.Payload, &fb); err != nil {
+		return outbox.Permanent(fmt.Errorf("failed to decode feedback: %w", err))
+	}
+	return notify(ctx, notifier, fb)
+}
+
+func notify(ctx context.Context, notifier Notifier, fb Feedback) error {
+	ctx, span := tracer.Start(ctx, "Notifier.Notify", trace.WithAttributes(
+		attribute.String("feedback.id", fb.ID),
+		attribute.String("feedback.notifier", notifier.Name()),
+	))
+	defer span.End()
+
+	if err := notifier.Notify(ctx, fb); err != nil {
+		err = fmt.Errorf("%s: %w", notifier.Name(), err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+// Check checks the notifiers that implement Checker.
+func (f *Fanout) Check(ctx context.Context) error {
+	errs := make([]error, 0)
+	for _, notifier := range f.notifiers {
+		if checker, ok := notifier.(Checker); ok {
+			if err := checker.Check(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", notifier.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,51 @@
+package feedback
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("feedback not found")
+	// ErrUnknownReference is returned when feedback refers to a quiz or an
+	// exercise that does not exist.
+	ErrUnknownReference = errors.New("quiz or exercise does not exist")
+)
+
+type Storage interface {
+	CreateFeedback(ctx context.Context, cmd CreateFeedbackCommand) (*Feedback, error)
+	FindFeedback(ctx context.Context, filter Filter) ([]Feedback, error)
+	FindFeedbackByID(ctx context.Context, id string) (*Feedback, error)
+	// UpdateTags adds the tags in add and then removes those in remove.
+	UpdateTags(ctx context.Context, id string, add, remove []string) (*Feedback, error)
+	SetStatus(ctx context.Context, id string, status Status) (*Feedback, error)
+}
+
+type CreateFeedbackCommand struct {
+	Text       string
+	PagePath   string
+	UserAgent  string
+	UserID     *string
+	QuizID     *string
+	ExerciseID *string
+	// Notifiers names the notifiers the feedback is sent to. It is queued
+	// for them when it is stored and sent in the background.
+	Notifiers []string
+}
+
+// IdempotencyKey identifies the delivery of feedback to a notifier.
+func IdempotencyKey(feedbackID, notifier string) string {
+	return "feedback:" + feedbackID + ":" + notifier
+}
+
+// Filter selects feedback. Empty fields match everything. Feedback is listed
+// newest first.
+type Filter struct {
+	Status     Status
+	Tag        string
+	QuizID     string
+	ExerciseID string
+	PagePath   string
+	Limit      int
+	Offset     int
+}
@@ -2,74 +2,307 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"strconv"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"languagequiz/api"
+	"languagequiz/config"
+	"languagequiz/feedback"
+	"languagequiz/logging"
+	"languagequiz/migrations"
+	"languagequiz/outbox"
 	"languagequiz/postgres"
+	"languagequiz/pow"
+	"languagequiz/ratelimit"
+	"languagequiz/tracing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
-
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// proofOfWorkTTL is how long a proof of work challenge can be solved.
+const proofOfWorkTTL = 5 * time.Minute
+
+const usage = `Usage: main [flags] [migrate <command>]
+
+Without a command, main runs the server and applies pending migrations unless
+AUTO_MIGRATE is false.
+
+`
+
 func main() {
-	err := godotenv.Load(".env")
+	slog.SetDefault(logging.New(os.Stdout, slog.LevelInfo))
+
+	cfg, args, err := config.Load(os.Args[1:])
 	if err != nil {
-		log.Fatal("Failed loading .env file: ", err)
+		var problems config.Errors
+		if errors.As(err, &problems) {
+			for _, problem := range problems {
+				slog.Error("invalid configuration", "problem", problem)
+			}
+			os.Exit(1)
+		}
+		fatal("failed to load configuration", err)
 	}
 
+	level, _ := cfg.Level()
+	slog.SetDefault(logging.New(os.Stdout, level))
+	// Requests are logged by the api, so gin's own debug output would only
+	// add lines that are not JSON.
+	gin.SetMode(gin.ReleaseMode)
+
 	zoneName, _ := time.Now().Zone()
-	fmt.Println("Configured time zone: ", zoneName)
+	slog.Info("configured time zone", "zone", zoneName)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter: cfg.Tracing.Exporter,
+		Endpoint: cfg.Tracing.OTLPEndpoint,
+		File:     cfg.Tracing.File,
+	})
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
 
-	connString := os.Getenv("POSTGRES_CONN_STRING")
-	config, err := pgxpool.ParseConfig(connString)
+	poolConfig, err := cfg.Database.PoolConfig()
 	if err != nil {
-		log.Fatal("Error parsing connection config: ", err)
+		fatal("failed to parse connection config", err)
 	}
+	poolConfig.ConnConfig.Tracer = postgres.NewQueryTracer()
 
-	dbpool, err := pgxpool.NewWithConfig(context.Background(), config)
+	dbpool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
-		log.Fatal("Unable to create connection pool: ", err)
+		fatal("failed to create connection pool", err)
 	}
 	defer dbpool.Close()
 
 	err = dbpool.Ping(context.Background())
 	if err != nil {
-		log.Fatal("Error pinging the database: ", err)
+		fatal("failed to ping the database", err)
 	}
 
-	fmt.Println("Database connection successful!")
+	slog.Info("connected to the database")
 
-	m, err := migrate.New("file://migrations", connString)
-	if err != nil {
-		log.Fatal("Failed to create Migrate instance: ", err)
+	if len(args) > 0 {
+		if args[0] != "migrate" {
+			fmt.Fprint(os.Stderr, usage+migrations.CommandUsage)
+			os.Exit(2)
+		}
+		runMigrateCommand(cfg.Database.ConnString, args[1:])
+		return
 	}
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatal("Failed to migrate the database: ", err)
+
+	if cfg.AutoMigrate {
+		autoMigrate(cfg.Database.ConnString)
 	}
 
+	prometheus.MustRegister(postgres.NewPoolCollector(dbpool))
+
 	quizStorage := postgres.NewQuizStorage(dbpool)
 	quizHandler := api.NewQuizHandler(quizStorage)
 
-	feedbackHandler := api.NewFeedbackHandler(os.Getenv("DISCORD_BOT_TOKEN"), os.Getenv("DISCORD_FEEDBACK_CHANNEL_ID"))
+	notifiers, err := newFeedbackNotifiers(cfg.Feedback)
+	if err != nil {
+		fatal("failed to set up feedback", err)
+	}
+	feedbackStorage := postgres.NewFeedbackStorage(dbpool)
+	verifier, err := newProofOfWorkVerifier(cfg.Feedback.ProofOfWork)
+	if err != nil {
+		fatal("failed to set up proof of work", err)
+	}
+	feedbackHandler := api.NewFeedbackHandler(feedbackStorage, notifiers, verifier)
+
+	reportHandler := api.NewReportHandler(postgres.NewReportStorage(dbpool), cfg.Reports.FlagThreshold)
+
+	latestMigration, err := migrations.Latest()
+	if err != nil {
+		fatal("failed to list migrations", err)
+	}
+	healthHandler := api.NewHealthHandler(
+		api.PingCheck("database", dbpool),
+		api.MigrationCheck(func(ctx context.Context) (uint, bool, error) {
+			return migrations.Version(ctx, dbpool)
+		}, latestMigration),
+		feedbackHandler.FeedbackCheck(),
+	)
+
+	var handlers = api.NewHandlers(quizHandler, feedbackHandler, reportHandler, healthHandler)
+
+	var server = api.NewServer(handlers, api.ServerOptions{
+		AllowedOrigins:    cfg.CORS.AllowedOrigins,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		ShutdownTimeout:   cfg.HTTP.ShutdownTimeout,
+		MaxBodyBytes:      cfg.HTTP.MaxBodyBytes,
+		MaxUploadBytes:    cfg.HTTP.MaxUploadBytes,
+		MaxFeedbackBytes:  cfg.HTTP.MaxFeedbackBytes,
+		AdminToken:        cfg.Admin.Token,
+		RateLimiter:       newRateLimiter(cfg.RateLimit, dbpool),
+		TrustedProxies:    cfg.HTTP.TrustedProxies,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Without notifiers nothing is queued. Messages queued before they were
+	// removed from the configuration wait until they are added again.
+	outboxDone := make(chan struct{})
+	if notifiers != nil {
+		worker := outbox.NewWorker(postgres.NewOutboxStorage(dbpool), notifiers, outbox.Options{
+			Workers:         cfg.Outbox.Workers,
+			PollInterval:    cfg.Outbox.PollInterval,
+			MaxAttempts:     cfg.Outbox.MaxAttempts,
+			BaseBackoff:     cfg.Outbox.BaseBackoff,
+			MaxBackoff:      cfg.Outbox.MaxBackoff,
+			DeliveryTimeout: cfg.Outbox.DeliveryTimeout,
+		})
+		go func() {
+			worker.Run(ctx)
+			close(outboxDone)
+		}()
+	} else {
+		close(outboxDone)
+	}
+
+	slog.Info("listening", "port", cfg.Port)
+	if err := server.Start(ctx, cfg.Port); err != nil {
+		fatal("server stopped", err)
+	}
+	slog.Info("shutting down")
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	select {
+	case <-outboxDone:
+	case <-flushCtx.Done():
+		slog.Error("outbox deliveries still running at shutdown")
+	}
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("failed to flush spans", "error", err.Error())
+	}
+}
 
-	var handlers = api.NewHandlers(quizHandler, feedbackHandler)
+func runMigrateCommand(connString string, args []string) {
+	m, err := migrations.New(connString)
+	if err != nil {
+		fatal("failed to create Migrate instance", err)
+	}
+	defer m.Close()
 
-	var server = api.NewServer(handlers)
-	log.Fatal(server.Start(mustParseInt(os.Getenv("PORT"))))
+	if err := migrations.RunCommand(m, args, os.Stdout); err != nil {
+		fatal("failed to migrate the database", err)
+	}
 }
 
-func mustParseInt(s string) int {
-	i, err := strconv.Atoi(s)
+// autoMigrate applies the pending migrations before the server starts and
+// logs the outcome, rather than printing the status like the migrate command.
+func autoMigrate(connString string) {
+	m, err := migrations.New(connString)
+	if err != nil {
+		fatal("failed to create Migrate instance", err)
+	}
+	defer m.Close()
+
+	from, err := migrations.Applied(m)
+	if err != nil {
+		fatal("failed to migrate the database", err)
+	}
+	if err := migrations.Up(m); err != nil {
+		fatal("failed to migrate the database", err)
+	}
+	to, err := migrations.Applied(m)
 	if err != nil {
-		panic(err)
+		fatal("failed to migrate the database", err)
+	}
+
+	if from == to {
+		slog.Info("database schema is up to date", "version", to)
+	} else {
+		slog.Info("migrated the database", "from_version", from, "to_version", to)
+	}
+}
+
+// newFeedbackNotifiers returns the notifiers of every configured channel, or
+// nil when there is none.
+func newFeedbackNotifiers(cfg config.Feedback) (*feedback.Fanout, error) {
+	notifiers := make([]feedback.Notifier, 0)
+	if cfg.Discord.BotToken != "" {
+		discord, err := feedback.NewDiscordNotifier(cfg.Discord.BotToken, cfg.Discord.ChannelID)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, discord)
+	}
+	if email := cfg.Email; email.Host != "" {
+		notifiers = append(notifiers, feedback.NewEmailNotifier(email.Host, email.Port, email.Username, email.Password, email.From, email.To))
+	}
+	if cfg.Webhook.URL != "" {
+		notifiers = append(notifiers, feedback.NewWebhookNotifier(cfg.Webhook.URL))
 	}
-	return i
+	if cfg.Slack.WebhookURL != "" {
+		notifiers = append(notifiers, feedback.NewSlackNotifier(cfg.Slack.WebhookURL))
+	}
+	if cfg.File != "" {
+		notifiers = append(notifiers, feedback.NewFileNotifier(cfg.File))
+	}
+	if cfg.Log {
+		notifiers = append(notifiers, feedback.NewLogNotifier())
+	}
+
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+	for _, notifier := range notifiers {
+		slog.Info("sending feedback", "notifier", notifier.Name())
+	}
+	return feedback.NewFanout(notifiers...), nil
+}
+
+// newProofOfWorkVerifier returns the verifier of anonymous feedback, or nil
+// when it needs no proof of work.
+func newProofOfWorkVerifier(cfg config.ProofOfWork) (*pow.Verifier, error) {
+	if cfg.Difficulty == 0 {
+		return nil, nil
+	}
+	secret := []byte(cfg.Secret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %w", err)
+		}
+	}
+	slog.Info("asking proof of work of anonymous feedback", "difficulty", cfg.Difficulty)
+	return pow.NewVerifier(secret, cfg.Difficulty, proofOfWorkTTL), nil
+}
+
+// newRateLimiter returns the limiter of the operations that anyone can call.
+// The limits were checked when the configuration was loaded.
+func newRateLimiter(cfg config.RateLimit, dbpool *pgxpool.Pool) *ratelimit.Limiter {
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.Store == "postgres" {
+		store = postgres.NewRateLimitStore(dbpool)
+	}
+	limit := func(s string) ratelimit.Limit {
+		l, _ := ratelimit.ParseLimit(s)
+		return l
+	}
+	return ratelimit.NewLimiter(store,
+		ratelimit.Rule{Name: api.RateLimitQuizzes, PerIP: limit(cfg.QuizzesPerIP)},
+		ratelimit.Rule{Name: api.RateLimitAnswers, PerIP: limit(cfg.AnswersPerIP)},
+		ratelimit.Rule{Name: api.RateLimitFeedback, PerIP: limit(cfg.FeedbackPerIP)},
+	)
+}
+
+// fatal logs err and exits. Deferred functions do not run.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err.Error())
+	os.Exit(1)
 }
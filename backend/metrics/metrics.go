@@ -0,0 +1,147 @@
+// Package metrics defines the Prometheus metrics of the service. They are
+// registered with the default registry, which also exports the Go runtime and
+// process metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "languagequiz"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	quizzesCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quizzes_created_total",
+		Help:      "Number of quizzes created, by the format they were created from.",
+	}, []string{"source"})
+
+	answersGraded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "answers_graded_total",
+		Help:      "Number of answers graded by exercise type and correctness.",
+	}, []string{"type", "correct"})
+
+	submissionCorrectRatio = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "submission_correct_ratio",
+		Help:      "Share of correct answers in each graded submission.",
+		Buckets:   prometheus.LinearBuckets(0.1, 0.1, 10),
+	})
+
+	feedbackSubmissions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feedback_submissions_total",
+		Help:      "Number of feedback submissions.",
+	})
+
+	feedbackRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feedback_rejections_total",
+		Help:      "Number of feedback submissions rejected as spam, by the check that caught them.",
+	}, []string{"check"})
+
+	rateLimitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_requests_total",
+		Help:      "Number of requests refused by a rate limit, by rule.",
+	}, []string{"rule"})
+
+	exerciseReports = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exercise_reports_total",
+		Help:      "Number of problems reported on exercises, by reason.",
+	}, []string{"reason"})
+
+	exercisesFlagged = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exercises_flagged_total",
+		Help:      "Number of times an exercise was flagged for reaching the report threshold.",
+	})
+
+	outboxDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "deliveries_total",
+		Help:      "Number of outbox delivery attempts by destination and result: delivered, retry or dead.",
+	}, []string{"destination", "result"})
+)
+
+// ObserveHTTPRequest records a handled request. route is the route pattern,
+// such as /v1/quizzes/:id, so that ids do not create a series each.
+func ObserveHTTPRequest(method, route, status string, seconds float64) {
+	httpRequests.WithLabelValues(method, route, status).Inc()
+	httpRequestDuration.WithLabelValues(method, route, status).Observe(seconds)
+}
+
+// QuizCreated records a quiz created from source, such as api or csv.
+func QuizCreated(source string) {
+	quizzesCreated.WithLabelValues(source).Inc()
+}
+
+// AnswerGraded records a graded answer to an exercise of exerciseType.
+func AnswerGraded(exerciseType string, correct bool) {
+	label := "false"
+	if correct {
+		label = "true"
+	}
+	answersGraded.WithLabelValues(exerciseType, label).Inc()
+}
+
+// SubmissionGraded records the share of correct answers in a submission.
+// Empty submissions are not recorded.
+func SubmissionGraded(correct, total int) {
+	if total == 0 {
+		return
+	}
+	submissionCorrectRatio.Observe(float64(correct) / float64(total))
+}
+
+// FeedbackSubmitted records a feedback submission.
+func FeedbackSubmitted() {
+	feedbackSubmissions.Inc()
+}
+
+// FeedbackRejected records feedback caught by check, such as honeypot.
+func FeedbackRejected(check string) {
+	feedbackRejections.WithLabelValues(check).Inc()
+}
+
+// RateLimited records a request refused by the rate limit rule.
+func RateLimited(rule string) {
+	rateLimitedRequests.WithLabelValues(rule).Inc()
+}
+
+// ExerciseReported records a problem reported on an exercise.
+func ExerciseReported(reason string) {
+	exerciseReports.WithLabelValues(reason).Inc()
+}
+
+// ExerciseFlagged records an exercise that reached the report threshold.
+func ExerciseFlagged() {
+	exercisesFlagged.Inc()
+}
+
+// OutboxDelivered records an attempt to deliver an outbox message to
+// destination. result is delivered, retry when the message is tried again
+// later, or dead when it is given up on.
+func OutboxDelivered(destination, result string) {
+	outboxDeliveries.WithLabelValues(destination, result).Inc()
+}
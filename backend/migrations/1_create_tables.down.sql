@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS exercise;
+DROP TABLE IF EXISTS quiz_section;
+DROP TABLE IF EXISTS quiz;
+
+DROP FUNCTION IF EXISTS trigger_set_updated_at();
+
+COMMIT;
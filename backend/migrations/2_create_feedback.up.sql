@@ -0,0 +1,27 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS feedback(
+    id UUID NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "text" TEXT NOT NULL,
+    page_path TEXT NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    user_id TEXT,
+    quiz_id UUID REFERENCES quiz (id) ON DELETE SET NULL,
+    exercise_id UUID REFERENCES exercise (id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE TRIGGER set_updated_at
+    BEFORE UPDATE
+    ON feedback
+    FOR EACH ROW
+EXECUTE PROCEDURE trigger_set_updated_at();
+
+CREATE INDEX IF NOT EXISTS feedback_status_created_at_idx ON feedback (status, created_at DESC);
+CREATE INDEX IF NOT EXISTS feedback_tags_idx ON feedback USING GIN (tags);
+
+COMMIT;
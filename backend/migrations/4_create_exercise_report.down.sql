@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS exercise_flag;
+DROP TABLE IF EXISTS exercise_report;
+
+COMMIT;
@@ -0,0 +1,31 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS exercise_report(
+    id UUID NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    exercise_id UUID NOT NULL REFERENCES exercise (id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    "comment" TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE TRIGGER set_updated_at
+    BEFORE UPDATE
+    ON exercise_report
+    FOR EACH ROW
+EXECUTE PROCEDURE trigger_set_updated_at();
+
+CREATE INDEX IF NOT EXISTS exercise_report_exercise_id_idx ON exercise_report (exercise_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS exercise_report_status_created_at_idx ON exercise_report (status, created_at DESC);
+
+-- An exercise is flagged when it reaches the report threshold, until its
+-- reports are resolved.
+CREATE TABLE IF NOT EXISTS exercise_flag(
+    exercise_id UUID NOT NULL PRIMARY KEY REFERENCES exercise (id) ON DELETE CASCADE,
+    flagged_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+-- Token buckets of the Postgres rate limit store. A bucket that is missing
+-- is full.
+CREATE TABLE IF NOT EXISTS rate_limit_bucket(
+    "key" TEXT NOT NULL PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS rate_limit_bucket_updated_at_idx ON rate_limit_bucket (updated_at);
+
+COMMIT;
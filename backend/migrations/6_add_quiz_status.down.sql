@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS quiz_status_idx;
+ALTER TABLE quiz DROP COLUMN IF EXISTS status;
+
+COMMIT;
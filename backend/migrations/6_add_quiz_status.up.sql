@@ -0,0 +1,10 @@
+BEGIN;
+
+-- Quizzes from before the lifecycle were public, so they start out
+-- published. New quizzes are drafts.
+ALTER TABLE quiz ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published';
+ALTER TABLE quiz ALTER COLUMN status SET DEFAULT 'draft';
+
+CREATE INDEX IF NOT EXISTS quiz_status_idx ON quiz (status);
+
+COMMIT;
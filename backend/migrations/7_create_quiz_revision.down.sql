@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS quiz_attempt;
+ALTER TABLE quiz DROP COLUMN IF EXISTS revision;
+DROP TABLE IF EXISTS quiz_revision;
+
+COMMIT;
@@ -0,0 +1,76 @@
+BEGIN;
+
+-- A revision is a snapshot of a quiz taken when it is published. The
+-- snapshot holds the name, the language tag and the sections with their
+-- exercises, which keep their IDs.
+CREATE TABLE IF NOT EXISTS quiz_revision(
+    id UUID NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    quiz_id UUID NOT NULL REFERENCES quiz (id) ON DELETE CASCADE,
+    "number" INTEGER NOT NULL,
+    rolled_back_from INTEGER,
+    snapshot JSONB NOT NULL,
+    UNIQUE (quiz_id, "number")
+);
+
+-- revision is the number of the current revision, which is shown to
+-- learners unless the quiz is a draft.
+ALTER TABLE quiz ADD COLUMN IF NOT EXISTS revision INTEGER;
+
+CREATE TABLE IF NOT EXISTS quiz_attempt(
+    id UUID NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    quiz_id UUID NOT NULL REFERENCES quiz (id) ON DELETE CASCADE,
+    -- revision is NULL for answers given to a draft.
+    revision INTEGER,
+    correct_count INTEGER NOT NULL,
+    total_count INTEGER NOT NULL,
+    FOREIGN KEY (quiz_id, revision) REFERENCES quiz_revision (quiz_id, "number") ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS quiz_attempt_quiz_id_created_at_idx ON quiz_attempt (quiz_id, created_at DESC);
+
+-- gen_random_uuid is built into PostgreSQL 13 and later, and comes from
+-- pgcrypto before that.
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- Quizzes that are already published get their first revision. Sections and
+-- exercises inserted together share created_at, so their id breaks the tie,
+-- as it does for the positions of migration 9.
+INSERT INTO quiz_revision (id, quiz_id, "number", snapshot)
+SELECT gen_random_uuid(), q.id, 1, jsonb_build_object(
+    'name', q.name,
+    'languageTag', q.language_tag,
+    'sections', COALESCE((
+        SELECT jsonb_agg(jsonb_build_object(
+            'name', s.name,
+            'exercises', COALESCE((
+                SELECT jsonb_agg(jsonb_build_object(
+                    'id', e.id,
+                    'createdAt', e.created_at,
+                    'updatedAt', e.updated_at,
+                    'feedback', e.feedback,
+                    'type', e.type,
+                    'question', e.question,
+                    'choices', e.choices,
+                    'answer', e.answer,
+                    'sentence', e.sentence,
+                    'correctedSentence', e.corrected_sentence
+                ) ORDER BY e.created_at, e.id)
+                FROM exercise e
+                WHERE e.quiz_section_id = s.id
+            ), '[]'::JSONB)
+        ) ORDER BY s.created_at, s.id)
+        FROM quiz_section s
+        WHERE s.quiz_id = q.id
+    ), '[]'::JSONB)
+)
+FROM quiz q
+WHERE q.status <> 'draft'
+ON CONFLICT DO NOTHING;
+
+UPDATE quiz
+SET revision = 1
+WHERE status <> 'draft' AND revision IS NULL;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS quiz_forked_from_idx;
+ALTER TABLE quiz DROP COLUMN IF EXISTS forked_from;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- forked_from is the quiz a quiz was cloned from, or NULL. It is cleared
+-- when that quiz is deleted.
+ALTER TABLE quiz ADD COLUMN IF NOT EXISTS forked_from UUID REFERENCES quiz (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS quiz_forked_from_idx ON quiz (forked_from);
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS exercise_quiz_section_id_position_idx;
+DROP INDEX IF EXISTS quiz_section_quiz_id_position_idx;
+ALTER TABLE exercise DROP COLUMN IF EXISTS position;
+ALTER TABLE quiz_section DROP COLUMN IF EXISTS position;
+
+COMMIT;
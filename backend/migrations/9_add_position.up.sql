@@ -0,0 +1,31 @@
+BEGIN;
+
+-- position orders the sections of a quiz and the exercises of a section.
+-- Rows inserted together share created_at, so existing rows are numbered by
+-- created_at and then id, like the first revisions taken by migration 7.
+ALTER TABLE quiz_section ADD COLUMN IF NOT EXISTS position INTEGER;
+ALTER TABLE exercise ADD COLUMN IF NOT EXISTS position INTEGER;
+
+UPDATE quiz_section
+SET position = numbered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY quiz_id ORDER BY created_at, id) - 1 AS position
+    FROM quiz_section
+) numbered
+WHERE quiz_section.id = numbered.id AND quiz_section.position IS NULL;
+
+UPDATE exercise
+SET position = numbered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY quiz_section_id ORDER BY created_at, id) - 1 AS position
+    FROM exercise
+) numbered
+WHERE exercise.id = numbered.id AND exercise.position IS NULL;
+
+ALTER TABLE quiz_section ALTER COLUMN position SET NOT NULL;
+ALTER TABLE exercise ALTER COLUMN position SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS quiz_section_quiz_id_position_idx ON quiz_section (quiz_id, position);
+CREATE INDEX IF NOT EXISTS exercise_quiz_section_id_position_idx ON exercise (quiz_section_id, position);
+
+COMMIT;
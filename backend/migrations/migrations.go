@@ -0,0 +1,218 @@
+// Package migrations holds the database migrations, embedded in the binary so
+// that a deploy only has to ship the binary.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// New returns a migrate instance for the embedded migrations.
+func New(connString string) (*migrate.Migrate, error) {
+	source, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", source, connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+	return m, nil
+}
+
+// Up applies every pending migration. It is not an error when there are none.
+func Up(m *migrate.Migrate) error {
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Applied returns the version m has applied, or 0 when no migration ran yet.
+func Applied(m *migrate.Migrate) (uint, error) {
+	version, _, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	return version, nil
+}
+
+// Migration is an embedded migration.
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+// List returns the embedded migrations in the order they are applied.
+func List() ([]Migration, error) {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0)
+	for _, name := range names {
+		version, rest, _ := strings.Cut(strings.TrimSuffix(name, ".up.sql"), "_")
+		v, err := strconv.ParseUint(version, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		migrations = append(migrations, Migration{Version: uint(v), Name: rest})
+	}
+	// Glob sorts by name, which puts 10 before 2.
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the version of the newest embedded migration.
+func Latest() (uint, error) {
+	migrations, err := List()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, errors.New("no migrations embedded")
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Version reads the applied version from the table migrate keeps, without
+// opening a connection of its own. It returns 0 when no migration ran yet.
+func Version(ctx context.Context, dbpool *pgxpool.Pool) (version uint, dirty bool, err error) {
+	var v int64
+	err = dbpool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&v, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return uint(v), dirty, nil
+}
+
+const CommandUsage = `Commands:
+  up [n]         Apply all or the next n pending migrations
+  down [n|-all]  Revert the last n migrations, 1 by default, or all of them
+  status         Print the applied and pending migrations
+  force <v>      Set the version without running migrations, to clear a dirty state
+`
+
+// RunCommand runs a migration command given as arguments, and writes what it
+// did to out as plain text for the command line.
+func RunCommand(m *migrate.Migrate, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("missing migrate command")
+	}
+
+	var err error
+	switch command := args[0]; command {
+	case "up":
+		var n int
+		if n, err = parseSteps(args[1:], 0); err != nil {
+			return err
+		}
+		if n == 0 {
+			err = m.Up()
+		} else {
+			err = m.Steps(n)
+		}
+	case "down":
+		if len(args) == 2 && args[1] == "-all" {
+			err = m.Down()
+			break
+		}
+		var n int
+		if n, err = parseSteps(args[1:], 1); err != nil {
+			return err
+		}
+		err = m.Steps(-n)
+	case "status":
+		return printStatus(m, out)
+	case "force":
+		if len(args) != 2 {
+			return errors.New("force needs exactly one version")
+		}
+		version, parseErr := strconv.Atoi(args[1])
+		if parseErr != nil {
+			return fmt.Errorf("invalid version: %s", args[1])
+		}
+		err = m.Force(version)
+	default:
+		return fmt.Errorf("unknown migrate command: %s", command)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Fprintln(out, "No change")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", args[0], err)
+	}
+	return printStatus(m, out)
+}
+
+func parseSteps(args []string, defaultSteps int) (int, error) {
+	switch len(args) {
+	case 0:
+		return defaultSteps, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid number of migrations: %s", args[0])
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("unexpected arguments: %s", strings.Join(args[1:], " "))
+	}
+}
+
+func printStatus(m *migrate.Migrate, out io.Writer) error {
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("failed to read migration version: %w", err)
+	}
+	applied := err == nil
+
+	migrations, err := List()
+	if err != nil {
+		return err
+	}
+
+	if !applied {
+		fmt.Fprintln(out, "Version: none")
+	} else if dirty {
+		fmt.Fprintf(out, "Version: %d (dirty, fix the database and run force)\n", version)
+	} else {
+		fmt.Fprintf(out, "Version: %d\n", version)
+	}
+	for _, migration := range migrations {
+		state := "pending"
+		if applied && migration.Version <= version {
+			state = "applied"
+			if dirty && migration.Version == version {
+				state = "dirty"
+			}
+		}
+		fmt.Fprintf(out, "  %-8s %d_%s\n", state, migration.Version, migration.Name)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Package outbox delivers outbound messages, such as feedback notifications,
+// in the background. Messages are stored in the same transaction as the
+// change that causes them, and a pool of workers delivers them with
+// exponential backoff. A message that keeps failing, or fails permanently, is
+// dead-lettered: it stays in the store for inspection but is not retried.
+//
+// Delivery is at least once. Every message has an idempotency key that
+// deliverers pass on to receivers that can deduplicate.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	StatusDead      Status = "dead"
+)
+
+type Message struct {
+	ID string
+	// IdempotencyKey is unique per message. Enqueuing a message with a key
+	// that is already stored does nothing.
+	IdempotencyKey string
+	// Destination names the deliverer that handles the message.
+	Destination string
+	Payload     []byte
+	// Attempts counts the failed deliveries before this one.
+	Attempts int
+}
+
+// Store holds messages. Claim hands out each pending message to one worker
+// at a time: a claimed message is leased for the given duration and is
+// claimed again after that if it was not marked in the meantime.
+type Store interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Message, error)
+	MarkDelivered(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id string, reason string, nextAttemptAt time.Time) error
+	MarkDead(ctx context.Context, id string, reason string) error
+}
+
+// Deliverer sends a message to its destination.
+type Deliverer interface {
+	Deliver(ctx context.Context, msg Message) error
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as a failure that retrying cannot fix, such as a
+// rejected payload. The message is dead-lettered right away.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+func isPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+type contextKey struct{}
+
+// IdempotencyKey returns the key of the message being delivered with ctx.
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(contextKey{}).(string)
+	return key
+}
+
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
@@ -0,0 +1,150 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"languagequiz/metrics"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("languagequiz/outbox")
+
+type Options struct {
+	// Workers is the number of messages delivered concurrently.
+	Workers int
+	// PollInterval is how often the store is polled when it had no messages.
+	PollInterval time.Duration
+	// MaxAttempts is the number of deliveries after which a message is
+	// dead-lettered.
+	MaxAttempts int
+	// BaseBackoff is the delay after the first failure. It doubles with every
+	// further failure, up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// DeliveryTimeout bounds a single delivery. Messages are leased for twice
+	// as long.
+	DeliveryTimeout time.Duration
+}
+
+type Worker struct {
+	store     Store
+	deliverer Deliverer
+	options   Options
+}
+
+func NewWorker(store Store, deliverer Deliverer, options Options) *Worker {
+	return &Worker{store: store, deliverer: deliverer, options: options}
+}
+
+// Run delivers messages until ctx is done, then waits for the deliveries in
+// flight to finish.
+func (w *Worker) Run(ctx context.Context) {
+	messages := make(chan Message)
+	var wg sync.WaitGroup
+	for i := 0; i < w.options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range messages {
+				w.deliver(msg)
+			}
+		}()
+	}
+
+	w.poll(ctx, messages)
+	close(messages)
+	wg.Wait()
+}
+
+func (w *Worker) poll(ctx context.Context, messages chan<- Message) {
+	lease := 2 * w.options.DeliveryTimeout
+	for {
+		claimed, err := w.store.Claim(ctx, w.options.Workers, lease)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "failed to claim outbox messages", "error", err.Error())
+		}
+
+		for _, msg := range claimed {
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				// The lease of the remaining messages runs out and they are
+				// claimed again on the next start.
+				return
+			}
+		}
+
+		// A full batch suggests more messages are waiting.
+		if err == nil && len(claimed) == w.options.Workers {
+			continue
+		}
+		select {
+		case <-time.After(w.options.PollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deliver delivers msg and records the outcome. It does not use the context
+// of Run, so that a shutdown lets the delivery finish.
+func (w *Worker) deliver(msg Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.options.DeliveryTimeout)
+	defer cancel()
+
+	ctx, span := tracer.Start(ctx, "outbox.deliver", trace.WithAttributes(
+		attribute.String("outbox.message_id", msg.ID),
+		attribute.String("outbox.destination", msg.Destination),
+		attribute.Int("outbox.attempt", msg.Attempts+1),
+	))
+	defer span.End()
+
+	err := w.deliverer.Deliver(withIdempotencyKey(ctx, msg.IdempotencyKey), msg)
+	if err == nil {
+		metrics.OutboxDelivered(msg.Destination, "delivered")
+		if err := w.store.MarkDelivered(ctx, msg.ID); err != nil {
+			slog.ErrorContext(ctx, "failed to mark outbox message delivered", "message_id", msg.ID, "error", err.Error())
+		}
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	attempts := msg.Attempts + 1
+	logAttrs := []any{"message_id", msg.ID, "destination", msg.Destination, "attempt", attempts, "error", err.Error()}
+
+	if isPermanent(err) || attempts >= w.options.MaxAttempts {
+		metrics.OutboxDelivered(msg.Destination, "dead")
+		slog.ErrorContext(ctx, "dead-lettered outbox message", logAttrs...)
+		err = w.store.MarkDead(ctx, msg.ID, err.Error())
+	} else {
+		metrics.OutboxDelivered(msg.Destination, "retry")
+		slog.WarnContext(ctx, "failed to deliver outbox message, retrying", logAttrs...)
+		err = w.store.MarkFailed(ctx, msg.ID, err.Error(), time.Now().Add(w.backoff(attempts)))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record outbox delivery failure", "message_id", msg.ID, "error", err.Error())
+	}
+}
+
+// backoff returns the delay after the given number of failed attempts, with
+// up to 20% jitter so that messages that failed together spread out.
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.options.BaseBackoff
+	for i := 1; i < attempts && delay < w.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.options.MaxBackoff {
+		delay = w.options.MaxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay - jitter
+}
@@ -0,0 +1,179 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"languagequiz/feedback"
+	"languagequiz/outbox"
+)
+
+// memoryStore holds one message and records what the worker does with it.
+type memoryStore struct {
+	mu            sync.Mutex
+	msg           outbox.Message
+	status        outbox.Status
+	nextAttemptAt time.Time
+	leasedUntil   time.Time
+	// backoffs are the delays the worker asked for after each failure.
+	backoffs []time.Duration
+	done     chan struct{}
+}
+
+func newMemoryStore(msg outbox.Message) *memoryStore {
+	return &memoryStore{msg: msg, status: outbox.StatusPending, done: make(chan struct{})}
+}
+
+func (s *memoryStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]outbox.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.status != outbox.StatusPending || now.Before(s.nextAttemptAt) || now.Before(s.leasedUntil) {
+		return nil, nil
+	}
+	s.leasedUntil = now.Add(lease)
+	return []outbox.Message{s.msg}, nil
+}
+
+func (s *memoryStore) MarkDelivered(ctx context.Context, id string) error {
+	return s.finish(outbox.StatusDelivered)
+}
+
+func (s *memoryStore) MarkFailed(ctx context.Context, id string, reason string, nextAttemptAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msg.Attempts++
+	s.backoffs = append(s.backoffs, time.Until(nextAttemptAt))
+	s.nextAttemptAt = nextAttemptAt
+	s.leasedUntil = time.Time{}
+	return nil
+}
+
+func (s *memoryStore) MarkDead(ctx context.Context, id string, reason string) error {
+	return s.finish(outbox.StatusDead)
+}
+
+func (s *memoryStore) finish(status outbox.Status) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	close(s.done)
+	return nil
+}
+
+func TestWorker(t *testing.T) {
+	const baseBackoff = 20 * time.Millisecond
+
+	tests := []struct {
+		name string
+		// responses are the status codes of the receiver, one per request.
+		// The last one is repeated.
+		responses    []int
+		wantStatus   outbox.Status
+		wantRequests int
+	}{
+		{
+			name:         "delivered",
+			responses:    []int{http.StatusNoContent},
+			wantStatus:   outbox.StatusDelivered,
+			wantRequests: 1,
+		},
+		{
+			name:         "retried after a server error",
+			responses:    []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK},
+			wantStatus:   outbox.StatusDelivered,
+			wantRequests: 3,
+		},
+		{
+			name:         "dead after the last attempt",
+			responses:    []int{http.StatusBadGateway},
+			wantStatus:   outbox.StatusDead,
+			wantRequests: 4,
+		},
+		{
+			name:         "dead after a rejected payload",
+			responses:    []int{http.StatusUnprocessableEntity},
+			wantStatus:   outbox.StatusDead,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+			keys := make([]string, 0)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				keys = append(keys, r.Header.Get("Idempotency-Key"))
+				w.WriteHeader(tt.responses[min(requests, len(tt.responses)-1)])
+				requests++
+			}))
+			defer server.Close()
+
+			payload, err := json.Marshal(feedback.Feedback{ID: "feedback-1", Text: "Typo in exercise 3"})
+			if err != nil {
+				t.Fatalf("failed to encode feedback: %v", err)
+			}
+			store := newMemoryStore(outbox.Message{
+				ID:             "message-1",
+				IdempotencyKey: "feedback-1:webhook",
+				Destination:    "webhook",
+				Payload:        payload,
+			})
+			worker := outbox.NewWorker(store, feedback.NewFanout(feedback.NewWebhookNotifier(server.URL)), outbox.Options{
+				Workers:         1,
+				PollInterval:    5 * time.Millisecond,
+				MaxAttempts:     4,
+				BaseBackoff:     baseBackoff,
+				MaxBackoff:      time.Second,
+				DeliveryTimeout: time.Second,
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			stopped := make(chan struct{})
+			go func() {
+				worker.Run(ctx)
+				close(stopped)
+			}()
+			select {
+			case <-store.done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("message was neither delivered nor dead-lettered")
+			}
+			cancel()
+			<-stopped
+
+			if store.status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", store.status, tt.wantStatus)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+			for i, key := range keys {
+				if key != "feedback-1:webhook" {
+					t.Errorf("request %d: Idempotency-Key = %q, want %q", i+1, key, "feedback-1:webhook")
+				}
+			}
+
+			// Every failure but the last is retried, and each retry doubles
+			// the backoff, less up to 20% jitter.
+			if len(store.backoffs) != tt.wantRequests-1 {
+				t.Errorf("retries = %d, want %d", len(store.backoffs), tt.wantRequests-1)
+			}
+			want := baseBackoff
+			for i, backoff := range store.backoffs {
+				if backoff > want || backoff < want*4/5-10*time.Millisecond {
+					t.Errorf("backoff %d = %s, want %s less up to 20%%", i+1, backoff, want)
+				}
+				want *= 2
+			}
+		})
+	}
+}
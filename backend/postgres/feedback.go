@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"languagequiz/feedback"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const foreignKeyViolation = "23503"
+
+type FeedbackStorage struct {
+	dbpool *pgxpool.Pool
+}
+
+func NewFeedbackStorage(conn *pgxpool.Pool) *FeedbackStorage {
+	return &FeedbackStorage{dbpool: conn}
+}
+
+// CreateFeedback stores feedback and, in the same transaction, queues a
+// message for each of cmd.Notifiers in the outbox.
+func (s *FeedbackStorage) CreateFeedback(ctx context.Context, cmd feedback.CreateFeedbackCommand) (*feedback.Feedback, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate new UUID: %w", err)
+	}
+
+	tx, err := s.dbpool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	entity, err := mapToFeedbackEntity(tx.QueryRow(ctx, `
+		INSERT INTO feedback (id, "text", page_path, user_agent, user_id, quiz_id, exercise_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *
+	`, id, cmd.Text, cmd.PagePath, cmd.UserAgent, cmd.UserID, cmd.QuizID, cmd.ExerciseID))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return nil, feedback.ErrUnknownReference
+		}
+		return nil, fmt.Errorf("failed to insert feedback: %w", err)
+	}
+	fb := mapToFeedback(*entity)
+
+	if len(cmd.Notifiers) > 0 {
+		payload, err := json.Marshal(fb)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode feedback: %w", err)
+		}
+		for _, notifier := range cmd.Notifiers {
+			err = enqueue(ctx, tx, feedback.IdempotencyKey(fb.ID, notifier), notifier, payload)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &fb, nil
+}
+
+func (s *FeedbackStorage) FindFeedback(ctx context.Context, filter feedback.Filter) ([]feedback.Feedback, error) {
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Status != "" {
+		where("status = $%d", string(filter.Status))
+	}
+	if filter.Tag != "" {
+		where("$%d = ANY (tags)", filter.Tag)
+	}
+	if filter.QuizID != "" {
+		where("quiz_id = $%d", filter.QuizID)
+	}
+	if filter.ExerciseID != "" {
+		where("exercise_id = $%d", filter.ExerciseID)
+	}
+	if filter.PagePath != "" {
+		where("page_path = $%d", filter.PagePath)
+	}
+
+	query := `
+		SELECT *
+		FROM feedback`
+	if len(conditions) > 0 {
+		query += `
+		WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(`
+		ORDER BY created_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, len(args)-1, len(args))
+
+	rows, err := s.dbpool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback table: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]feedback.Feedback, 0)
+	for rows.Next() {
+		entity, err := mapToFeedbackEntity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map row to feedback entity: %w", err)
+		}
+		items = append(items, mapToFeedback(*entity))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read feedback table rows: %w", err)
+	}
+	return items, nil
+}
+
+func (s *FeedbackStorage) FindFeedbackByID(ctx context.Context, id string) (*feedback.Feedback, error) {
+	return s.findOne(ctx, "find", id, `
+		SELECT *
+		FROM feedback
+		WHERE id = $1
+	`)
+}
+
+func (s *FeedbackStorage) UpdateTags(ctx context.Context, id string, add, remove []string) (*feedback.Feedback, error) {
+	return s.findOne(ctx, "update", id, `
+		UPDATE feedback
+		SET tags = ARRAY(
+			SELECT DISTINCT tag
+			FROM unnest(tags || $2::TEXT[]) AS tag
+			WHERE tag <> ALL ($3::TEXT[])
+			ORDER BY tag
+		)
+		WHERE id = $1
+		RETURNING *
+	`, add, remove)
+}
+
+func (s *FeedbackStorage) SetStatus(ctx context.Context, id string, status feedback.Status) (*feedback.Feedback, error) {
+	return s.findOne(ctx, "update", id, `
+		UPDATE feedback
+		SET status = $2::TEXT,
+			resolved_at = CASE WHEN $2::TEXT = 'resolved' THEN COALESCE(resolved_at, NOW()) END
+		WHERE id = $1
+		RETURNING *
+	`, string(status))
+}
+
+// findOne runs a query for the feedback with id, which is its first argument.
+func (s *FeedbackStorage) findOne(ctx context.Context, verb, id, query string, args ...any) (*feedback.Feedback, error) {
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, feedback.ErrNotFound
+	}
+
+	entity, err := mapToFeedbackEntity(s.dbpool.QueryRow(ctx, query, append([]any{uuid}, args...)...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, feedback.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s feedback %s: %w", verb, id, err)
+	}
+
+	fb := mapToFeedback(*entity)
+	return &fb, nil
+}
+
+type FeedbackEntity struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Text       string
+	PagePath   string
+	UserAgent  string
+	UserID     *string
+	QuizID     *uuid.UUID
+	ExerciseID *uuid.UUID
+	Status     string
+	Tags       []string
+	ResolvedAt *time.Time
+}
+
+func mapToFeedbackEntity(row pgx.Row) (*FeedbackEntity, error) {
+	var entity FeedbackEntity
+	err := row.Scan(
+		&entity.ID,
+		&entity.CreatedAt,
+		&entity.UpdatedAt,
+		&entity.Text,
+		&entity.PagePath,
+		&entity.UserAgent,
+		&entity.UserID,
+		&entity.QuizID,
+		&entity.ExerciseID,
+		&entity.Status,
+		&entity.Tags,
+		&entity.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+func mapToFeedback(entity FeedbackEntity) feedback.Feedback {
+	return feedback.Feedback{
+		ID:         entity.ID.String(),
+		Text:       entity.Text,
+		PagePath:   entity.PagePath,
+		UserAgent:  entity.UserAgent,
+		UserID:     entity.UserID,
+		QuizID:     uuidString(entity.QuizID),
+		ExerciseID: uuidString(entity.ExerciseID),
+		Status:     feedback.Status(entity.Status),
+		Tags:       entity.Tags,
+		ReceivedAt: entity.CreatedAt,
+		ResolvedAt: entity.ResolvedAt,
+	}
+}
+
+func uuidString(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
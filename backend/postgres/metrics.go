@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector exports the statistics of a connection pool to Prometheus.
+// The statistics are read from the pool on every scrape.
+type PoolCollector struct {
+	dbpool *pgxpool.Pool
+
+	acquiredConns       *prometheus.Desc
+	idleConns           *prometheus.Desc
+	constructingConns   *prometheus.Desc
+	totalConns          *prometheus.Desc
+	maxConns            *prometheus.Desc
+	acquires            *prometheus.Desc
+	acquireDuration     *prometheus.Desc
+	canceledAcquires    *prometheus.Desc
+	emptyAcquires       *prometheus.Desc
+	newConns            *prometheus.Desc
+	maxLifetimeDestroys *prometheus.Desc
+	maxIdleTimeDestroys *prometheus.Desc
+}
+
+func NewPoolCollector(dbpool *pgxpool.Pool) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("languagequiz", "db_pool", name), help, nil, nil)
+	}
+	return &PoolCollector{
+		dbpool:              dbpool,
+		acquiredConns:       desc("acquired_conns", "Number of connections currently in use."),
+		idleConns:           desc("idle_conns", "Number of idle connections."),
+		constructingConns:   desc("constructing_conns", "Number of connections being opened."),
+		totalConns:          desc("total_conns", "Number of open connections."),
+		maxConns:            desc("max_conns", "Maximum number of connections."),
+		acquires:            desc("acquires_total", "Number of successful connection acquires."),
+		acquireDuration:     desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		canceledAcquires:    desc("canceled_acquires_total", "Number of acquires canceled by their context."),
+		emptyAcquires:       desc("empty_acquires_total", "Number of acquires that waited for a connection."),
+		newConns:            desc("new_conns_total", "Number of connections opened."),
+		maxLifetimeDestroys: desc("max_lifetime_destroys_total", "Number of connections closed for reaching their maximum lifetime."),
+		maxIdleTimeDestroys: desc("max_idle_time_destroys_total", "Number of connections closed for being idle too long."),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.dbpool.Stat()
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.constructingConns, float64(stat.ConstructingConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	gauge(c.maxConns, float64(stat.MaxConns()))
+	counter(c.acquires, float64(stat.AcquireCount()))
+	counter(c.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(c.canceledAcquires, float64(stat.CanceledAcquireCount()))
+	counter(c.emptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(c.newConns, float64(stat.NewConnsCount()))
+	counter(c.maxLifetimeDestroys, float64(stat.MaxLifetimeDestroyCount()))
+	counter(c.maxIdleTimeDestroys, float64(stat.MaxIdleDestroyCount()))
+}
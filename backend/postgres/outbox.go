@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"languagequiz/outbox"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutboxStorage struct {
+	dbpool *pgxpool.Pool
+}
+
+func NewOutboxStorage(conn *pgxpool.Pool) *OutboxStorage {
+	return &OutboxStorage{dbpool: conn}
+}
+
+// Claim leases up to limit due messages, oldest due first. Rows locked by
+// another worker's claim are skipped rather than waited for.
+func (s *OutboxStorage) Claim(ctx context.Context, limit int, lease time.Duration) ([]outbox.Message, error) {
+	rows, err := s.dbpool.Query(ctx, `
+		UPDATE outbox
+		SET locked_until = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id
+			FROM outbox
+			WHERE status = 'pending'
+				AND next_attempt_at <= NOW()
+				AND (locked_until IS NULL OR locked_until <= NOW())
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, idempotency_key, destination, payload, attempts
+	`, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]outbox.Message, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		var msg outbox.Message
+		if err := rows.Scan(&id, &msg.IdempotencyKey, &msg.Destination, &msg.Payload, &msg.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to map row to outbox message: %w", err)
+		}
+		msg.ID = id.String()
+		messages = append(messages, msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outbox table rows: %w", err)
+	}
+	return messages, nil
+}
+
+func (s *OutboxStorage) MarkDelivered(ctx context.Context, id string) error {
+	return s.update(ctx, id, `
+		UPDATE outbox
+		SET status = 'delivered',
+			attempts = attempts + 1,
+			locked_until = NULL,
+			delivered_at = NOW()
+		WHERE id = $1
+	`)
+}
+
+func (s *OutboxStorage) MarkFailed(ctx context.Context, id string, reason string, nextAttemptAt time.Time) error {
+	return s.update(ctx, id, `
+		UPDATE outbox
+		SET attempts = attempts + 1,
+			locked_until = NULL,
+			last_error = $2,
+			next_attempt_at = $3
+		WHERE id = $1
+	`, reason, nextAttemptAt)
+}
+
+func (s *OutboxStorage) MarkDead(ctx context.Context, id string, reason string) error {
+	return s.update(ctx, id, `
+		UPDATE outbox
+		SET status = 'dead',
+			attempts = attempts + 1,
+			locked_until = NULL,
+			last_error = $2
+		WHERE id = $1
+	`, reason)
+}
+
+// update runs a statement that changes the message with id, which is its
+// first argument.
+func (s *OutboxStorage) update(ctx context.Context, id, query string, args ...any) error {
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("failed to parse id as uuid: %w", err)
+	}
+
+	tag, err := s.dbpool.Exec(ctx, query, append([]any{uuid}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("outbox message %s does not exist", id)
+	}
+	return nil
+}
+
+// enqueue adds a message to the outbox as part of tx, so that it is only sent
+// when tx commits. A message with the same idempotency key is not added
+// twice.
+func enqueue(ctx context.Context, tx pgx.Tx, idempotencyKey, destination string, payload []byte) error {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("failed to generate new UUID: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox (id, idempotency_key, destination, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (idempotency_key) DO NOTHING
+	`, id, idempotencyKey, destination, payload)
+	if err != nil {
+		return fmt.Errorf("failed to insert outbox message: %w", err)
+	}
+	return nil
+}
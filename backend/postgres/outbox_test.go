@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"languagequiz/migrations"
+	"languagequiz/outbox"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newTestPool connects to the database in TEST_DATABASE_URL and migrates it,
+// or skips the test when it is not set. Tests delete the rows they work on,
+// so it must not point at a database that is in use.
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	connString := os.Getenv("TEST_DATABASE_URL")
+	if connString == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	m, err := migrations.New(connString)
+	if err != nil {
+		t.Fatalf("failed to open migrations: %v", err)
+	}
+	if err := migrations.Up(m); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	m.Close()
+
+	dbpool, err := pgxpool.New(context.Background(), connString)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(dbpool.Close)
+	return dbpool
+}
+
+func TestOutboxStorageClaim(t *testing.T) {
+	ctx := context.Background()
+	dbpool := newTestPool(t)
+	if _, err := dbpool.Exec(ctx, `DELETE FROM outbox`); err != nil {
+		t.Fatalf("failed to clear outbox: %v", err)
+	}
+	storage := NewOutboxStorage(dbpool)
+
+	// enqueueAll adds messages with the given idempotency keys, due in the
+	// order given.
+	enqueueAll := func(keys ...string) {
+		t.Helper()
+		tx, err := dbpool.Begin(ctx)
+		if err != nil {
+			t.Fatalf("failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback(ctx)
+		for i, key := range keys {
+			if err := enqueue(ctx, tx, key, "webhook", []byte(`{}`)); err != nil {
+				t.Fatalf("failed to enqueue %s: %v", key, err)
+			}
+			if _, err := tx.Exec(ctx, `
+				UPDATE outbox
+				SET next_attempt_at = NOW() - $2 * INTERVAL '1 second'
+				WHERE idempotency_key = $1
+			`, key, len(keys)-i); err != nil {
+				t.Fatalf("failed to make %s due: %v", key, err)
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatalf("failed to commit transaction: %v", err)
+		}
+	}
+	// claim claims up to limit messages and returns their idempotency keys.
+	claim := func(limit int, lease time.Duration) []string {
+		t.Helper()
+		messages, err := storage.Claim(ctx, limit, lease)
+		if err != nil {
+			t.Fatalf("failed to claim messages: %v", err)
+		}
+		keys := make([]string, 0)
+		for _, msg := range messages {
+			keys = append(keys, msg.IdempotencyKey)
+		}
+		return keys
+	}
+	wantClaimed := func(got []string, want ...string) {
+		t.Helper()
+		if !reflect.DeepEqual(got, append([]string{}, want...)) {
+			t.Fatalf("claimed %v, want %v", got, want)
+		}
+	}
+
+	enqueueAll("first", "second", "third")
+	// A message with the same idempotency key is not added twice.
+	enqueueAll("first")
+
+	// Leased messages are not claimed again, oldest due first.
+	wantClaimed(claim(1, time.Minute), "first")
+	wantClaimed(claim(1, time.Minute), "second")
+
+	// Rows locked by another claim are skipped rather than waited for.
+	tx, err := dbpool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	if _, err := tx.Exec(ctx, `SELECT id FROM outbox WHERE idempotency_key = 'third' FOR UPDATE`); err != nil {
+		t.Fatalf("failed to lock third: %v", err)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	messages, err := storage.Claim(timeoutCtx, 10, time.Minute)
+	cancel()
+	if err != nil {
+		t.Fatalf("claim waited for a locked row: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Fatalf("claimed %d messages while the only free one is locked", len(messages))
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("failed to roll back transaction: %v", err)
+	}
+
+	// An expired lease makes a message due again.
+	wantClaimed(claim(1, time.Millisecond), "third")
+	time.Sleep(50 * time.Millisecond)
+	messages, err = storage.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to claim messages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].IdempotencyKey != "third" {
+		t.Fatalf("claimed %v after the lease expired, want third", messages)
+	}
+	thirdMsg := messages[0]
+
+	// A failed message is due at its next attempt, with one more attempt.
+	if err := storage.MarkFailed(ctx, thirdMsg.ID, "503 Service Unavailable", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("failed to mark third as failed: %v", err)
+	}
+	messages, err = storage.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to claim messages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Attempts != thirdMsg.Attempts+1 {
+		t.Fatalf("claimed %v after a failure, want third with %d attempts", messages, thirdMsg.Attempts+1)
+	}
+	if err := storage.MarkFailed(ctx, thirdMsg.ID, "503 Service Unavailable", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to mark third as failed: %v", err)
+	}
+
+	// Delivered and dead messages are never claimed again, nor are messages
+	// whose next attempt is still to come.
+	var ids []string
+	rows, err := dbpool.Query(ctx, `SELECT id::TEXT FROM outbox WHERE idempotency_key IN ('first', 'second') ORDER BY idempotency_key`)
+	if err != nil {
+		t.Fatalf("failed to find messages: %v", err)
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("failed to read message id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if len(ids) != 2 {
+		t.Fatalf("found %d messages, want first and second", len(ids))
+	}
+	if err := storage.MarkDelivered(ctx, ids[0]); err != nil {
+		t.Fatalf("failed to mark first as delivered: %v", err)
+	}
+	if err := storage.MarkDead(ctx, ids[1], "422 Unprocessable Entity"); err != nil {
+		t.Fatalf("failed to mark second as dead: %v", err)
+	}
+	if _, err := dbpool.Exec(ctx, `UPDATE outbox SET locked_until = NULL`); err != nil {
+		t.Fatalf("failed to clear leases: %v", err)
+	}
+	wantClaimed(claim(10, time.Minute))
+
+	var statuses []outbox.Status
+	rows, err = dbpool.Query(ctx, `SELECT status FROM outbox ORDER BY idempotency_key`)
+	if err != nil {
+		t.Fatalf("failed to read statuses: %v", err)
+	}
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			t.Fatalf("failed to read status: %v", err)
+		}
+		statuses = append(statuses, outbox.Status(status))
+	}
+	rows.Close()
+	want := []outbox.Status{outbox.StatusDelivered, outbox.StatusDead, outbox.StatusPending}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/text/language"
 )
 
@@ -22,31 +25,51 @@ func NewQuizStorage(conn *pgxpool.Pool) *QuizStorage {
 	return &QuizStorage{dbpool: conn}
 }
 
-func (s *QuizStorage) FindByID(id string) (*quiz.Quiz, error) {
+func (s *QuizStorage) FindByID(ctx context.Context, id string) (_ *quiz.Quiz, err error) {
+	ctx, span := tracer.Start(ctx, "QuizStorage.FindByID", trace.WithAttributes(attribute.String("quiz.id", id)))
+	defer func() {
+		if err != nil {
+			err = wrapStorageError(id, err)
+		}
+		endSpan(span, err)
+	}()
+
 	uuid, err := uuid.Parse(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse id as uuid: %w", err)
+		return nil, quiz.ErrNotFound
 	}
 
-	row := s.dbpool.QueryRow(context.Background(), `
+	row := s.dbpool.QueryRow(ctx, `
 		SELECT *
 		FROM quiz
 		WHERE id = $1
 	`, uuid)
 
 	entity, err := mapToQuizEntity(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, quiz.ErrNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to map row to entity: %w", err)
 	}
 
-	return s.buildQuiz(*entity)
+	q, err := buildQuiz(ctx, s.dbpool, *entity)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(quizAttributes(*q)...)
+	return q, nil
 }
 
-func (s *QuizStorage) FindQuizzes() ([]quiz.Quiz, error) {
-	rows, err := s.dbpool.Query(context.Background(), `
+func (s *QuizStorage) FindQuizzes(ctx context.Context, filter quiz.Filter) (_ []quiz.Quiz, err error) {
+	ctx, span := tracer.Start(ctx, "QuizStorage.FindQuizzes", trace.WithAttributes(attribute.String("quiz.status", string(filter.Status))))
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.dbpool.Query(ctx, `
 		SELECT *
 		FROM quiz
-	`)
+		WHERE $1 = '' OR status = $1
+	`, string(filter.Status))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query quiz table: %w", err)
 	}
@@ -66,18 +89,47 @@ func (s *QuizStorage) FindQuizzes() ([]quiz.Quiz, error) {
 
 	quizzes := make([]quiz.Quiz, 0)
 	for _, quizEntity := range quizEntities {
-		quiz, err := s.buildQuiz(quizEntity)
+		quiz, err := buildQuiz(ctx, s.dbpool, quizEntity)
 		if err != nil {
-			return nil, fmt.Errorf("failed to map entity to quiz: %w", err)
+			return nil, wrapStorageError(quizEntity.ID.String(), fmt.Errorf("failed to map entity to quiz: %w", err))
 		}
 		quizzes = append(quizzes, *quiz)
 	}
 
+	span.SetAttributes(attribute.Int("quiz.count", len(quizzes)))
 	return quizzes, nil
 }
 
-func (s *QuizStorage) buildQuiz(quizEntity QuizEntity) (*quiz.Quiz, error) {
-	quizSectionEntities, err := s.findQuizSectionEntities(quizEntity.ID)
+// buildQuiz loads the sections and exercises of a quiz: those of its current
+// revision unless it is a draft, and the draft otherwise. They are read
+// through db, which is the transaction that locked the quiz if there is one.
+// It has a span of its own, so that the queries of each quiz listed by
+// FindQuizzes are grouped.
+func buildQuiz(ctx context.Context, db querier, quizEntity QuizEntity) (_ *quiz.Quiz, err error) {
+	ctx, span := tracer.Start(ctx, "QuizStorage.buildQuiz", trace.WithAttributes(attribute.String("quiz.id", quizEntity.ID.String())))
+	defer func() { endSpan(span, err) }()
+
+	if quizEntity.Revision != nil && quizEntity.Status != string(quiz.StatusDraft) {
+		revisionEntity, err := findRevisionEntity(ctx, db, quizEntity.ID, *quizEntity.Revision)
+		if err != nil {
+			return nil, err
+		}
+		revision, err := mapToRevision(*revisionEntity)
+		if err != nil {
+			return nil, err
+		}
+		q := revision.Quiz
+		q.CreatedAt = quizEntity.CreatedAt
+		q.UpdatedAt = quizEntity.UpdatedAt
+		q.Status = quiz.Status(quizEntity.Status)
+		if quizEntity.ForkedFrom != nil {
+			q.ForkedFrom = quizEntity.ForkedFrom.String()
+		}
+		span.SetAttributes(quizAttributes(q)...)
+		return &q, nil
+	}
+
+	quizSectionEntities, err := findQuizSectionEntities(ctx, db, quizEntity.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find quiz section entities: %w", err)
 	}
@@ -87,19 +139,25 @@ func (s *QuizStorage) buildQuiz(quizEntity QuizEntity) (*quiz.Quiz, error) {
 		quizSectionIDs = append(quizSectionIDs, quizSectionEntity.ID)
 	}
 
-	exerciseEntitiesBySectionID, err := s.findExerciseEntitiesBySectionID(quizSectionIDs)
+	exerciseEntitiesBySectionID, err := findExerciseEntitiesBySectionID(ctx, db, quizSectionIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find exercise entities: %w", err)
 	}
 
-	return combineEntitiesIntoQuiz(quizEntity, quizSectionEntities, exerciseEntitiesBySectionID)
+	q, err := combineEntitiesIntoQuiz(quizEntity, quizSectionEntities, exerciseEntitiesBySectionID)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(quizAttributes(*q)...)
+	return q, nil
 }
 
-func (s *QuizStorage) findExerciseEntitiesBySectionID(quizSectionIDs []uuid.UUID) (map[string][]ExerciseEntity, error) {
-	rows, err := s.dbpool.Query(context.Background(), `
+func findExerciseEntitiesBySectionID(ctx context.Context, db querier, quizSectionIDs []uuid.UUID) (map[string][]ExerciseEntity, error) {
+	rows, err := db.Query(ctx, `
 		SELECT *
 		FROM exercise
 		WHERE quiz_section_id = ANY ($1)
+		ORDER BY position
 	`, quizSectionIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query exercise table: %w", err)
@@ -129,11 +187,12 @@ func (s *QuizStorage) findExerciseEntitiesBySectionID(quizSectionIDs []uuid.UUID
 	return exerciseEntitiesBySectionID, nil
 }
 
-func (s *QuizStorage) findQuizSectionEntities(quizID uuid.UUID) ([]QuizSectionEntity, error) {
-	rows, err := s.dbpool.Query(context.Background(), `
+func findQuizSectionEntities(ctx context.Context, db querier, quizID uuid.UUID) ([]QuizSectionEntity, error) {
+	rows, err := db.Query(ctx, `
 		SELECT *
 		FROM quiz_section
 		WHERE quiz_id = $1
+		ORDER BY position
 	`, quizID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query quiz_section table: %w", err)
@@ -155,55 +214,168 @@ func (s *QuizStorage) findQuizSectionEntities(quizID uuid.UUID) ([]QuizSectionEn
 	return quizSectionEntities, nil
 }
 
-func (s *QuizStorage) CreateQuiz(cmd quiz.CreateQuizCommand) (*quiz.Quiz, error) {
+func (s *QuizStorage) CreateQuiz(ctx context.Context, cmd quiz.CreateQuizCommand, status quiz.Status) (_ *quiz.Quiz, err error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate new UUID: %w", err)
+	}
+	exerciseCount := 0
+	for _, section := range cmd.Sections {
+		exerciseCount += len(section.Exercises)
+	}
+	ctx, span := tracer.Start(ctx, "QuizStorage.CreateQuiz", trace.WithAttributes(
+		attribute.String("quiz.id", id.String()),
+		attribute.String("quiz.language_tag", cmd.LanguageTag.String()),
+		attribute.Int("quiz.exercise_count", exerciseCount),
+	))
+	defer func() {
+		if err != nil {
+			err = wrapStorageError(id.String(), err)
+		}
+		endSpan(span, err)
+	}()
+
+	tx, err := s.dbpool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	q, err := insertQuiz(ctx, tx, id, cmd, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != quiz.StatusDraft {
+		q, err = moveQuiz(ctx, tx, id, q, nil, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return q, nil
+}
+
+// CloneQuiz reads the quiz to copy in the transaction that creates the copy,
+// with a lock that keeps its status and current revision from changing
+// meanwhile.
+func (s *QuizStorage) CloneQuiz(ctx context.Context, cmd quiz.CloneQuizCommand, status quiz.Status) (_ *quiz.Quiz, err error) {
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new UUID: %w", err)
 	}
+	ctx, span := tracer.Start(ctx, "QuizStorage.CloneQuiz", trace.WithAttributes(
+		attribute.String("quiz.id", id.String()),
+		attribute.String("quiz.forked_from", cmd.QuizID),
+	))
+	defer func() {
+		if err != nil {
+			err = wrapStorageError(id.String(), err)
+		}
+		endSpan(span, err)
+	}()
+
+	sourceID, err := uuid.Parse(cmd.QuizID)
+	if err != nil {
+		return nil, quiz.ErrNotFound
+	}
 
-	tx, err := s.dbpool.Begin(context.Background())
+	tx, err := s.dbpool.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(ctx)
+
+	sourceEntity, err := mapToQuizEntity(tx.QueryRow(ctx, `
+		SELECT *
+		FROM quiz
+		WHERE id = $1
+		FOR SHARE
+	`, sourceID))
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !quiz.Status(sourceEntity.Status).Public()) {
+		return nil, quiz.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find quiz to clone: %w", err)
+	}
+	source, err := buildQuiz(ctx, tx, *sourceEntity)
+	if err != nil {
+		return nil, wrapStorageError(cmd.QuizID, err)
+	}
+
+	createQuizCommand, err := quiz.NewCreateQuizCommandFrom(*source)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Name != "" {
+		createQuizCommand.Name = cmd.Name
+	}
+	if cmd.LanguageTag != language.Und {
+		createQuizCommand.LanguageTag = cmd.LanguageTag
+	}
+	span.SetAttributes(attribute.String("quiz.language_tag", createQuizCommand.LanguageTag.String()))
 
-	quizEntity, err := mapToQuizEntity(tx.QueryRow(context.Background(), `
-		INSERT INTO quiz (id, name, language_tag)
-		VALUES ($1, $2, $3)
+	q, err := insertQuiz(ctx, tx, id, createQuizCommand, &sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if status != quiz.StatusDraft {
+		q, err = moveQuiz(ctx, tx, id, q, nil, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return q, nil
+}
+
+// insertQuiz inserts a draft quiz with its sections and exercises, numbered
+// in the order of cmd.
+func insertQuiz(ctx context.Context, tx pgx.Tx, id uuid.UUID, cmd quiz.CreateQuizCommand, forkedFrom *uuid.UUID) (*quiz.Quiz, error) {
+	quizEntity, err := mapToQuizEntity(tx.QueryRow(ctx, `
+		INSERT INTO quiz (id, name, language_tag, forked_from)
+		VALUES ($1, $2, $3, $4)
 		RETURNING *
-	`, id, cmd.Name, cmd.LanguageTag.String()))
+	`, id, cmd.Name, cmd.LanguageTag.String(), forkedFrom))
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert quiz: %w", err)
 	}
 
 	quizSectionEntities := make([]QuizSectionEntity, 0)
 	exerciseEntitiesBySectionID := make(map[string][]ExerciseEntity)
-	for _, createSectionCommand := range cmd.Sections {
+	for i, createSectionCommand := range cmd.Sections {
 		id, err := uuid.NewRandom()
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate new UUID: %w", err)
 		}
 
-		quizSectionEntity, err := mapToQuizSectionEntity(tx.QueryRow(context.Background(), `
-			INSERT INTO quiz_section (id, quiz_id, name)
-			VALUES ($1, $2, $3)
+		quizSectionEntity, err := mapToQuizSectionEntity(tx.QueryRow(ctx, `
+			INSERT INTO quiz_section (id, quiz_id, name, position)
+			VALUES ($1, $2, $3, $4)
 			RETURNING *
-		`, id, quizEntity.ID, createSectionCommand.Name))
+		`, id, quizEntity.ID, createSectionCommand.Name, i))
 		if err != nil {
 			return nil, fmt.Errorf("failed to insert quiz section: %w", err)
 		}
 		quizSectionEntities = append(quizSectionEntities, *quizSectionEntity)
 
-		for _, createExerciseCommand := range createSectionCommand.Exercises {
+		for j, createExerciseCommand := range createSectionCommand.Exercises {
 			var exerciseEntity *ExerciseEntity
 			var err error
 			switch createExerciseCommand := createExerciseCommand.(type) {
 			case *exercise.CreateMultipleChoiceExerciseCommand:
-				exerciseEntity, err = insertMultipleChoiceExercise(tx, *createExerciseCommand, quizSectionEntity.ID)
+				exerciseEntity, err = insertMultipleChoiceExercise(ctx, tx, *createExerciseCommand, quizSectionEntity.ID, j)
 			case *exercise.CreateFillInTheBlankExerciseCommand:
-				exerciseEntity, err = insertFillInTheBlankExercise(tx, *createExerciseCommand, quizSectionEntity.ID)
+				exerciseEntity, err = insertFillInTheBlankExercise(ctx, tx, *createExerciseCommand, quizSectionEntity.ID, j)
 			case *exercise.CreateSentenceCorrectionExerciseCommand:
-				exerciseEntity, err = insertSentenceCorrectionExercise(tx, *createExerciseCommand, quizSectionEntity.ID)
+				exerciseEntity, err = insertSentenceCorrectionExercise(ctx, tx, *createExerciseCommand, quizSectionEntity.ID, j)
 			default:
 				return nil, fmt.Errorf("unknown exercise type: %T", createExerciseCommand)
 			}
@@ -215,67 +387,203 @@ func (s *QuizStorage) CreateQuiz(cmd quiz.CreateQuizCommand) (*quiz.Quiz, error)
 		}
 	}
 
-	err = tx.Commit(context.Background())
+	return combineEntitiesIntoQuiz(*quizEntity, quizSectionEntities, exerciseEntitiesBySectionID)
+}
+
+// SetStatus locks the quiz with id while it checks that the quiz can move to
+// status, so that concurrent changes are applied one after the other. The
+// quiz is read through the same transaction, and a quiz is published with a
+// snapshot of that draft as a new revision.
+func (s *QuizStorage) SetStatus(ctx context.Context, id string, status quiz.Status) (_ *quiz.Quiz, err error) {
+	ctx, span := tracer.Start(ctx, "QuizStorage.SetStatus", trace.WithAttributes(
+		attribute.String("quiz.id", id),
+		attribute.String("quiz.status", string(status)),
+	))
+	defer func() {
+		if err != nil {
+			err = wrapStorageError(id, err)
+		}
+		endSpan(span, err)
+	}()
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, quiz.ErrNotFound
+	}
+
+	tx, err := s.dbpool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	entity, err := lockQuiz(ctx, tx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := buildQuiz(ctx, tx, *entity)
+	if err != nil {
+		return nil, err
+	}
+	q, err = moveQuiz(ctx, tx, uuid, q, entity.Revision, status)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	return q, nil
+}
 
-	return combineEntitiesIntoQuiz(*quizEntity, quizSectionEntities, exerciseEntitiesBySectionID)
+// moveQuiz moves q, which tx has locked or inserted, to status and returns
+// it as tx now reads it. revision is the current revision of q, if any. A
+// quiz is published with a snapshot of q as a new revision.
+func moveQuiz(ctx context.Context, tx pgx.Tx, id uuid.UUID, q *quiz.Quiz, revision *int, status quiz.Status) (*quiz.Quiz, error) {
+	if err := q.SetStatus(status); err != nil {
+		return nil, err
+	}
+
+	if status == quiz.StatusPublished {
+		snapshot, err := newRevisionSnapshot(*q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to take snapshot: %w", err)
+		}
+		number, err := insertRevision(ctx, tx, id, snapshot, nil)
+		if err != nil {
+			return nil, err
+		}
+		revision = &number
+	}
+
+	entity, err := mapToQuizEntity(tx.QueryRow(ctx, `
+		UPDATE quiz
+		SET status = $2, revision = $3
+		WHERE id = $1
+		RETURNING *
+	`, id, string(status), revision))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update quiz status: %w", err)
+	}
+	return buildQuiz(ctx, tx, *entity)
+}
+
+// DeleteQuiz deletes a quiz with its sections and exercises.
+func (s *QuizStorage) DeleteQuiz(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "QuizStorage.DeleteQuiz", trace.WithAttributes(attribute.String("quiz.id", id)))
+	defer func() {
+		if err != nil {
+			err = wrapStorageError(id, err)
+		}
+		endSpan(span, err)
+	}()
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		return quiz.ErrNotFound
+	}
+
+	tx, err := s.dbpool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM exercise
+		WHERE quiz_section_id IN (SELECT id FROM quiz_section WHERE quiz_id = $1)
+	`, uuid)
+	if err != nil {
+		return fmt.Errorf("failed to delete exercises: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM quiz_section
+		WHERE quiz_id = $1
+	`, uuid)
+	if err != nil {
+		return fmt.Errorf("failed to delete quiz sections: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM quiz
+		WHERE id = $1
+	`, uuid)
+	if err != nil {
+		return fmt.Errorf("failed to delete quiz: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return quiz.ErrNotFound
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
 
 func insertMultipleChoiceExercise(
+	ctx context.Context,
 	tx pgx.Tx,
 	cmd exercise.CreateMultipleChoiceExerciseCommand,
 	quizSectionId uuid.UUID,
+	position int,
 ) (*ExerciseEntity, error) {
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new UUID: %w", err)
 	}
 
-	row := tx.QueryRow(context.Background(), `
-		INSERT INTO exercise (id, quiz_section_id, type, question, choices, answer, feedback) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7) 
+	row := tx.QueryRow(ctx, `
+		INSERT INTO exercise (id, quiz_section_id, type, question, choices, answer, feedback, position) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
 		RETURNING *
-	`, id, quizSectionId, exercise.TypeMultipleChoice, cmd.Question, cmd.Choices, cmd.Answer, cmd.Feedback)
+	`, id, quizSectionId, exercise.TypeMultipleChoice, cmd.Question, cmd.Choices, cmd.Answer, cmd.Feedback, position)
 
 	return mapToExerciseEntity(row)
 }
 
 func insertFillInTheBlankExercise(
+	ctx context.Context,
 	tx pgx.Tx,
 	cmd exercise.CreateFillInTheBlankExerciseCommand,
 	quizSectionId uuid.UUID,
+	position int,
 ) (*ExerciseEntity, error) {
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new UUID: %w", err)
 	}
 
-	row := tx.QueryRow(context.Background(), `
-		INSERT INTO exercise (id, quiz_section_id, type, question, answer, feedback) 
-		VALUES ($1, $2, $3, $4, $5, $6) 
+	row := tx.QueryRow(ctx, `
+		INSERT INTO exercise (id, quiz_section_id, type, question, answer, feedback, position) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7) 
 		RETURNING *
-	`, id, quizSectionId, exercise.TypeFillInTheBlank, cmd.Question, cmd.Answer, cmd.Feedback)
+	`, id, quizSectionId, exercise.TypeFillInTheBlank, cmd.Question, cmd.Answer, cmd.Feedback, position)
 
 	return mapToExerciseEntity(row)
 }
 
 func insertSentenceCorrectionExercise(
+	ctx context.Context,
 	tx pgx.Tx,
 	cmd exercise.CreateSentenceCorrectionExerciseCommand,
 	quizSectionId uuid.UUID,
+	position int,
 ) (*ExerciseEntity, error) {
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new UUID: %w", err)
 	}
 
-	row := tx.QueryRow(context.Background(), `
-		INSERT INTO exercise (id, quiz_section_id, type, sentence, corrected_sentence, feedback) 
-		VALUES ($1, $2, $3, $4, $5, $6) 
+	row := tx.QueryRow(ctx, `
+		INSERT INTO exercise (id, quiz_section_id, type, sentence, corrected_sentence, feedback, position) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7) 
 		RETURNING *
-	`, id, quizSectionId, exercise.TypeSentenceCorrection, cmd.Sentence, cmd.CorrectedSentence, cmd.Feedback)
+	`, id, quizSectionId, exercise.TypeSentenceCorrection, cmd.Sentence, cmd.CorrectedSentence, cmd.Feedback, position)
 
 	return mapToExerciseEntity(row)
 }
@@ -288,6 +596,9 @@ func mapToQuizEntity(row pgx.Row) (*QuizEntity, error) {
 		&entity.UpdatedAt,
 		&entity.LanguageTag,
 		&entity.Name,
+		&entity.Status,
+		&entity.Revision,
+		&entity.ForkedFrom,
 	)
 	return &entity, err
 }
@@ -300,6 +611,7 @@ func mapToQuizSectionEntity(row pgx.Row) (*QuizSectionEntity, error) {
 		&entity.CreatedAt,
 		&entity.UpdatedAt,
 		&entity.Name,
+		&entity.Position,
 	)
 	return &entity, err
 }
@@ -318,6 +630,7 @@ func mapToExerciseEntity(row pgx.Row) (*ExerciseEntity, error) {
 		&entity.Answer,
 		&entity.Sentence,
 		&entity.CorrectedSentence,
+		&entity.Position,
 	)
 	return &entity, err
 }
@@ -347,9 +660,13 @@ func combineEntitiesIntoQuiz(
 		quizEntity.UpdatedAt,
 		quizEntity.Name,
 		language.MustParse(quizEntity.LanguageTag),
+		quiz.Status(quizEntity.Status),
 		sections,
 	)
 
+	if quizEntity.ForkedFrom != nil {
+		quiz.ForkedFrom = quizEntity.ForkedFrom.String()
+	}
 	return &quiz, nil
 }
 
@@ -359,6 +676,11 @@ type QuizEntity struct {
 	UpdatedAt   time.Time
 	Name        string
 	LanguageTag string
+	Status      string
+	// Revision is the number of the current revision, nil until the quiz is
+	// published.
+	Revision   *int
+	ForkedFrom *uuid.UUID
 }
 
 type QuizSectionEntity struct {
@@ -367,6 +689,8 @@ type QuizSectionEntity struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
+	// Position orders the sections of a quiz, from 0.
+	Position int
 }
 
 type ExerciseEntity struct {
@@ -384,6 +708,9 @@ type ExerciseEntity struct {
 
 	Sentence          *string
 	CorrectedSentence *string
+
+	// Position orders the exercises of a section, from 0.
+	Position int
 }
 
 func mapToExercises(entities []ExerciseEntity) ([]exercise.Exercise, error) {
@@ -407,8 +734,32 @@ func mapToExercise(entity ExerciseEntity) (exercise.Exercise, error) {
 	case exercise.TypeSentenceCorrection:
 		return mapToSentenceCorrectionExercise(entity), nil
 	default:
-		return nil, fmt.Errorf("unknown exercise type: %s", entity.Type)
+		return nil, &quiz.StorageError{
+			ExerciseID: entity.ID.String(),
+			Err:        fmt.Errorf("unknown exercise type: %s", entity.Type),
+		}
+	}
+}
+
+func quizAttributes(q quiz.Quiz) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("quiz.id", q.ID),
+		attribute.String("quiz.language_tag", q.LanguageTag.String()),
+		attribute.Int("quiz.exercise_count", len(q.GetExercises())),
+	}
+}
+
+// wrapStorageError returns err as a quiz.StorageError for the quiz quizID,
+// keeping the exercise ID of a StorageError it already wraps.
+func wrapStorageError(quizID string, err error) error {
+	var storageErr *quiz.StorageError
+	if errors.As(err, &storageErr) {
+		if storageErr.QuizID == "" {
+			storageErr.QuizID = quizID
+		}
+		return err
 	}
+	return &quiz.StorageError{QuizID: quizID, Err: err}
 }
 
 func mapToMultipleChoiceExercise(entity ExerciseEntity) *exercise.MultipleChoiceExercise {
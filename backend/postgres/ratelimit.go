@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"languagequiz/ratelimit"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// rateLimitSweepInterval is how often buckets that are full again are
+// deleted.
+const rateLimitSweepInterval = 10 * time.Minute
+
+// RateLimitStore keeps rate limit buckets in Postgres, so that they are
+// shared by every server.
+type RateLimitStore struct {
+	dbpool *pgxpool.Pool
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	// maxPeriod is the longest period of the limits seen. A bucket that was
+	// not used for that long is full.
+	maxPeriod time.Duration
+}
+
+func NewRateLimitStore(conn *pgxpool.Pool) *RateLimitStore {
+	return &RateLimitStore{dbpool: conn, lastSweep: time.Now()}
+}
+
+// Take refills the bucket and then takes a token if it has one. Both
+// statements are sent together; the second only changes the bucket when a
+// token is left, so concurrent requests cannot take the same token.
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	s.maybeSweep(limit.Period)
+
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		INSERT INTO rate_limit_bucket AS bucket ("key", tokens, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT ("key") DO UPDATE
+		SET tokens = LEAST($2, bucket.tokens + EXTRACT(EPOCH FROM NOW() - bucket.updated_at) * $3),
+			updated_at = NOW()
+		RETURNING tokens
+	`, key, float64(limit.Burst), limit.Rate())
+	batch.Queue(`
+		UPDATE rate_limit_bucket
+		SET tokens = tokens - 1
+		WHERE "key" = $1 AND tokens >= 1
+		RETURNING tokens
+	`, key)
+
+	results := s.dbpool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	var refilled float64
+	if err := results.QueryRow().Scan(&refilled); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to refill rate limit bucket: %w", err)
+	}
+	var remaining float64
+	err := results.QueryRow().Scan(&remaining)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ratelimit.Result{
+			Remaining:  int(refilled),
+			RetryAfter: limit.RetryAfter(refilled),
+		}, nil
+	}
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	return ratelimit.Result{Allowed: true, Remaining: int(remaining)}, nil
+}
+
+// maybeSweep deletes the buckets that are full again in the background, at
+// most once per rateLimitSweepInterval.
+func (s *RateLimitStore) maybeSweep(period time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxPeriod = max(s.maxPeriod, period)
+	if time.Since(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = time.Now()
+
+	go func(maxPeriod time.Duration) {
+		_, err := s.dbpool.Exec(context.Background(), `
+			DELETE FROM rate_limit_bucket
+			WHERE updated_at < NOW() - $1 * INTERVAL '1 millisecond'
+		`, maxPeriod.Milliseconds())
+		if err != nil {
+			slog.Error("failed to delete full rate limit buckets", "error", err.Error())
+		}
+	}(s.maxPeriod)
+}
@@ -0,0 +1,313 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"languagequiz/report"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReportStorage struct {
+	dbpool *pgxpool.Pool
+}
+
+func NewReportStorage(conn *pgxpool.Pool) *ReportStorage {
+	return &ReportStorage{dbpool: conn}
+}
+
+func (s *ReportStorage) CreateReport(ctx context.Context, cmd report.CreateReportCommand, flagThreshold int) (_ *report.Report, flagged bool, err error) {
+	quizID, err := uuid.Parse(cmd.QuizID)
+	if err != nil {
+		return nil, false, report.ErrNotFound
+	}
+	exerciseID, err := uuid.Parse(cmd.ExerciseID)
+	if err != nil {
+		return nil, false, report.ErrNotFound
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to generate new UUID: %w", err)
+	}
+
+	tx, err := s.dbpool.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the exercise makes concurrent reports on it count each other.
+	exerciseQuizID, err := lockExercise(ctx, tx, exerciseID)
+	if err != nil {
+		return nil, false, err
+	}
+	if exerciseQuizID != quizID {
+		return nil, false, report.ErrNotFound
+	}
+
+	entity, err := mapToReportEntity(tx.QueryRow(ctx, `
+		INSERT INTO exercise_report (id, exercise_id, reason, "comment", user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *, $6::UUID
+	`, id, exerciseID, string(cmd.Reason), cmd.Comment, cmd.UserAgent, quizID))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to insert exercise report: %w", err)
+	}
+
+	var openCount int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM exercise_report
+		WHERE exercise_id = $1 AND status = 'open'
+	`, exerciseID).Scan(&openCount)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to count open reports: %w", err)
+	}
+
+	if openCount >= flagThreshold {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO exercise_flag (exercise_id)
+			VALUES ($1)
+			ON CONFLICT (exercise_id) DO NOTHING
+		`, exerciseID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to flag exercise: %w", err)
+		}
+		flagged = tag.RowsAffected() == 1
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r := mapToReport(*entity)
+	return &r, flagged, nil
+}
+
+func (s *ReportStorage) FindInbox(ctx context.Context, filter report.Filter) ([]report.ExerciseReports, error) {
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Status != "" {
+		where("exercise_report.status = $%d", string(filter.Status))
+	}
+	if filter.QuizID != "" {
+		where("quiz_section.quiz_id = $%d", filter.QuizID)
+	}
+	if filter.FlaggedOnly {
+		conditions = append(conditions, "exercise_flag.exercise_id IS NOT NULL")
+	}
+
+	query := `
+		SELECT exercise_report.exercise_id, quiz_section.quiz_id, exercise_flag.flagged_at
+		FROM exercise_report
+		JOIN exercise ON exercise.id = exercise_report.exercise_id
+		JOIN quiz_section ON quiz_section.id = exercise.quiz_section_id
+		LEFT JOIN exercise_flag ON exercise_flag.exercise_id = exercise_report.exercise_id`
+	if len(conditions) > 0 {
+		query += `
+		WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(`
+		GROUP BY exercise_report.exercise_id, quiz_section.quiz_id, exercise_flag.flagged_at
+		ORDER BY MAX(exercise_report.created_at) DESC, exercise_report.exercise_id
+		LIMIT $%d OFFSET $%d
+	`, len(args)-1, len(args))
+
+	rows, err := s.dbpool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exercise_report table: %w", err)
+	}
+	defer rows.Close()
+
+	inbox := make([]report.ExerciseReports, 0)
+	exerciseIDs := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var exerciseID, quizID uuid.UUID
+		var flaggedAt *time.Time
+		if err := rows.Scan(&exerciseID, &quizID, &flaggedAt); err != nil {
+			return nil, fmt.Errorf("failed to map row to exercise reports: %w", err)
+		}
+		exerciseIDs = append(exerciseIDs, exerciseID)
+		inbox = append(inbox, report.ExerciseReports{
+			QuizID:     quizID.String(),
+			ExerciseID: exerciseID.String(),
+			FlaggedAt:  flaggedAt,
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read exercise_report table rows: %w", err)
+	}
+
+	reportsByExerciseID, err := s.findReportsByExerciseID(ctx, exerciseIDs, filter.Status)
+	if err != nil {
+		return nil, err
+	}
+	for i := range inbox {
+		inbox[i].Reports = reportsByExerciseID[inbox[i].ExerciseID]
+	}
+	return inbox, nil
+}
+
+func (s *ReportStorage) ResolveReports(ctx context.Context, exerciseID string) (*report.ExerciseReports, error) {
+	id, err := uuid.Parse(exerciseID)
+	if err != nil {
+		return nil, report.ErrNotFound
+	}
+
+	tx, err := s.dbpool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	quizID, err := lockExercise(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE exercise_report
+		SET status = 'resolved',
+			resolved_at = NOW()
+		WHERE exercise_id = $1 AND status = 'open'
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve exercise reports: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM exercise_flag
+		WHERE exercise_id = $1
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear exercise flag: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	reportsByExerciseID, err := s.findReportsByExerciseID(ctx, []uuid.UUID{id}, "")
+	if err != nil {
+		return nil, err
+	}
+	return &report.ExerciseReports{
+		QuizID:     quizID.String(),
+		ExerciseID: id.String(),
+		Reports:    reportsByExerciseID[id.String()],
+	}, nil
+}
+
+// findReportsByExerciseID returns the reports on the exercises, newest first.
+// With a status, only reports with that status are returned.
+func (s *ReportStorage) findReportsByExerciseID(ctx context.Context, exerciseIDs []uuid.UUID, status report.Status) (map[string][]report.Report, error) {
+	rows, err := s.dbpool.Query(ctx, `
+		SELECT exercise_report.*, quiz_section.quiz_id
+		FROM exercise_report
+		JOIN exercise ON exercise.id = exercise_report.exercise_id
+		JOIN quiz_section ON quiz_section.id = exercise.quiz_section_id
+		WHERE exercise_report.exercise_id = ANY ($1)
+			AND ($2::TEXT = '' OR exercise_report.status = $2::TEXT)
+		ORDER BY exercise_report.created_at DESC, exercise_report.id
+	`, exerciseIDs, string(status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exercise_report table: %w", err)
+	}
+	defer rows.Close()
+
+	reportsByExerciseID := make(map[string][]report.Report)
+	for rows.Next() {
+		entity, err := mapToReportEntity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map row to report entity: %w", err)
+		}
+		r := mapToReport(*entity)
+		reportsByExerciseID[r.ExerciseID] = append(reportsByExerciseID[r.ExerciseID], r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read exercise_report table rows: %w", err)
+	}
+	return reportsByExerciseID, nil
+}
+
+// lockExercise locks the exercise with id for the rest of tx and returns the
+// id of its quiz.
+func lockExercise(ctx context.Context, tx pgx.Tx, id uuid.UUID) (uuid.UUID, error) {
+	var quizID uuid.UUID
+	err := tx.QueryRow(ctx, `
+		SELECT quiz_section.quiz_id
+		FROM exercise
+		JOIN quiz_section ON quiz_section.id = exercise.quiz_section_id
+		WHERE exercise.id = $1
+		FOR UPDATE OF exercise
+	`, id).Scan(&quizID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, report.ErrNotFound
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find exercise %s: %w", id, err)
+	}
+	return quizID, nil
+}
+
+// ReportEntity is a row of exercise_report followed by the id of the quiz
+// the exercise belongs to.
+type ReportEntity struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ExerciseID uuid.UUID
+	Reason     string
+	Comment    string
+	UserAgent  string
+	Status     string
+	ResolvedAt *time.Time
+	QuizID     uuid.UUID
+}
+
+func mapToReportEntity(row pgx.Row) (*ReportEntity, error) {
+	var entity ReportEntity
+	err := row.Scan(
+		&entity.ID,
+		&entity.CreatedAt,
+		&entity.UpdatedAt,
+		&entity.ExerciseID,
+		&entity.Reason,
+		&entity.Comment,
+		&entity.UserAgent,
+		&entity.Status,
+		&entity.ResolvedAt,
+		&entity.QuizID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+func mapToReport(entity ReportEntity) report.Report {
+	return report.Report{
+		ID:         entity.ID.String(),
+		QuizID:     entity.QuizID.String(),
+		ExerciseID: entity.ExerciseID.String(),
+		Reason:     report.Reason(entity.Reason),
+		Comment:    entity.Comment,
+		UserAgent:  entity.UserAgent,
+		Status:     report.Status(entity.Status),
+		CreatedAt:  entity.CreatedAt,
+		ResolvedAt: entity.ResolvedAt,
+	}
+}
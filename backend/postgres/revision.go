@@ -0,0 +1,414 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/text/language"
+)
+
+func (s *QuizStorage) FindRevisions(ctx context.Context, quizID string) (_ []quiz.Revision, err error) {
+	ctx, span := tracer.Start(ctx, "QuizStorage.FindRevisions", trace.WithAttributes(attribute.String("quiz.id", quizID)))
+	defer func() {
+		if err != nil {
+			err = wrapStorageError(quizID, err)
+		}
+		endSpan(span, err)
+	}()
+
+	id, err := uuid.Parse(quizID)
+	if err != nil {
+		return nil, quiz.ErrNotFound
+	}
+
+	rows, err := s.dbpool.Query(ctx, `
+		SELECT *
+		FROM quiz_revision
+		WHERE quiz_id = $1
+		ORDER BY "number" DESC
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quiz_revision table: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := make([]quiz.Revision, 0)
+	for rows.Next() {
+		entity, err := mapToRevisionEntity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map row to revision entity: %w", err)
+		}
+		revision, err := mapToRevision(*entity)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read quiz_revision table rows: %w", err)
+	}
+
+	if len(revisions) == 0 {
+		var exists bool
+		err := s.dbpool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM quiz WHERE id = $1)`, id).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find quiz: %w", err)
+		}
+		if !exists {
+			return nil, quiz.ErrNotFound
+		}
+	}
+	return revisions, nil
+}
+
+func (s *QuizStorage) FindRevision(ctx context.Context, quizID string, number int) (_ *quiz.Revision, err error) {
+	ctx, span := tracer.Start(ctx, "QuizStorage.FindRevision", trace.WithAttributes(
+		attribute.String("quiz.id", quizID),
+		attribute.Int("quiz.revision", number),
+	))
+	defer func() {
+		if err != nil {
+			err = wrapStorageError(quizID, err)
+		}
+		endSpan(span, err)
+	}()
+
+	id, err := uuid.Parse(quizID)
+	if err != nil {
+		return nil, quiz.ErrRevisionNotFound
+	}
+
+	entity, err := findRevisionEntity(ctx, s.dbpool, id, number)
+	if err != nil {
+		return nil, err
+	}
+	return mapToRevision(*entity)
+}
+
+func (s *QuizStorage) Rollback(ctx context.Context, quizID string, number int) (_ *quiz.Quiz, err error) {
+	ctx, span := tracer.Start(ctx, "QuizStorage.Rollback", trace.WithAttributes(
+		attribute.String("quiz.id", quizID),
+		attribute.Int("quiz.revision", number),
+	))
+	defer func() {
+		if err != nil {
+			err = wrapStorageError(quizID, err)
+		}
+		endSpan(span, err)
+	}()
+
+	id, err := uuid.Parse(quizID)
+	if err != nil {
+		return nil, quiz.ErrNotFound
+	}
+
+	tx, err := s.dbpool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	quizEntity, err := lockQuiz(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if quizEntity.Status != string(quiz.StatusPublished) {
+		return nil, quiz.ErrNotPublished
+	}
+
+	revisionEntity, err := findRevisionEntity(ctx, tx, id, number)
+	if err != nil {
+		return nil, err
+	}
+	current, err := insertRevision(ctx, tx, id, revisionEntity.Snapshot, &number)
+	if err != nil {
+		return nil, err
+	}
+
+	quizEntity, err = mapToQuizEntity(tx.QueryRow(ctx, `
+		UPDATE quiz
+		SET revision = $2
+		WHERE id = $1
+		RETURNING *
+	`, id, current))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update quiz revision: %w", err)
+	}
+	q, err := buildQuiz(ctx, tx, *quizEntity)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return q, nil
+}
+
+func (s *QuizStorage) CreateAttempt(ctx context.Context, cmd quiz.CreateAttemptCommand) (_ *quiz.Attempt, err error) {
+	quizID, err := uuid.Parse(cmd.QuizID)
+	if err != nil {
+		return nil, quiz.ErrNotFound
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate new UUID: %w", err)
+	}
+	var revision *int
+	if cmd.Revision > 0 {
+		revision = &cmd.Revision
+	}
+
+	var entity AttemptEntity
+	err = s.dbpool.QueryRow(ctx, `
+		INSERT INTO quiz_attempt (id, quiz_id, revision, correct_count, total_count)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *
+	`, id, quizID, revision, cmd.Correct, cmd.Total).Scan(
+		&entity.ID,
+		&entity.CreatedAt,
+		&entity.QuizID,
+		&entity.Revision,
+		&entity.CorrectCount,
+		&entity.TotalCount,
+	)
+	if err != nil {
+		return nil, wrapStorageError(cmd.QuizID, fmt.Errorf("failed to insert quiz attempt: %w", err))
+	}
+
+	attempt := quiz.Attempt{
+		ID:        entity.ID.String(),
+		QuizID:    entity.QuizID.String(),
+		CreatedAt: entity.CreatedAt,
+		Correct:   entity.CorrectCount,
+		Total:     entity.TotalCount,
+	}
+	if entity.Revision != nil {
+		attempt.Revision = *entity.Revision
+	}
+	return &attempt, nil
+}
+
+// lockQuiz locks the quiz with id for the rest of tx.
+func lockQuiz(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*QuizEntity, error) {
+	entity, err := mapToQuizEntity(tx.QueryRow(ctx, `
+		SELECT *
+		FROM quiz
+		WHERE id = $1
+		FOR UPDATE
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, quiz.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock quiz: %w", err)
+	}
+	return entity, nil
+}
+
+// insertRevision adds a revision with the next number to a quiz, which must
+// be locked by tx, and returns its number.
+func insertRevision(ctx context.Context, tx pgx.Tx, quizID uuid.UUID, snapshot []byte, rolledBackFrom *int) (int, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate new UUID: %w", err)
+	}
+
+	var number int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO quiz_revision (id, quiz_id, "number", rolled_back_from, snapshot)
+		SELECT $1::UUID, $2::UUID, COALESCE(MAX("number"), 0) + 1, $3::INTEGER, $4::JSONB
+		FROM quiz_revision
+		WHERE quiz_id = $2
+		RETURNING "number"
+	`, id, quizID, rolledBackFrom, snapshot).Scan(&number)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert quiz revision: %w", err)
+	}
+	return number, nil
+}
+
+// querier is a pool or a transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func findRevisionEntity(ctx context.Context, q querier, quizID uuid.UUID, number int) (*RevisionEntity, error) {
+	entity, err := mapToRevisionEntity(q.QueryRow(ctx, `
+		SELECT *
+		FROM quiz_revision
+		WHERE quiz_id = $1 AND "number" = $2
+	`, quizID, number))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, quiz.ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find quiz revision %d: %w", number, err)
+	}
+	return entity, nil
+}
+
+// revisionSnapshot is the content of a quiz as it is stored in a revision.
+// Exercises are stored with the columns of the exercise table, so that they
+// are mapped back the same way.
+type revisionSnapshot struct {
+	Name        string            `json:"name"`
+	LanguageTag string            `json:"languageTag"`
+	Sections    []snapshotSection `json:"sections"`
+}
+
+type snapshotSection struct {
+	Name      string             `json:"name"`
+	Exercises []snapshotExercise `json:"exercises"`
+}
+
+type snapshotExercise struct {
+	ID                string    `json:"id"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+	Feedback          *string   `json:"feedback"`
+	Type              string    `json:"type"`
+	Question          *string   `json:"question"`
+	Choices           *[]string `json:"choices"`
+	Answer            *string   `json:"answer"`
+	Sentence          *string   `json:"sentence"`
+	CorrectedSentence *string   `json:"correctedSentence"`
+}
+
+func newRevisionSnapshot(q quiz.Quiz) ([]byte, error) {
+	snapshot := revisionSnapshot{
+		Name:        q.Name,
+		LanguageTag: q.LanguageTag.String(),
+		Sections:    make([]snapshotSection, 0, len(q.Sections)),
+	}
+	for _, s := range q.Sections {
+		section := snapshotSection{Name: s.Name, Exercises: make([]snapshotExercise, 0, len(s.Exercises))}
+		for _, e := range s.Exercises {
+			var se snapshotExercise
+			switch e := e.(type) {
+			case *exercise.MultipleChoiceExercise:
+				answer := e.Answer().(string)
+				se = snapshotExercise{ID: e.ID, CreatedAt: e.CreatedAt, UpdatedAt: e.UpdatedAt, Type: e.Type,
+					Question: &e.Question, Choices: &e.Choices, Answer: &answer}
+			case *exercise.FillInTheBlankExercise:
+				answer := e.Answer().(string)
+				se = snapshotExercise{ID: e.ID, CreatedAt: e.CreatedAt, UpdatedAt: e.UpdatedAt, Type: e.Type,
+					Question: &e.Question, Answer: &answer}
+			case *exercise.SentenceCorrectionExercise:
+				se = snapshotExercise{ID: e.ID, CreatedAt: e.CreatedAt, UpdatedAt: e.UpdatedAt, Type: e.Type,
+					Sentence: &e.Sentence, CorrectedSentence: &e.CorrectedSentence}
+			default:
+				return nil, fmt.Errorf("unknown exercise type: %T", e)
+			}
+			se.Feedback = e.Feedback()
+			section.Exercises = append(section.Exercises, se)
+		}
+		snapshot.Sections = append(snapshot.Sections, section)
+	}
+	return json.Marshal(snapshot)
+}
+
+// mapToRevision maps a revision to a published quiz with the ID of its quiz
+// and the time the revision was created.
+func mapToRevision(entity RevisionEntity) (*quiz.Revision, error) {
+	var snapshot revisionSnapshot
+	if err := json.Unmarshal(entity.Snapshot, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot of revision %d: %w", entity.Number, err)
+	}
+
+	sections := make([]quiz.Section, 0, len(snapshot.Sections))
+	for _, s := range snapshot.Sections {
+		exerciseEntities := make([]ExerciseEntity, 0, len(s.Exercises))
+		for _, e := range s.Exercises {
+			id, err := uuid.Parse(e.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse exercise id of revision %d: %w", entity.Number, err)
+			}
+			exerciseEntities = append(exerciseEntities, ExerciseEntity{
+				ID:                id,
+				CreatedAt:         e.CreatedAt,
+				UpdatedAt:         e.UpdatedAt,
+				Feedback:          e.Feedback,
+				Type:              e.Type,
+				Question:          e.Question,
+				Choices:           e.Choices,
+				Answer:            e.Answer,
+				Sentence:          e.Sentence,
+				CorrectedSentence: e.CorrectedSentence,
+			})
+		}
+		exercises, err := mapToExercises(exerciseEntities)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, quiz.NewSection(s.Name, exercises))
+	}
+
+	languageTag, err := language.Parse(snapshot.LanguageTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse language tag of revision %d: %w", entity.Number, err)
+	}
+	q := quiz.New(
+		entity.QuizID.String(),
+		entity.CreatedAt,
+		entity.CreatedAt,
+		snapshot.Name,
+		languageTag,
+		quiz.StatusPublished,
+		sections,
+	)
+	q.Revision = entity.Number
+
+	revision := quiz.Revision{
+		Number:    entity.Number,
+		CreatedAt: entity.CreatedAt,
+		Quiz:      q,
+	}
+	if entity.RolledBackFrom != nil {
+		revision.RolledBackFrom = *entity.RolledBackFrom
+	}
+	return &revision, nil
+}
+
+type RevisionEntity struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	QuizID         uuid.UUID
+	Number         int
+	RolledBackFrom *int
+	Snapshot       []byte
+}
+
+func mapToRevisionEntity(row pgx.Row) (*RevisionEntity, error) {
+	var entity RevisionEntity
+	err := row.Scan(
+		&entity.ID,
+		&entity.CreatedAt,
+		&entity.QuizID,
+		&entity.Number,
+		&entity.RolledBackFrom,
+		&entity.Snapshot,
+	)
+	return &entity, err
+}
+
+type AttemptEntity struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	QuizID       uuid.UUID
+	Revision     *int
+	CorrectCount int
+	TotalCount   int
+}
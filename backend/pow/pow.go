@@ -0,0 +1,136 @@
+// Package pow implements a hashcash-style proof of work that makes sending
+// many anonymous requests expensive. The server hands out signed challenges,
+// and a client proves its work with a nonce such that the SHA-256 hash of
+// challenge:nonce starts with Difficulty zero bits.
+//
+// Challenges are stateless apart from the ones already used, which are
+// remembered until they expire so that a proof is accepted once. They are
+// remembered per server.
+package pow
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalid = errors.New("invalid proof of work challenge")
+	ErrExpired = errors.New("proof of work challenge expired")
+	ErrUsed    = errors.New("proof of work challenge was already used")
+	// ErrInsufficient is returned when the hash of a proof does not have
+	// enough leading zero bits.
+	ErrInsufficient = errors.New("proof of work does not solve the challenge")
+)
+
+type Challenge struct {
+	Value      string
+	Difficulty int
+	ExpiresAt  time.Time
+}
+
+type Verifier struct {
+	secret     []byte
+	difficulty int
+	ttl        time.Duration
+
+	mu   sync.Mutex
+	used map[string]time.Time
+	now  func() time.Time
+}
+
+// NewVerifier returns a Verifier that signs challenges with secret. A
+// challenge expires ttl after it is issued.
+func NewVerifier(secret []byte, difficulty int, ttl time.Duration) *Verifier {
+	return &Verifier{
+		secret:     secret,
+		difficulty: difficulty,
+		ttl:        ttl,
+		used:       make(map[string]time.Time),
+		now:        time.Now,
+	}
+}
+
+// NewChallenge returns a challenge of the form
+// expiry.difficulty.random.signature.
+func (v *Verifier) NewChallenge() (Challenge, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return Challenge{}, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	expiresAt := v.now().Add(v.ttl).Truncate(time.Second)
+	payload := fmt.Sprintf("%d.%d.%s", expiresAt.Unix(), v.difficulty, hex.EncodeToString(random))
+	return Challenge{
+		Value:      payload + "." + v.sign(payload),
+		Difficulty: v.difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// Verify checks that nonce solves challenge, and that challenge was issued
+// by v, has not expired and was not used before.
+func (v *Verifier) Verify(challenge, nonce string) error {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 {
+		return ErrInvalid
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(v.sign(payload))) {
+		return ErrInvalid
+	}
+	expiry, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return ErrInvalid
+	}
+	expiresAt := time.Unix(expiry, 0)
+
+	now := v.now()
+	if !now.Before(expiresAt) {
+		return ErrExpired
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(challenge+":"+nonce))) < difficulty {
+		return ErrInsufficient
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for used, expiresAt := range v.used {
+		if !now.Before(expiresAt) {
+			delete(v.used, used)
+		}
+	}
+	if _, ok := v.used[challenge]; ok {
+		return ErrUsed
+	}
+	v.used[challenge] = expiresAt
+	return nil
+}
+
+func (v *Verifier) sign(payload string) string {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func leadingZeroBits(hash [sha256.Size]byte) int {
+	count := 0
+	for _, b := range hash {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+	return count
+}
@@ -0,0 +1,181 @@
+// Package anki turns Anki deck packages (.apkg) into quiz.CreateQuizCommand.
+//
+// An .apkg file is a zip archive holding the collection as a SQLite database.
+// Every note becomes one exercise, or one per cloze deletion for cloze notes,
+// placed in a section named after the deck of its first card. Basic notes
+// either become fill in the blank exercises asking for the back of the card,
+// or multiple choice exercises whose distractors are the backs of other cards
+// in the same deck.
+//
+// Only the legacy collection schema is read. Packages exported with "Support
+// older Anki versions" turned off hold the collection in collection.anki21b,
+// next to a collection.anki2 stub that only asks to update Anki, and are
+// rejected.
+package anki
+
+import (
+	"archive/zip"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"languagequiz/quiz"
+
+	"golang.org/x/text/language"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	ModeFillInTheBlank = "fillInTheBlank"
+	ModeMultipleChoice = "multipleChoice"
+)
+
+// collectionFiles are the database names inside a package, newest first.
+var collectionFiles = []string{"collection.anki21", "collection.anki2"}
+
+// newCollectionFile is the database name of packages in the new format.
+const newCollectionFile = "collection.anki21b"
+
+// MaxCollectionSize is the largest collection database that is extracted
+// from a package. A small package can hold a collection that is far larger
+// once uncompressed.
+const MaxCollectionSize = 256 << 20
+
+var (
+	ErrCollectionTooLarge  = fmt.Errorf("collection is larger than %d MiB", MaxCollectionSize>>20)
+	ErrNewCollectionFormat = errors.New("package uses the new collection format; export it again with 'Support older Anki versions' enabled")
+)
+
+type Options struct {
+	Name        string
+	LanguageTag language.Tag
+	// Mode is ModeFillInTheBlank or ModeMultipleChoice and applies to notes
+	// that are not cloze notes.
+	Mode string
+	// FrontField and BackField name the note fields to use. When empty, the
+	// first and second field of the note type are used.
+	FrontField string
+	BackField  string
+	// Seed makes the choice of distractors and their order reproducible.
+	Seed int64
+	// SkipInvalid skips notes that cannot be turned into an exercise instead
+	// of failing the import. Skipped notes are returned as warnings.
+	SkipInvalid bool
+}
+
+func NewOptions(name string, languageTag language.Tag) Options {
+	return Options{
+		Name:        name,
+		LanguageTag: languageTag,
+		Mode:        ModeFillInTheBlank,
+	}
+}
+
+// Issue is a problem with a single note.
+type Issue struct {
+	NoteID  int64
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("note %d: %s", i.NoteID, i.Message)
+}
+
+// Errors collects every invalid note in a package.
+type Errors []Issue
+
+func (e Errors) Error() string {
+	messages := make([]string, 0)
+	for _, issue := range e {
+		messages = append(messages, issue.String())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Import reads an .apkg package. It returns Errors when one or more notes are
+// invalid, unless opts.SkipInvalid is set, in which case they are returned as
+// warnings.
+func Import(r io.ReaderAt, size int64, opts Options) (*quiz.CreateQuizCommand, []Issue, error) {
+	if opts.Mode != ModeFillInTheBlank && opts.Mode != ModeMultipleChoice {
+		return nil, nil, fmt.Errorf("unsupported mode: %q", opts.Mode)
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open package: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "anki-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := extractCollection(archive, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open collection: %w", err)
+	}
+	defer db.Close()
+
+	col, err := readCollection(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return convert(col, opts)
+}
+
+func extractCollection(archive *zip.Reader, dir string) (string, error) {
+	files := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	// The collection.anki2 next to a collection.anki21b is a stub, so the
+	// new format is checked first.
+	if _, ok := files[newCollectionFile]; ok {
+		return "", ErrNewCollectionFormat
+	}
+
+	for _, name := range collectionFiles {
+		f, ok := files[name]
+		if !ok {
+			continue
+		}
+		if f.UncompressedSize64 > MaxCollectionSize {
+			return "", ErrCollectionTooLarge
+		}
+
+		src, err := f.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer src.Close()
+
+		path := filepath.Join(dir, name)
+		dst, err := os.Create(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		defer dst.Close()
+
+		// The size in the header is not to be trusted, so the copy is limited
+		// as well.
+		n, err := io.Copy(dst, io.LimitReader(src, MaxCollectionSize+1))
+		if err != nil {
+			return "", fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		if n > MaxCollectionSize {
+			return "", ErrCollectionTooLarge
+		}
+		return path, nil
+	}
+	return "", errors.New("package has no collection")
+}
@@ -0,0 +1,58 @@
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractCollection(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		// want is the name of the extracted file.
+		want    string
+		wantErr error
+	}{
+		{name: "legacy", files: []string{"collection.anki2", "media"}, want: "collection.anki2"},
+		{name: "newest legacy schema first", files: []string{"collection.anki2", "collection.anki21"}, want: "collection.anki21"},
+		{name: "new format with a stub", files: []string{"collection.anki2", "collection.anki21b"}, wantErr: ErrNewCollectionFormat},
+		{name: "new format only", files: []string{"collection.anki21b"}, wantErr: ErrNewCollectionFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := zip.NewWriter(&buf)
+			for _, name := range tt.files {
+				if _, err := w.Create(name); err != nil {
+					t.Fatalf("failed to create %s: %v", name, err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("failed to write package: %v", err)
+			}
+			archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("failed to read package: %v", err)
+			}
+
+			dir := t.TempDir()
+			path, err := extractCollection(archive, dir)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if path != filepath.Join(dir, tt.want) {
+				t.Errorf("path = %s, want %s", path, filepath.Join(dir, tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package anki
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	fieldSeparator      = "\x1f"
+	modelTypeCloze      = 1
+	legacyDeckSeparator = "::"
+)
+
+type collection struct {
+	decks  map[int64]string
+	models map[int64]model
+	notes  []note
+}
+
+type model struct {
+	name    string
+	fields  []string
+	isCloze bool
+}
+
+type note struct {
+	id      int64
+	modelID int64
+	deckID  int64
+	fields  []string
+}
+
+// field returns the value of the named field, or of the field at ord when
+// name is empty.
+func (n note) field(m model, name string, ord int) (string, bool) {
+	if name != "" {
+		ord = -1
+		for i, fieldName := range m.fields {
+			if strings.EqualFold(fieldName, name) {
+				ord = i
+				break
+			}
+		}
+		if ord < 0 {
+			return "", false
+		}
+	}
+	if ord >= len(n.fields) {
+		return "", false
+	}
+	return n.fields[ord], true
+}
+
+type legacyModel struct {
+	Name   string `json:"name"`
+	Type   int    `json:"type"`
+	Fields []struct {
+		Name string `json:"name"`
+		Ord  int    `json:"ord"`
+	} `json:"flds"`
+}
+
+type legacyDeck struct {
+	Name string `json:"name"`
+}
+
+func readCollection(db *sql.DB) (*collection, error) {
+	var modelsJSON, decksJSON string
+	if err := db.QueryRow(`SELECT models, decks FROM col`).Scan(&modelsJSON, &decksJSON); err != nil {
+		return nil, fmt.Errorf("failed to read col table: %w", err)
+	}
+
+	col := &collection{}
+	var err error
+	if strings.TrimSpace(modelsJSON) == "" || strings.TrimSpace(modelsJSON) == "{}" {
+		col.models, col.decks, err = readSchema18(db)
+	} else {
+		col.models, col.decks, err = readLegacySchema(modelsJSON, decksJSON)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	col.notes, err = readNotes(db)
+	if err != nil {
+		return nil, err
+	}
+	return col, nil
+}
+
+func readLegacySchema(modelsJSON, decksJSON string) (map[int64]model, map[int64]string, error) {
+	var legacyModels map[string]legacyModel
+	if err := json.Unmarshal([]byte(modelsJSON), &legacyModels); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode note types: %w", err)
+	}
+	var legacyDecks map[string]legacyDeck
+	if err := json.Unmarshal([]byte(decksJSON), &legacyDecks); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode decks: %w", err)
+	}
+
+	models := make(map[int64]model)
+	for id, m := range legacyModels {
+		modelID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid note type id: %s", id)
+		}
+		sort.Slice(m.Fields, func(i, j int) bool { return m.Fields[i].Ord < m.Fields[j].Ord })
+		fields := make([]string, 0)
+		for _, f := range m.Fields {
+			fields = append(fields, f.Name)
+		}
+		models[modelID] = model{name: m.Name, fields: fields, isCloze: m.Type == modelTypeCloze}
+	}
+
+	decks := make(map[int64]string)
+	for id, d := range legacyDecks {
+		deckID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid deck id: %s", id)
+		}
+		decks[deckID] = d.Name
+	}
+	return models, decks, nil
+}
+
+// readSchema18 reads note types and decks from the tables that replaced the
+// JSON columns of col in Anki 2.1.28. Whether a note type is a cloze type is
+// stored in a protobuf blob, so cloze notes are recognised by their content.
+func readSchema18(db *sql.DB) (map[int64]model, map[int64]string, error) {
+	models := make(map[int64]model)
+	rows, err := db.Query(`SELECT nt.id, nt.name, f.name FROM notetypes nt JOIN fields f ON f.ntid = nt.id ORDER BY nt.id, f.ord`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query note types: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var name, fieldName string
+		if err := rows.Scan(&id, &name, &fieldName); err != nil {
+			return nil, nil, fmt.Errorf("failed to read note type: %w", err)
+		}
+		m := models[id]
+		m.name = name
+		m.fields = append(m.fields, fieldName)
+		models[id] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read note types: %w", err)
+	}
+
+	decks := make(map[int64]string)
+	deckRows, err := db.Query(`SELECT id, name FROM decks`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query decks: %w", err)
+	}
+	defer deckRows.Close()
+	for deckRows.Next() {
+		var id int64
+		var name string
+		if err := deckRows.Scan(&id, &name); err != nil {
+			return nil, nil, fmt.Errorf("failed to read deck: %w", err)
+		}
+		decks[id] = strings.ReplaceAll(name, fieldSeparator, legacyDeckSeparator)
+	}
+	if err := deckRows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read decks: %w", err)
+	}
+	return models, decks, nil
+}
+
+// readNotes reads every note with the deck of its first card.
+func readNotes(db *sql.DB) ([]note, error) {
+	rows, err := db.Query(`
+		SELECT n.id, n.mid, n.flds, c.did
+		FROM notes n
+		JOIN cards c ON c.id = (
+			SELECT c2.id FROM cards c2 WHERE c2.nid = n.id ORDER BY c2.ord, c2.id LIMIT 1
+		)
+		ORDER BY n.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := make([]note, 0)
+	for rows.Next() {
+		var n note
+		var fields string
+		if err := rows.Scan(&n.id, &n.modelID, &fields, &n.deckID); err != nil {
+			return nil, fmt.Errorf("failed to read note: %w", err)
+		}
+		n.fields = strings.Split(fields, fieldSeparator)
+		notes = append(notes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read notes: %w", err)
+	}
+	return notes, nil
+}
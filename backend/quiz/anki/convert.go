@@ -0,0 +1,290 @@
+package anki
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+)
+
+const (
+	blank          = "______"
+	distractorSize = 3
+)
+
+var (
+	clozeRegex      = regexp.MustCompile(`\{\{c(\d+)::(.*?)(?:::(.*?))?\}\}`)
+	lineBreakRegex  = regexp.MustCompile(`(?i)<br\s*/?>|</(div|p|li)>`)
+	tagRegex        = regexp.MustCompile(`<[^>]*>`)
+	soundRegex      = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// cleanField turns the HTML of a note field into plain text.
+func cleanField(s string) string {
+	s = lineBreakRegex.ReplaceAllString(s, " ")
+	s = tagRegex.ReplaceAllString(s, "")
+	s = soundRegex.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(whitespaceRegex.ReplaceAllString(s, " "))
+}
+
+type card struct {
+	noteID   int64
+	deck     string
+	question string
+	answer   string
+}
+
+type section struct {
+	name     string
+	deck     string
+	typ      string
+	commands []exercise.CreateExerciseCommand
+}
+
+func convert(col *collection, opts Options) (*quiz.CreateQuizCommand, []Issue, error) {
+	issues := make([]Issue, 0)
+	deckNames := newDeckNames(col)
+
+	sections := make([]*section, 0)
+	sectionsByKey := make(map[string]*section)
+	add := func(deck string, cmd exercise.CreateExerciseCommand) {
+		key := deck + "\x00" + cmd.Type()
+		s, ok := sectionsByKey[key]
+		if !ok {
+			s = &section{name: deckNames.display(deck), deck: deck, typ: cmd.Type()}
+			sectionsByKey[key] = s
+			sections = append(sections, s)
+		}
+		s.commands = append(s.commands, cmd)
+	}
+
+	basicCards := make([]card, 0)
+	for _, n := range col.notes {
+		m, ok := col.models[n.modelID]
+		if !ok {
+			issues = append(issues, Issue{NoteID: n.id, Message: "note type not found"})
+			continue
+		}
+		deck := col.decks[n.deckID]
+
+		front, ok := n.field(m, opts.FrontField, 0)
+		if !ok {
+			issues = append(issues, Issue{NoteID: n.id, Message: fmt.Sprintf("note type %q has no front field %q", m.name, opts.FrontField)})
+			continue
+		}
+
+		if m.isCloze || clozeRegex.MatchString(front) {
+			extra, _ := n.field(m, "", 1)
+			cmds, err := clozeCommands(front, optional(cleanField(extra)))
+			if err != nil {
+				issues = append(issues, Issue{NoteID: n.id, Message: err.Error()})
+				continue
+			}
+			for _, cmd := range cmds {
+				add(deck, cmd)
+			}
+			continue
+		}
+
+		back, ok := n.field(m, opts.BackField, 1)
+		if !ok {
+			issues = append(issues, Issue{NoteID: n.id, Message: fmt.Sprintf("note type %q has no back field %q", m.name, opts.BackField)})
+			continue
+		}
+		c := card{noteID: n.id, deck: deck, question: cleanField(front), answer: cleanField(back)}
+		if c.question == "" || c.answer == "" {
+			issues = append(issues, Issue{NoteID: n.id, Message: "front or back is empty"})
+			continue
+		}
+		basicCards = append(basicCards, c)
+	}
+
+	random := rand.New(rand.NewSource(opts.Seed))
+	for _, c := range basicCards {
+		var cmd exercise.CreateExerciseCommand
+		var err error
+		switch opts.Mode {
+		case ModeMultipleChoice:
+			cmd, err = multipleChoiceCommand(c, basicCards, random)
+		default:
+			question := c.question
+			if !strings.Contains(question, blank) {
+				question += " " + blank
+			}
+			cmd, err = exercise.NewCreateFillInTheBlankExerciseCommand(question, c.answer, nil)
+		}
+		if err != nil {
+			issues = append(issues, Issue{NoteID: c.noteID, Message: err.Error()})
+			continue
+		}
+		add(c.deck, cmd)
+	}
+
+	if len(issues) > 0 && !opts.SkipInvalid {
+		return nil, nil, Errors(issues)
+	}
+
+	createSectionCommands := make([]quiz.CreateSectionCommand, 0)
+	typesByDeck := make(map[string]int)
+	for _, s := range sections {
+		typesByDeck[s.deck]++
+	}
+	for _, s := range sections {
+		name := s.name
+		if name == "" {
+			name = opts.Name
+		}
+		if typesByDeck[s.deck] > 1 {
+			name = fmt.Sprintf("%s (%s)", name, typeLabels[s.typ])
+		}
+		createSectionCommand, err := quiz.NewCreateSectionCommand(name, s.commands)
+		if err != nil {
+			return nil, nil, err
+		}
+		createSectionCommands = append(createSectionCommands, *createSectionCommand)
+	}
+	if len(createSectionCommands) == 0 {
+		return nil, issues, errors.New("package has no usable notes")
+	}
+
+	cmd := quiz.NewCreateQuizCommand(opts.Name, opts.LanguageTag, createSectionCommands)
+	return &cmd, issues, nil
+}
+
+var typeLabels = map[string]string{
+	exercise.TypeMultipleChoice:     "multiple choice",
+	exercise.TypeFillInTheBlank:     "fill in the blank",
+	exercise.TypeSentenceCorrection: "sentence correction",
+}
+
+// clozeCommands returns one fill in the blank exercise per cloze number, with
+// the other deletions of the note filled in.
+func clozeCommands(text string, feedback *string) ([]exercise.CreateExerciseCommand, error) {
+	text = cleanField(text)
+	matches := clozeRegex.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return nil, errors.New("cloze note has no deletions")
+	}
+
+	numbers := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, match := range matches {
+		number, _ := strconv.Atoi(match[1])
+		if !seen[number] {
+			seen[number] = true
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+
+	cmds := make([]exercise.CreateExerciseCommand, 0)
+	for _, number := range numbers {
+		answer := ""
+		question := clozeRegex.ReplaceAllStringFunc(text, func(deletion string) string {
+			match := clozeRegex.FindStringSubmatch(deletion)
+			if match[1] == strconv.Itoa(number) {
+				answer = match[2]
+				return blank
+			}
+			return match[2]
+		})
+
+		cmd, err := exercise.NewCreateFillInTheBlankExerciseCommand(question, answer, feedback)
+		if err != nil {
+			return nil, fmt.Errorf("cloze c%d: %w", number, err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+// multipleChoiceCommand picks distractors among the answers of other cards,
+// preferring cards from the same deck.
+func multipleChoiceCommand(c card, cards []card, random *rand.Rand) (exercise.CreateExerciseCommand, error) {
+	sameDeck := make([]string, 0)
+	otherDecks := make([]string, 0)
+	seen := map[string]bool{strings.ToLower(c.answer): true}
+	for _, other := range cards {
+		key := strings.ToLower(other.answer)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if other.deck == c.deck {
+			sameDeck = append(sameDeck, other.answer)
+		} else {
+			otherDecks = append(otherDecks, other.answer)
+		}
+	}
+
+	random.Shuffle(len(sameDeck), func(i, j int) { sameDeck[i], sameDeck[j] = sameDeck[j], sameDeck[i] })
+	random.Shuffle(len(otherDecks), func(i, j int) { otherDecks[i], otherDecks[j] = otherDecks[j], otherDecks[i] })
+	candidates := append(sameDeck, otherDecks...)
+	if len(candidates) < distractorSize {
+		return nil, fmt.Errorf("not enough other cards to pick %d distractors from", distractorSize)
+	}
+
+	choices := append([]string{c.answer}, candidates[:distractorSize]...)
+	random.Shuffle(len(choices), func(i, j int) { choices[i], choices[j] = choices[j], choices[i] })
+
+	cmd, err := exercise.NewCreateMultipleChoiceExerciseCommand(c.question, choices, c.answer, nil)
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// deckNames shortens deck names for use as section names by dropping the
+// parent deck that all notes share.
+type deckNames struct {
+	root string
+}
+
+func newDeckNames(col *collection) deckNames {
+	used := make(map[string]bool)
+	for _, n := range col.notes {
+		used[col.decks[n.deckID]] = true
+	}
+	if len(used) < 2 {
+		return deckNames{}
+	}
+
+	root := ""
+	for name := range used {
+		first, _, _ := strings.Cut(name, legacyDeckSeparator)
+		if root == "" {
+			root = first
+		} else if root != first {
+			return deckNames{}
+		}
+	}
+	return deckNames{root: root}
+}
+
+func (d deckNames) display(deck string) string {
+	if d.root != "" {
+		if rest, ok := strings.CutPrefix(deck, d.root+legacyDeckSeparator); ok {
+			deck = rest
+		}
+	}
+	if deck == "" {
+		deck = d.root
+	}
+	return strings.ReplaceAll(deck, legacyDeckSeparator, " / ")
+}
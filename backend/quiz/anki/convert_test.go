@@ -0,0 +1,198 @@
+package anki
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"languagequiz/quiz/exercise"
+)
+
+func TestClozeCommands(t *testing.T) {
+	feedback := "Der Hund is the dog."
+
+	tests := []struct {
+		name     string
+		text     string
+		feedback *string
+		want     []exercise.CreateExerciseCommand
+		wantErr  string
+	}{
+		{
+			name:     "one deletion",
+			text:     "Der {{c1::Hund}} bellt.",
+			feedback: &feedback,
+			want: []exercise.CreateExerciseCommand{
+				&exercise.CreateFillInTheBlankExerciseCommand{Question: "Der ______ bellt.", Answer: "Hund", Feedback: &feedback},
+			},
+		},
+		{
+			name: "one exercise per number, in order, with the others filled in",
+			text: "Die {{c2::Katze::Tier}} jagt die {{c1::Maus}}.",
+			want: []exercise.CreateExerciseCommand{
+				&exercise.CreateFillInTheBlankExerciseCommand{Question: "Die Katze jagt die ______.", Answer: "Maus"},
+				&exercise.CreateFillInTheBlankExerciseCommand{Question: "Die ______ jagt die Maus.", Answer: "Katze"},
+			},
+		},
+		{
+			name: "html cleaned",
+			text: "<div>Der {{c1::<b>Hund</b>}}</div>bellt&amp;knurrt.",
+			want: []exercise.CreateExerciseCommand{
+				&exercise.CreateFillInTheBlankExerciseCommand{Question: "Der ______ bellt&knurrt.", Answer: "Hund"},
+			},
+		},
+		{
+			name:    "no deletions",
+			text:    "Der Hund bellt.",
+			wantErr: "cloze note has no deletions",
+		},
+		{
+			name:    "number used twice",
+			text:    "{{c1::Der}} {{c1::Hund}} bellt.",
+			wantErr: "cloze c1: more than one blank '______' found in question",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := clozeCommands(tt.text, tt.feedback)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultipleChoiceCommand(t *testing.T) {
+	c := card{noteID: 1, deck: "German::Animals", question: "dog", answer: "Hund"}
+
+	tests := []struct {
+		name  string
+		cards []card
+		// wantChoices are the choices in any order.
+		wantChoices []string
+		wantErr     string
+	}{
+		{
+			name: "distractors from the same deck first",
+			cards: []card{
+				c,
+				{deck: "German::Animals", answer: "Katze"},
+				{deck: "German::Colours", answer: "rot"},
+				{deck: "German::Animals", answer: "Maus"},
+				{deck: "German::Animals", answer: "Pferd"},
+			},
+			wantChoices: []string{"Hund", "Katze", "Maus", "Pferd"},
+		},
+		{
+			name: "other decks when the deck is too small",
+			cards: []card{
+				c,
+				{deck: "German::Animals", answer: "Katze"},
+				{deck: "German::Colours", answer: "rot"},
+				{deck: "German::Colours", answer: "blau"},
+			},
+			wantChoices: []string{"Hund", "Katze", "blau", "rot"},
+		},
+		{
+			name: "answers compared regardless of case",
+			cards: []card{
+				c,
+				{deck: "German::Animals", answer: "hund"},
+				{deck: "German::Animals", answer: "Katze"},
+				{deck: "German::Animals", answer: "katze"},
+				{deck: "German::Animals", answer: "Maus"},
+				{deck: "German::Animals", answer: "Pferd"},
+			},
+			wantChoices: []string{"Hund", "Katze", "Maus", "Pferd"},
+		},
+		{
+			name: "not enough other cards",
+			cards: []card{
+				c,
+				{deck: "German::Animals", answer: "Katze"},
+				{deck: "German::Animals", answer: "KATZE"},
+				{deck: "German::Animals", answer: "Maus"},
+			},
+			wantErr: "not enough other cards to pick 3 distractors from",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := multipleChoiceCommand(c, tt.cards, rand.New(rand.NewSource(1)))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cmd := got.(*exercise.CreateMultipleChoiceExerciseCommand)
+			if cmd.Question != c.question || cmd.Answer != c.answer {
+				t.Errorf("question, answer = %q, %q, want %q, %q", cmd.Question, cmd.Answer, c.question, c.answer)
+			}
+			choices := append([]string(nil), cmd.Choices...)
+			sort.Strings(choices)
+			if !reflect.DeepEqual(choices, tt.wantChoices) {
+				t.Errorf("choices = %v, want %v in any order", cmd.Choices, tt.wantChoices)
+			}
+		})
+	}
+}
+
+func TestDeckNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		decks []string
+		// want is the display name of each deck.
+		want []string
+	}{
+		{
+			name:  "one deck kept whole",
+			decks: []string{"German::Animals"},
+			want:  []string{"German / Animals"},
+		},
+		{
+			name:  "shared parent dropped",
+			decks: []string{"German::Animals", "German::Colours::Light", "German"},
+			want:  []string{"Animals", "Colours / Light", "German"},
+		},
+		{
+			name:  "different parents kept",
+			decks: []string{"German::Animals", "French::Animals"},
+			want:  []string{"German / Animals", "French / Animals"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			col := &collection{decks: make(map[int64]string)}
+			for i, deck := range tt.decks {
+				col.decks[int64(i)] = deck
+				col.notes = append(col.notes, note{id: int64(i), deckID: int64(i)})
+			}
+
+			names := newDeckNames(col)
+			got := make([]string, 0)
+			for _, deck := range tt.decks {
+				got = append(got, names.display(deck))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("display names = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -25,6 +25,49 @@ func NewCreateQuizCommand(
 	}
 }
 
+// CloneQuizCommand copies the quiz with QuizID. The copy takes Name and
+// LanguageTag unless they are empty or undetermined.
+type CloneQuizCommand struct {
+	QuizID      string
+	Name        string
+	LanguageTag language.Tag
+}
+
+// NewCreateQuizCommandFrom returns the command that creates a copy of q.
+func NewCreateQuizCommandFrom(q Quiz) (CreateQuizCommand, error) {
+	sections := make([]CreateSectionCommand, 0, len(q.Sections))
+	for _, s := range q.Sections {
+		exercises := make([]exercise.CreateExerciseCommand, 0, len(s.Exercises))
+		for _, e := range s.Exercises {
+			switch e := e.(type) {
+			case *exercise.MultipleChoiceExercise:
+				exercises = append(exercises, &exercise.CreateMultipleChoiceExerciseCommand{
+					Question: e.Question,
+					Choices:  e.Choices,
+					Answer:   e.Answer().(string),
+					Feedback: e.Feedback(),
+				})
+			case *exercise.FillInTheBlankExercise:
+				exercises = append(exercises, &exercise.CreateFillInTheBlankExerciseCommand{
+					Question: e.Question,
+					Answer:   e.Answer().(string),
+					Feedback: e.Feedback(),
+				})
+			case *exercise.SentenceCorrectionExercise:
+				exercises = append(exercises, &exercise.CreateSentenceCorrectionExerciseCommand{
+					Sentence:          e.Sentence,
+					CorrectedSentence: e.CorrectedSentence,
+					Feedback:          e.Feedback(),
+				})
+			default:
+				return CreateQuizCommand{}, fmt.Errorf("unknown exercise type: %T", e)
+			}
+		}
+		sections = append(sections, CreateSectionCommand{Name: s.Name, Exercises: exercises})
+	}
+	return NewCreateQuizCommand(q.Name, q.LanguageTag, sections), nil
+}
+
 type CreateSectionCommand struct {
 	Name      string
 	Exercises []exercise.CreateExerciseCommand
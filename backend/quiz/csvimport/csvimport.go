@@ -0,0 +1,348 @@
+// Package csvimport turns CSV and TSV spreadsheets into quiz.CreateQuizCommand.
+//
+// Every row is one exercise. Rows are grouped into sections by the section
+// column, in order of first appearance. Sentence correction exercises take
+// the sentence from the question column and the corrected sentence from the
+// answer column.
+package csvimport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+const defaultChoiceSeparator = "|"
+
+// ColumnMapping maps exercise fields to header names. Choices is either a
+// single column holding separated choices or one column per choice.
+type ColumnMapping struct {
+	Section  string
+	Type     string
+	Question string
+	Choices  []string
+	Answer   string
+	Feedback string
+}
+
+func DefaultColumnMapping() ColumnMapping {
+	return ColumnMapping{
+		Section:  "section",
+		Type:     "type",
+		Question: "question",
+		Choices:  []string{"choices"},
+		Answer:   "answer",
+		Feedback: "feedback",
+	}
+}
+
+// ParseColumnMapping parses overrides of the default mapping written as
+// comma-separated field:header pairs, e.g. "question:Front,answer:Back".
+// Choices may be given multiple times to map one column per choice.
+func ParseColumnMapping(s string) (ColumnMapping, error) {
+	mapping := DefaultColumnMapping()
+	if strings.TrimSpace(s) == "" {
+		return mapping, nil
+	}
+
+	choices := make([]string, 0)
+	for _, pair := range strings.Split(s, ",") {
+		field, header, ok := strings.Cut(pair, ":")
+		field, header = strings.TrimSpace(field), strings.TrimSpace(header)
+		if !ok || header == "" {
+			return ColumnMapping{}, fmt.Errorf("invalid column mapping %q, expected field:header", pair)
+		}
+
+		switch field {
+		case "section":
+			mapping.Section = header
+		case "type":
+			mapping.Type = header
+		case "question":
+			mapping.Question = header
+		case "choices":
+			choices = append(choices, header)
+		case "answer":
+			mapping.Answer = header
+		case "feedback":
+			mapping.Feedback = header
+		default:
+			return ColumnMapping{}, fmt.Errorf("unknown field in column mapping: %s", field)
+		}
+	}
+	if len(choices) > 0 {
+		mapping.Choices = choices
+	}
+	return mapping, nil
+}
+
+type Options struct {
+	Name        string
+	LanguageTag language.Tag
+	// Comma is the field delimiter, ',' for CSV and '\t' for TSV. When zero,
+	// it is detected from the header row.
+	Comma           rune
+	Columns         ColumnMapping
+	ChoiceSeparator string
+	// DefaultSection is used for rows without a section.
+	DefaultSection string
+}
+
+func NewOptions(name string, languageTag language.Tag, columns ColumnMapping) Options {
+	return Options{
+		Name:            name,
+		LanguageTag:     languageTag,
+		Columns:         columns,
+		ChoiceSeparator: defaultChoiceSeparator,
+		DefaultSection:  name,
+	}
+}
+
+// RowError is a problem with a single row. Row is the 1-based line on which
+// the row starts, counting the header row.
+type RowError struct {
+	Row    int
+	Column string
+	Err    error
+}
+
+func (e RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Err)
+	}
+	return fmt.Sprintf("row %d, column %s: %s", e.Row, e.Column, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// Errors collects every row error found in a file.
+type Errors []RowError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0)
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// DetectComma returns the delimiter used in the given header line.
+func DetectComma(header string) rune {
+	if strings.Count(header, "\t") > strings.Count(header, ",") {
+		return '\t'
+	}
+	return ','
+}
+
+type section struct {
+	name     string
+	row      int
+	commands []exercise.CreateExerciseCommand
+}
+
+// Import reads all rows of r. It returns Errors when one or more rows are
+// invalid.
+func Import(r io.Reader, opts Options) (*quiz.CreateQuizCommand, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	content := strings.TrimPrefix(string(data), "\ufeff")
+
+	comma := opts.Comma
+	if comma == 0 {
+		header, _, _ := strings.Cut(content, "\n")
+		comma = DetectComma(header)
+	}
+	separator := opts.ChoiceSeparator
+	if separator == "" {
+		separator = defaultChoiceSeparator
+	}
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	if comma == '\t' {
+		reader.LazyQuotes = true
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file is empty")
+		}
+		return nil, fmt.Errorf("failed to read header row: %w", err)
+	}
+	columns, err := newColumnIndex(header, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	rowErrors := make(Errors, 0)
+	sections := make([]*section, 0)
+	sectionsByName := make(map[string]*section)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, RowError{Row: parseErr.Line, Err: parseErr.Err})
+				continue
+			}
+			return nil, fmt.Errorf("failed to read row: %w", err)
+		}
+		rowNumber, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+
+		sectionName := columns.value(record, opts.Columns.Section)
+		if sectionName == "" {
+			sectionName = opts.DefaultSection
+		}
+
+		cmd, rowErr := columns.toCommand(record, opts.Columns, separator)
+		if rowErr != nil {
+			rowErr.Row = rowNumber
+			rowErrors = append(rowErrors, *rowErr)
+			continue
+		}
+
+		s, ok := sectionsByName[sectionName]
+		if !ok {
+			s = &section{name: sectionName, row: rowNumber}
+			sectionsByName[sectionName] = s
+			sections = append(sections, s)
+		}
+		s.commands = append(s.commands, cmd)
+	}
+
+	createSectionCommands := make([]quiz.CreateSectionCommand, 0)
+	for _, s := range sections {
+		createSectionCommand, err := quiz.NewCreateSectionCommand(s.name, s.commands)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: s.row, Column: opts.Columns.Section, Err: err})
+			continue
+		}
+		createSectionCommands = append(createSectionCommands, *createSectionCommand)
+	}
+	if len(createSectionCommands) == 0 && len(rowErrors) == 0 {
+		return nil, errors.New("file has no exercises")
+	}
+	if len(rowErrors) > 0 {
+		return nil, rowErrors
+	}
+
+	cmd := quiz.NewCreateQuizCommand(opts.Name, opts.LanguageTag, createSectionCommands)
+	return &cmd, nil
+}
+
+type columnIndex map[string]int
+
+func newColumnIndex(header []string, mapping ColumnMapping) (columnIndex, error) {
+	index := make(columnIndex)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, required := range []string{mapping.Question, mapping.Answer} {
+		if _, ok := index[strings.ToLower(required)]; !ok {
+			return nil, fmt.Errorf("required column is missing: %s", required)
+		}
+	}
+	return index, nil
+}
+
+func (c columnIndex) value(record []string, column string) string {
+	i, ok := c[strings.ToLower(column)]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func (c columnIndex) choices(record []string, mapping ColumnMapping, separator string) []string {
+	choices := make([]string, 0)
+	if len(mapping.Choices) == 1 {
+		for _, choice := range strings.Split(c.value(record, mapping.Choices[0]), separator) {
+			if choice = strings.TrimSpace(choice); choice != "" {
+				choices = append(choices, choice)
+			}
+		}
+		return choices
+	}
+	for _, column := range mapping.Choices {
+		if choice := c.value(record, column); choice != "" {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+func (c columnIndex) toCommand(record []string, mapping ColumnMapping, separator string) (exercise.CreateExerciseCommand, *RowError) {
+	question := c.value(record, mapping.Question)
+	answer := c.value(record, mapping.Answer)
+	choices := c.choices(record, mapping, separator)
+
+	var feedback *string
+	if value := c.value(record, mapping.Feedback); value != "" {
+		feedback = &value
+	}
+
+	exerciseType := c.value(record, mapping.Type)
+	if exerciseType == "" {
+		exerciseType = inferType(choices)
+	}
+
+	if question == "" {
+		return nil, &RowError{Column: mapping.Question, Err: errors.New("required field is missing")}
+	}
+	if answer == "" {
+		return nil, &RowError{Column: mapping.Answer, Err: errors.New("required field is missing")}
+	}
+
+	var cmd exercise.CreateExerciseCommand
+	var err error
+	switch exerciseType {
+	case exercise.TypeMultipleChoice:
+		cmd, err = exercise.NewCreateMultipleChoiceExerciseCommand(question, choices, answer, feedback)
+	case exercise.TypeFillInTheBlank:
+		cmd, err = exercise.NewCreateFillInTheBlankExerciseCommand(question, answer, feedback)
+	case exercise.TypeSentenceCorrection:
+		cmd, err = exercise.NewCreateSentenceCorrectionExerciseCommand(question, answer, feedback)
+	default:
+		return nil, &RowError{Column: mapping.Type, Err: fmt.Errorf("unsupported exercise type: %q", exerciseType)}
+	}
+	if err != nil {
+		return nil, &RowError{Err: err}
+	}
+	return cmd, nil
+}
+
+func inferType(choices []string) string {
+	if len(choices) > 0 {
+		return exercise.TypeMultipleChoice
+	}
+	return exercise.TypeFillInTheBlank
+}
+
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
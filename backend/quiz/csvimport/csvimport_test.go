@@ -0,0 +1,240 @@
+package csvimport
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+func TestDetectComma(t *testing.T) {
+	tests := []struct {
+		header string
+		want   rune
+	}{
+		{"section,question,answer", ','},
+		{"section\tquestion\tanswer", '\t'},
+		{"question\tanswer, with a comma\tfeedback", '\t'},
+		{"question,answer\twith a tab,feedback", ','},
+		{"question\tanswer,feedback", ','},
+		{"question", ','},
+	}
+
+	for _, tt := range tests {
+		if got := DetectComma(tt.header); got != tt.want {
+			t.Errorf("DetectComma(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestParseColumnMapping(t *testing.T) {
+	defaults := DefaultColumnMapping()
+	withFrontAndBack := DefaultColumnMapping()
+	withFrontAndBack.Question = "Front"
+	withFrontAndBack.Answer = "Back"
+	withChoiceColumns := DefaultColumnMapping()
+	withChoiceColumns.Choices = []string{"A", "B", "C", "D"}
+
+	tests := []struct {
+		name    string
+		s       string
+		want    ColumnMapping
+		wantErr string
+	}{
+		{name: "empty", s: " ", want: defaults},
+		{name: "overrides", s: "question:Front, answer : Back", want: withFrontAndBack},
+		{name: "one column per choice", s: "choices:A,choices:B,choices:C,choices:D", want: withChoiceColumns},
+		{name: "missing header", s: "question:", wantErr: `invalid column mapping "question:", expected field:header`},
+		{name: "missing colon", s: "question", wantErr: `invalid column mapping "question", expected field:header`},
+		{name: "unknown field", s: "hint:Notes", wantErr: "unknown field in column mapping: hint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseColumnMapping(tt.s)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mapping = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	feedback := "Rot is red."
+
+	tests := []struct {
+		name    string
+		content string
+		// columns overrides the default mapping.
+		columns string
+		want    []quiz.CreateSectionCommand
+	}{
+		{
+			name: "sections in order of first appearance",
+			content: "section,question,choices,answer,feedback\n" +
+				"Colours,What is red?,rot|blau|grün|gelb,rot,Rot is red.\n" +
+				"Animals,Der ______ bellt.,,Hund,\n" +
+				"Colours,What is blue?,rot|blau|grün|gelb,blau,\n",
+			want: []quiz.CreateSectionCommand{
+				{Name: "Colours", Exercises: []exercise.CreateExerciseCommand{
+					&exercise.CreateMultipleChoiceExerciseCommand{Question: "What is red?", Choices: []string{"rot", "blau", "grün", "gelb"}, Answer: "rot", Feedback: &feedback},
+					&exercise.CreateMultipleChoiceExerciseCommand{Question: "What is blue?", Choices: []string{"rot", "blau", "grün", "gelb"}, Answer: "blau"},
+				}},
+				{Name: "Animals", Exercises: []exercise.CreateExerciseCommand{
+					&exercise.CreateFillInTheBlankExerciseCommand{Question: "Der ______ bellt.", Answer: "Hund"},
+				}},
+			},
+		},
+		{
+			name:    "tabs, a byte order mark and rows without a section",
+			content: "\ufeffquestion\tanswer\n\nDer ______ bellt.\tHund\n",
+			want: []quiz.CreateSectionCommand{
+				{Name: "Animals", Exercises: []exercise.CreateExerciseCommand{
+					&exercise.CreateFillInTheBlankExerciseCommand{Question: "Der ______ bellt.", Answer: "Hund"},
+				}},
+			},
+		},
+		{
+			name: "quoted fields",
+			content: "type,question,answer\n" +
+				`sentenceCorrection,"Er sagt, ""ich gehen"".","Er sagt, ""ich gehe""."` + "\n" +
+				"sentenceCorrection,\"Ich habe\nein Hund.\",\"Ich habe\neinen Hund.\"\n",
+			want: []quiz.CreateSectionCommand{
+				{Name: "Animals", Exercises: []exercise.CreateExerciseCommand{
+					&exercise.CreateSentenceCorrectionExerciseCommand{Sentence: `Er sagt, "ich gehen".`, CorrectedSentence: `Er sagt, "ich gehe".`},
+					&exercise.CreateSentenceCorrectionExerciseCommand{Sentence: "Ich habe\nein Hund.", CorrectedSentence: "Ich habe\neinen Hund."},
+				}},
+			},
+		},
+		{
+			name:    "header overrides matched regardless of case",
+			content: " FRONT ,back,a,b,c,d\nWhat is red?,rot,rot,blau,grün,gelb\n",
+			columns: "question:Front,answer:Back,choices:A,choices:B,choices:C,choices:D",
+			want: []quiz.CreateSectionCommand{
+				{Name: "Animals", Exercises: []exercise.CreateExerciseCommand{
+					&exercise.CreateMultipleChoiceExerciseCommand{Question: "What is red?", Choices: []string{"rot", "blau", "grün", "gelb"}, Answer: "rot"},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := ParseColumnMapping(tt.columns)
+			if err != nil {
+				t.Fatalf("failed to parse column mapping: %v", err)
+			}
+			opts := NewOptions("Animals", language.German, columns)
+
+			cmd, err := Import(strings.NewReader(tt.content), opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := quiz.NewCreateQuizCommand("Animals", language.German, tt.want)
+			if !reflect.DeepEqual(*cmd, want) {
+				t.Errorf("command = %+v, want %+v", *cmd, want)
+			}
+		})
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		// wantRows are the rows of the RowErrors, if any.
+		wantRows []int
+	}{
+		{
+			name:    "empty file",
+			content: "",
+			wantErr: "file is empty",
+		},
+		{
+			name:    "no exercises",
+			content: "question,answer\n,\n",
+			wantErr: "file has no exercises",
+		},
+		{
+			name:    "missing column",
+			content: "question,solution\nDer ______ bellt.,Hund\n",
+			wantErr: "required column is missing: answer",
+		},
+		{
+			name: "every invalid row",
+			content: "type,question,choices,answer\n" +
+				"fillInTheBlank,Der ______ bellt.,,\n" +
+				"essay,Describe your dog.,,Brown\n" +
+				"multipleChoice,What is red?,rot|blau|grün,rot\n" +
+				"fillInTheBlank,Der Hund bellt.,,Hund\n",
+			wantErr: "row 2, column answer: required field is missing; " +
+				`row 3, column type: unsupported exercise type: "essay"; ` +
+				"row 4: expected 4 choices, found: 3; " +
+				"row 5: no blank '______' found in question",
+			wantRows: []int{2, 3, 4, 5},
+		},
+		{
+			name: "rows counted by line after a quoted line break",
+			content: "question,answer\n" +
+				"\"Der ______\nbellt.\",Hund\n" +
+				"Die ______ miaut.,\n",
+			wantErr:  "row 4, column answer: required field is missing",
+			wantRows: []int{4},
+		},
+		{
+			name: "malformed quotes",
+			content: "question,answer\n" +
+				"Der \"______\" bellt.,Hund\n" +
+				"Die ______ miaut.,Katze\n",
+			wantErr:  `row 2: bare " in non-quoted-field`,
+			wantRows: []int{2},
+		},
+		{
+			name: "section with more than one exercise type",
+			content: "section,question,choices,answer\n" +
+				"Pets,Der ______ bellt.,,Hund\n" +
+				"Pets,What is red?,rot|blau|grün|gelb,rot\n",
+			wantErr:  "row 2, column section: section cannot have more than one exercise type: [fillInTheBlank multipleChoice]",
+			wantRows: []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewOptions("Animals", language.German, DefaultColumnMapping())
+			_, err := Import(strings.NewReader(tt.content), opts)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("error = %v, want %s", err, tt.wantErr)
+			}
+
+			rowErrors, ok := err.(Errors)
+			if tt.wantRows == nil {
+				if ok {
+					t.Errorf("error is %T, want an error about the whole file", err)
+				}
+				return
+			}
+			rows := make([]int, 0)
+			for _, rowErr := range rowErrors {
+				rows = append(rows, rowErr.Row)
+			}
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("rows = %v, want %v", rows, tt.wantRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package quiz
+
+import (
+	"reflect"
+
+	"languagequiz/quiz/exercise"
+)
+
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is a difference between two versions of a quiz. It is about the
+// quiz when ExerciseID is empty and Section is -1, about a section when only
+// ExerciseID is empty, and about an exercise otherwise. Field names the field
+// that changed; it is empty when a section or an exercise is added or
+// removed.
+type Change struct {
+	Kind ChangeKind
+	// Section is the index of the section in the newer version, or in the
+	// older one when it was removed.
+	Section    int
+	ExerciseID string
+	Field      string
+	Old        any
+	New        any
+}
+
+// Diff returns the changes that turn from into to. Exercises are matched by
+// ID, so an exercise that moved to another section is changed rather than
+// removed and added again. Sections are matched by position.
+func Diff(from, to Quiz) []Change {
+	changes := make([]Change, 0)
+	if from.Name != to.Name {
+		changes = append(changes, Change{Kind: ChangeChanged, Section: -1, Field: "name", Old: from.Name, New: to.Name})
+	}
+	if from.LanguageTag != to.LanguageTag {
+		changes = append(changes, Change{Kind: ChangeChanged, Section: -1, Field: "languageTag",
+			Old: from.LanguageTag.String(), New: to.LanguageTag.String()})
+	}
+
+	for i := 0; i < max(len(from.Sections), len(to.Sections)); i++ {
+		switch {
+		case i >= len(from.Sections):
+			changes = append(changes, Change{Kind: ChangeAdded, Section: i, New: to.Sections[i].Name})
+		case i >= len(to.Sections):
+			changes = append(changes, Change{Kind: ChangeRemoved, Section: i, Old: from.Sections[i].Name})
+		case from.Sections[i].Name != to.Sections[i].Name:
+			changes = append(changes, Change{Kind: ChangeChanged, Section: i, Field: "name",
+				Old: from.Sections[i].Name, New: to.Sections[i].Name})
+		}
+	}
+
+	old := indexExercises(from)
+	seen := make(map[string]bool)
+	for _, e := range listExercises(to) {
+		seen[e.id] = true
+		o, ok := old[e.id]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeAdded, Section: e.section, ExerciseID: e.id})
+			continue
+		}
+		if o.section != e.section {
+			changes = append(changes, Change{Kind: ChangeChanged, Section: e.section, ExerciseID: e.id,
+				Field: "section", Old: o.section, New: e.section})
+		}
+		for _, f := range exerciseFieldNames {
+			if !reflect.DeepEqual(o.fields[f], e.fields[f]) {
+				changes = append(changes, Change{Kind: ChangeChanged, Section: e.section, ExerciseID: e.id,
+					Field: f, Old: o.fields[f], New: e.fields[f]})
+			}
+		}
+	}
+	for _, o := range listExercises(from) {
+		if !seen[o.id] {
+			changes = append(changes, Change{Kind: ChangeRemoved, Section: o.section, ExerciseID: o.id})
+		}
+	}
+	return changes
+}
+
+// exerciseFieldNames are the fields of exercises compared by Diff, in the
+// order their changes are listed.
+var exerciseFieldNames = []string{"type", "question", "choices", "answer", "sentence", "correctedSentence", "feedback"}
+
+type exerciseFields struct {
+	id      string
+	section int
+	fields  map[string]any
+}
+
+func listExercises(q Quiz) []exerciseFields {
+	list := make([]exerciseFields, 0)
+	for i, s := range q.Sections {
+		for _, e := range s.Exercises {
+			id, fields := fieldsOf(e)
+			list = append(list, exerciseFields{id: id, section: i, fields: fields})
+		}
+	}
+	return list
+}
+
+func indexExercises(q Quiz) map[string]exerciseFields {
+	index := make(map[string]exerciseFields)
+	for _, e := range listExercises(q) {
+		index[e.id] = e
+	}
+	return index
+}
+
+func fieldsOf(e exercise.Exercise) (string, map[string]any) {
+	var feedback any
+	if e.Feedback() != nil {
+		feedback = *e.Feedback()
+	}
+	switch e := e.(type) {
+	case *exercise.MultipleChoiceExercise:
+		return e.ID, map[string]any{"type": e.Type, "question": e.Question, "choices": e.Choices, "answer": e.Answer(), "feedback": feedback}
+	case *exercise.FillInTheBlankExercise:
+		return e.ID, map[string]any{"type": e.Type, "question": e.Question, "answer": e.Answer(), "feedback": feedback}
+	case *exercise.SentenceCorrectionExercise:
+		return e.ID, map[string]any{"type": e.Type, "sentence": e.Sentence, "correctedSentence": e.CorrectedSentence, "feedback": feedback}
+	default:
+		return "", map[string]any{}
+	}
+}
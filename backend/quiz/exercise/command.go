@@ -1,7 +1,6 @@
 package exercise
 
 import (
-	"errors"
 	"fmt"
 	myslices "languagequiz/utils/slices"
 	"regexp"
@@ -15,6 +14,17 @@ type CreateExerciseCommand interface {
 	Type() string
 }
 
+// FieldError is returned by the command constructors when a single field is
+// invalid. Field is the name of the field in the api, e.g. choices.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
 type CreateMultipleChoiceExerciseCommand struct {
 	Question string
 	Choices  []string
@@ -33,14 +43,14 @@ func NewCreateMultipleChoiceExerciseCommand(
 	feedback *string,
 ) (*CreateMultipleChoiceExerciseCommand, error) {
 	if len(choices) != 4 {
-		return nil, fmt.Errorf("expected 4 choices, found: %d", len(choices))
+		return nil, FieldError{Field: "choices", Message: fmt.Sprintf("expected 4 choices, found: %d", len(choices))}
 	}
 	duplicateChoice := myslices.FindDuplicate(choices)
 	if duplicateChoice != nil {
-		return nil, fmt.Errorf("duplicate choice found: %s", *duplicateChoice)
+		return nil, FieldError{Field: "choices", Message: fmt.Sprintf("duplicate choice found: %s", *duplicateChoice)}
 	}
 	if !slices.Contains(choices, answer) {
-		return nil, fmt.Errorf("answer is not a choice")
+		return nil, FieldError{Field: "answer", Message: "answer is not a choice"}
 	}
 
 	return &CreateMultipleChoiceExerciseCommand{
@@ -67,10 +77,10 @@ func NewCreateFillInTheBlankExerciseCommand(
 ) (*CreateFillInTheBlankExerciseCommand, error) {
 	blanks := blankRegex.FindAllStringSubmatch(question, -1)
 	if len(blanks) == 0 {
-		return nil, errors.New("no blank '______' found in question")
+		return nil, FieldError{Field: "question", Message: "no blank '______' found in question"}
 	}
 	if len(blanks) > 1 {
-		return nil, errors.New("more than one blank '______' found in question")
+		return nil, FieldError{Field: "question", Message: "more than one blank '______' found in question"}
 	}
 
 	return &CreateFillInTheBlankExerciseCommand{
@@ -95,7 +105,7 @@ func NewCreateSentenceCorrectionExerciseCommand(
 	feedback *string,
 ) (*CreateSentenceCorrectionExerciseCommand, error) {
 	if sentence == correctedSentence {
-		return nil, errors.New("sentence and correctedSentence are the same")
+		return nil, FieldError{Field: "correctedSentence", Message: "sentence and correctedSentence are the same"}
 	}
 
 	return &CreateSentenceCorrectionExerciseCommand{
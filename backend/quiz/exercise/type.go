@@ -5,3 +5,17 @@ const (
 	TypeFillInTheBlank     = "fillInTheBlank"
 	TypeSentenceCorrection = "sentenceCorrection"
 )
+
+// TypeOf returns the type of e, or "unknown" for an exercise of another type.
+func TypeOf(e Exercise) string {
+	switch e.(type) {
+	case *MultipleChoiceExercise:
+		return TypeMultipleChoice
+	case *FillInTheBlankExercise:
+		return TypeFillInTheBlank
+	case *SentenceCorrectionExercise:
+		return TypeSentenceCorrection
+	default:
+		return "unknown"
+	}
+}
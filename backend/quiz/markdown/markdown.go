@@ -0,0 +1,332 @@
+// Package markdown reads and writes quizzes in a plain text syntax based on
+// Markdown, for authoring long quizzes by hand:
+//
+//	# Dutch for beginners
+//	language: nl
+//
+//	## Articles
+//
+//	Which article goes with "huis"?
+//	- [ ] de
+//	- [x] het
+//	- [ ] een
+//	- [ ] des
+//	> Most diminutives and nouns ending in -um take "het".
+//
+//	## Verbs
+//
+//	Ik ______ naar school.
+//	- [x] ga
+//
+//	Hij gaan naar huis. ~> Hij gaat naar huis.
+//
+// A level one heading names the quiz and may be followed by a language line
+// with its BCP 47 tag. Every other heading starts a section. Exercises are
+// separated by blank lines and start with their question. A question with one
+// checked choice and a blank '______' is a fill in the blank exercise, any
+// other question with choices is a multiple choice exercise. A sentence and
+// its correction separated by '~>' make a sentence correction exercise.
+// Blockquotes after an exercise hold its feedback. A line starting with a
+// backslash is taken literally without it, and '\~>' is a literal '~>'.
+package markdown
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	blank                   = "______"
+	correctionMarker        = "~>"
+	escapedCorrectionMarker = `\` + correctionMarker
+)
+
+var (
+	headingRegex  = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	languageRegex = regexp.MustCompile(`(?i)^language:\s*(.*)$`)
+	choiceRegex   = regexp.MustCompile(`^[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	quoteRegex    = regexp.MustCompile(`^>\s?(.*)$`)
+)
+
+// Options hold the name and language of the quiz for documents that do not
+// state them.
+type Options struct {
+	Name        string
+	LanguageTag language.Tag
+}
+
+// Issue is an error found at a line of the input.
+type Issue struct {
+	Line    int
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+}
+
+// Errors collects every error found in a document.
+type Errors []Issue
+
+func (e Errors) Error() string {
+	messages := make([]string, 0)
+	for _, issue := range e {
+		messages = append(messages, issue.String())
+	}
+	return strings.Join(messages, "; ")
+}
+
+type section struct {
+	name      string
+	line      int
+	exercises int
+	commands  []exercise.CreateExerciseCommand
+}
+
+type choice struct {
+	text    string
+	correct bool
+}
+
+type block struct {
+	line     int
+	question []string
+	choices  []choice
+	feedback []string
+}
+
+type parser struct {
+	opts     Options
+	errs     Errors
+	sections []*section
+	current  *block
+	title    bool
+}
+
+// Parse reads a quiz document. It returns Errors when one or more lines are
+// invalid.
+func Parse(r io.Reader, opts Options) (*quiz.CreateQuizCommand, error) {
+	p := &parser{opts: opts, errs: make(Errors, 0)}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		p.parseLine(lineNumber, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	p.flush()
+
+	return p.build()
+}
+
+func (p *parser) parseLine(lineNumber int, line string) {
+	if line == "" {
+		p.flush()
+		return
+	}
+
+	if match := headingRegex.FindStringSubmatch(line); match != nil {
+		p.flush()
+		p.parseHeading(lineNumber, len(match[1]), match[2])
+		return
+	}
+
+	if match := quoteRegex.FindStringSubmatch(line); match != nil {
+		if p.current == nil {
+			p.errs = append(p.errs, Issue{Line: lineNumber, Message: "feedback without an exercise"})
+			return
+		}
+		p.current.feedback = append(p.current.feedback, match[1])
+		return
+	}
+
+	if match := choiceRegex.FindStringSubmatch(line); match != nil {
+		switch {
+		case p.current == nil:
+			p.errs = append(p.errs, Issue{Line: lineNumber, Message: "choice without a question"})
+		case len(p.current.feedback) > 0:
+			p.errs = append(p.errs, Issue{Line: lineNumber, Message: "choices must come before the feedback"})
+		default:
+			p.current.choices = append(p.current.choices, choice{text: match[2], correct: match[1] != " "})
+		}
+		return
+	}
+
+	if len(p.sections) == 0 {
+		if match := languageRegex.FindStringSubmatch(line); match != nil {
+			languageTag, err := language.Parse(match[1])
+			if err != nil {
+				p.errs = append(p.errs, Issue{Line: lineNumber, Message: fmt.Sprintf("invalid language: %s", err)})
+				return
+			}
+			p.opts.LanguageTag = languageTag
+			return
+		}
+		p.errs = append(p.errs, Issue{Line: lineNumber, Message: "exercise outside a section; add a '## ' heading above it"})
+		return
+	}
+
+	line = strings.TrimPrefix(line, `\`)
+	if p.current == nil {
+		p.current = &block{line: lineNumber}
+	}
+	if len(p.current.choices) > 0 || len(p.current.feedback) > 0 {
+		p.errs = append(p.errs, Issue{Line: lineNumber, Message: "unexpected text; separate exercises with a blank line"})
+		return
+	}
+	p.current.question = append(p.current.question, line)
+}
+
+// parseHeading names the quiz for the first level one heading, and starts a
+// section for any other heading.
+func (p *parser) parseHeading(lineNumber, level int, text string) {
+	if level == 1 && !p.title && len(p.sections) == 0 {
+		p.title = true
+		p.opts.Name = text
+		return
+	}
+	p.sections = append(p.sections, &section{name: text, line: lineNumber})
+}
+
+// flush turns the exercise being read, if any, into a command of the current
+// section.
+func (p *parser) flush() {
+	b := p.current
+	if b == nil {
+		return
+	}
+	p.current = nil
+
+	if len(b.question) == 0 {
+		return
+	}
+	s := p.sections[len(p.sections)-1]
+	s.exercises++
+	cmd, err := b.toCommand()
+	if err != nil {
+		p.errs = append(p.errs, Issue{Line: b.line, Message: err.Error()})
+		return
+	}
+	s.commands = append(s.commands, cmd)
+}
+
+func (p *parser) build() (*quiz.CreateQuizCommand, error) {
+	if p.opts.Name == "" {
+		p.errs = append(p.errs, Issue{Line: 1, Message: "quiz has no name; start the document with a '# ' heading"})
+	}
+	if p.opts.LanguageTag == language.Und {
+		p.errs = append(p.errs, Issue{Line: 1, Message: "quiz has no language; add a 'language:' line below the title"})
+	}
+
+	createSectionCommands := make([]quiz.CreateSectionCommand, 0)
+	for _, s := range p.sections {
+		if s.exercises == 0 {
+			p.errs = append(p.errs, Issue{Line: s.line, Message: fmt.Sprintf("section %q has no exercises", s.name)})
+			continue
+		}
+		createSectionCommand, err := quiz.NewCreateSectionCommand(s.name, s.commands)
+		if err != nil {
+			p.errs = append(p.errs, Issue{Line: s.line, Message: fmt.Sprintf("section %q: %s", s.name, err)})
+			continue
+		}
+		createSectionCommands = append(createSectionCommands, *createSectionCommand)
+	}
+	if len(p.errs) > 0 {
+		sort.SliceStable(p.errs, func(i, j int) bool { return p.errs[i].Line < p.errs[j].Line })
+		return nil, p.errs
+	}
+	if len(createSectionCommands) == 0 {
+		return nil, errors.New("document has no sections")
+	}
+
+	cmd := quiz.NewCreateQuizCommand(p.opts.Name, p.opts.LanguageTag, createSectionCommands)
+	return &cmd, nil
+}
+
+func (b *block) toCommand() (exercise.CreateExerciseCommand, error) {
+	question := strings.Join(b.question, " ")
+
+	var feedback *string
+	if text := strings.TrimSpace(strings.Join(b.feedback, "\n")); text != "" {
+		feedback = &text
+	}
+
+	if sentence, correctedSentence, ok := cutCorrection(question); ok {
+		if len(b.choices) > 0 {
+			return nil, errors.New("exercise has both a correction and choices")
+		}
+		cmd, err := exercise.NewCreateSentenceCorrectionExerciseCommand(strings.TrimSpace(sentence), strings.TrimSpace(correctedSentence), feedback)
+		if err != nil {
+			return nil, err
+		}
+		return cmd, nil
+	}
+
+	if len(b.choices) == 0 {
+		return nil, fmt.Errorf("exercise has no answer; add choices with '- [ ]' and '- [x]', or a correction after '%s'", correctionMarker)
+	}
+	question = unescapeCorrectionMarkers(question)
+
+	answers := make([]string, 0)
+	choices := make([]string, 0)
+	for _, c := range b.choices {
+		if c.correct {
+			answers = append(answers, c.text)
+		}
+		choices = append(choices, c.text)
+	}
+	if len(answers) != 1 {
+		return nil, fmt.Errorf("exactly one choice must be checked with '[x]', found %d", len(answers))
+	}
+
+	if len(choices) == 1 && strings.Contains(question, blank) {
+		cmd, err := exercise.NewCreateFillInTheBlankExerciseCommand(question, answers[0], feedback)
+		if err != nil {
+			return nil, err
+		}
+		return cmd, nil
+	}
+
+	cmd, err := exercise.NewCreateMultipleChoiceExerciseCommand(question, choices, answers[0], feedback)
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// cutCorrection splits a question at its first correction marker that is not
+// escaped.
+func cutCorrection(question string) (sentence, correctedSentence string, ok bool) {
+	for i := 0; i+len(correctionMarker) <= len(question); i++ {
+		if strings.HasPrefix(question[i:], escapedCorrectionMarker) {
+			i += len(escapedCorrectionMarker) - 1
+			continue
+		}
+		if strings.HasPrefix(question[i:], correctionMarker) {
+			sentence = unescapeCorrectionMarkers(question[:i])
+			correctedSentence = unescapeCorrectionMarkers(question[i+len(correctionMarker):])
+			return sentence, correctedSentence, true
+		}
+	}
+	return "", "", false
+}
+
+func unescapeCorrectionMarkers(s string) string {
+	return strings.ReplaceAll(s, escapedCorrectionMarker, correctionMarker)
+}
@@ -0,0 +1,149 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+func TestParse(t *testing.T) {
+	feedback := "Most diminutives take \"het\".\nSo does huis."
+	doc := "\ufeff# Dutch for beginners\n" +
+		"language: nl\n" +
+		"\n" +
+		"## Articles\n" +
+		"\n" +
+		"Which article goes\n" +
+		"with \"huis\"?\n" +
+		"* [ ] de\n" +
+		"- [X] het\n" +
+		"+ [ ] een\n" +
+		"- [ ] des\n" +
+		"> Most diminutives take \"het\".\n" +
+		">So does huis.\n" +
+		"\n" +
+		"## Verbs ##\n" +
+		"\n" +
+		"Ik ______ naar school.\n" +
+		"- [x] ga\n" +
+		"\n" +
+		"## Sentences\n" +
+		"Hij gaan naar huis. ~> Hij gaat naar huis.\n" +
+		"\n" +
+		`Pijl \~> rechts ~> Pijl \~> links` + "\n"
+
+	cmd, err := Parse(strings.NewReader(doc), Options{Name: "Ignored", LanguageTag: language.German})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := quiz.NewCreateQuizCommand("Dutch for beginners", language.Dutch, []quiz.CreateSectionCommand{
+		{Name: "Articles", Exercises: []exercise.CreateExerciseCommand{
+			&exercise.CreateMultipleChoiceExerciseCommand{Question: "Which article goes with \"huis\"?", Choices: []string{"de", "het", "een", "des"}, Answer: "het", Feedback: &feedback},
+		}},
+		{Name: "Verbs", Exercises: []exercise.CreateExerciseCommand{
+			&exercise.CreateFillInTheBlankExerciseCommand{Question: "Ik ______ naar school.", Answer: "ga"},
+		}},
+		{Name: "Sentences", Exercises: []exercise.CreateExerciseCommand{
+			&exercise.CreateSentenceCorrectionExerciseCommand{Sentence: "Hij gaan naar huis.", CorrectedSentence: "Hij gaat naar huis."},
+			&exercise.CreateSentenceCorrectionExerciseCommand{Sentence: "Pijl ~> rechts", CorrectedSentence: "Pijl ~> links"},
+		}},
+	})
+	if !reflect.DeepEqual(*cmd, want) {
+		t.Errorf("command = %+v, want %+v", *cmd, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+		// wantLines are the lines of the Errors, if any.
+		wantLines []int
+	}{
+		{
+			name:    "no sections",
+			doc:     "# Dutch\nlanguage: nl\n",
+			wantErr: "document has no sections",
+		},
+		{
+			name:      "no name or language",
+			doc:       "## Verbs\n\nIk ______ naar school.\n- [x] ga\n",
+			wantErr:   "line 1: quiz has no name; start the document with a '# ' heading; line 1: quiz has no language; add a 'language:' line below the title",
+			wantLines: []int{1, 1},
+		},
+		{
+			name: "every invalid line",
+			doc: "# Dutch\n" +
+				"language: xx-!!\n" +
+				"Ik ______ naar school.\n" +
+				"\n" +
+				"## Verbs\n" +
+				"- [x] ga\n" +
+				"> Feedback\n" +
+				"\n" +
+				"Ik ______ naar school.\n" +
+				"> Feedback\n" +
+				"- [x] ga\n" +
+				"\n" +
+				"Hij gaan. ~> Hij gaat.\n" +
+				"- [x] gaat\n" +
+				"\n" +
+				"Which one?\n" +
+				"- [x] de\n" +
+				"- [x] het\n" +
+				"More text\n" +
+				"\n" +
+				"## Empty\n",
+			wantErr: "line 1: quiz has no language; add a 'language:' line below the title; " +
+				"line 2: invalid language: language: tag is not well-formed; " +
+				"line 3: exercise outside a section; add a '## ' heading above it; " +
+				"line 6: choice without a question; " +
+				"line 7: feedback without an exercise; " +
+				"line 9: exercise has no answer; add choices with '- [ ]' and '- [x]', or a correction after '~>'; " +
+				"line 11: choices must come before the feedback; " +
+				"line 13: exercise has both a correction and choices; " +
+				"line 16: exactly one choice must be checked with '[x]', found 2; " +
+				"line 19: unexpected text; separate exercises with a blank line; " +
+				`line 21: section "Empty" has no exercises`,
+			wantLines: []int{1, 2, 3, 6, 7, 9, 11, 13, 16, 19, 21},
+		},
+		{
+			name: "section with more than one exercise type",
+			doc: "# Dutch\nlanguage: nl\n\n## Mixed\n\n" +
+				"Ik ______ naar school.\n- [x] ga\n\n" +
+				"Hij gaan. ~> Hij gaat.\n",
+			wantErr:   `line 4: section "Mixed": section cannot have more than one exercise type: [fillInTheBlank sentenceCorrection]`,
+			wantLines: []int{4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.doc), Options{})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("error = %v, want %s", err, tt.wantErr)
+			}
+
+			issues, ok := err.(Errors)
+			if tt.wantLines == nil {
+				if ok {
+					t.Errorf("error is %T, want an error about the whole document", err)
+				}
+				return
+			}
+			lines := make([]int, 0)
+			for _, issue := range issues {
+				lines = append(lines, issue.Line)
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("lines = %v, want %v", lines, tt.wantLines)
+			}
+		})
+	}
+}
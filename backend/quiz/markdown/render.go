@@ -0,0 +1,76 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+)
+
+// Render writes a quiz as a document that Parse reads back into the same quiz.
+func Render(w io.Writer, q quiz.Quiz) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", singleLine(q.Name))
+	fmt.Fprintf(&b, "language: %s\n", q.LanguageTag)
+
+	for _, s := range q.Sections {
+		fmt.Fprintf(&b, "\n## %s\n", singleLine(s.Name))
+		for _, e := range s.Exercises {
+			b.WriteString("\n")
+			if err := renderExercise(&b, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func renderExercise(b *strings.Builder, e exercise.Exercise) error {
+	switch e := e.(type) {
+	case *exercise.MultipleChoiceExercise:
+		b.WriteString(escapeLine(e.Question) + "\n")
+		for _, c := range e.Choices {
+			mark := " "
+			if c == e.Answer() {
+				mark = "x"
+			}
+			fmt.Fprintf(b, "- [%s] %s\n", mark, singleLine(c))
+		}
+	case *exercise.FillInTheBlankExercise:
+		b.WriteString(escapeLine(e.Question) + "\n")
+		fmt.Fprintf(b, "- [x] %s\n", singleLine(fmt.Sprint(e.Answer())))
+	case *exercise.SentenceCorrectionExercise:
+		fmt.Fprintf(b, "%s %s %s\n", escapeLine(e.Sentence), correctionMarker, escapeCorrectionMarkers(singleLine(e.CorrectedSentence)))
+	default:
+		return fmt.Errorf("unsupported exercise: %T", e)
+	}
+
+	if feedback := e.Feedback(); feedback != nil {
+		for _, line := range strings.Split(*feedback, "\n") {
+			b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+		}
+	}
+	return nil
+}
+
+// escapeLine keeps text from being read as a heading, choice, feedback or
+// sentence correction.
+func escapeLine(s string) string {
+	s = escapeCorrectionMarkers(singleLine(s))
+	if strings.HasPrefix(s, `\`) || strings.HasPrefix(s, ">") || headingRegex.MatchString(s) || choiceRegex.MatchString(s) {
+		return `\` + s
+	}
+	return s
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func escapeCorrectionMarkers(s string) string {
+	return strings.ReplaceAll(s, correctionMarker, escapedCorrectionMarker)
+}
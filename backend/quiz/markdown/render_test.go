@@ -0,0 +1,100 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+func TestRenderRoundTrip(t *testing.T) {
+	feedback := "Huis takes \"het\".\n> Not a nested quote."
+	arrow := "An arrow ~> points right."
+
+	tests := []struct {
+		name     string
+		exercise exercise.Exercise
+		want     exercise.CreateExerciseCommand
+	}{
+		{
+			name:     "multiple choice with feedback",
+			exercise: newMultipleChoice("Which article goes with \"huis\"?", []string{"de", "het", "een", "des"}, "het", &feedback),
+			want:     &exercise.CreateMultipleChoiceExerciseCommand{Question: "Which article goes with \"huis\"?", Choices: []string{"de", "het", "een", "des"}, Answer: "het", Feedback: &feedback},
+		},
+		{
+			name:     "question with a correction marker",
+			exercise: newMultipleChoice("Is ~> an arrow?", []string{"yes", "no", "maybe", "~>"}, "yes", nil),
+			want:     &exercise.CreateMultipleChoiceExerciseCommand{Question: "Is ~> an arrow?", Choices: []string{"yes", "no", "maybe", "~>"}, Answer: "yes"},
+		},
+		{
+			name:     "question starting with a correction marker",
+			exercise: newMultipleChoice("~> is an arrow?", []string{"yes", "no", "maybe", "never"}, "yes", nil),
+			want:     &exercise.CreateMultipleChoiceExerciseCommand{Question: "~> is an arrow?", Choices: []string{"yes", "no", "maybe", "never"}, Answer: "yes"},
+		},
+		{
+			name:     "questions that look like markup",
+			exercise: newMultipleChoice("# Not a heading", []string{"- [x] a", "> b", `\c`, "## d"}, "> b", nil),
+			want:     &exercise.CreateMultipleChoiceExerciseCommand{Question: "# Not a heading", Choices: []string{"- [x] a", "> b", `\c`, "## d"}, Answer: "> b"},
+		},
+		{
+			name:     "fill in the blank",
+			exercise: newFillInTheBlank("- [ ] Ik ______ naar school.", "ga"),
+			want:     &exercise.CreateFillInTheBlankExerciseCommand{Question: "- [ ] Ik ______ naar school.", Answer: "ga"},
+		},
+		{
+			name:     "sentence correction with markers on both sides",
+			exercise: newSentenceCorrection(arrow+" Hij gaan.", arrow+" Hij gaat."),
+			want:     &exercise.CreateSentenceCorrectionExerciseCommand{Sentence: arrow + " Hij gaan.", CorrectedSentence: arrow + " Hij gaat."},
+		},
+		{
+			name:     "sentence correction starting with a backslash",
+			exercise: newSentenceCorrection(`\ Hij gaan.`, `\ Hij gaat.`),
+			want:     &exercise.CreateSentenceCorrectionExerciseCommand{Sentence: `\ Hij gaan.`, CorrectedSentence: `\ Hij gaat.`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := quiz.Quiz{
+				Name:        "Dutch for beginners",
+				LanguageTag: language.Dutch,
+				Sections:    []quiz.Section{quiz.NewSection("Basics", []exercise.Exercise{tt.exercise})},
+			}
+			var b strings.Builder
+			if err := Render(&b, q); err != nil {
+				t.Fatalf("failed to render quiz: %v", err)
+			}
+
+			cmd, err := Parse(strings.NewReader(b.String()), Options{})
+			if err != nil {
+				t.Fatalf("failed to parse rendered quiz: %v\n%s", err, b.String())
+			}
+			want := quiz.NewCreateQuizCommand("Dutch for beginners", language.Dutch, []quiz.CreateSectionCommand{
+				{Name: "Basics", Exercises: []exercise.CreateExerciseCommand{tt.want}},
+			})
+			if !reflect.DeepEqual(*cmd, want) {
+				t.Errorf("command = %+v, want %+v\n%s", *cmd, want, b.String())
+			}
+		})
+	}
+}
+
+func newMultipleChoice(question string, choices []string, answer string, feedback *string) exercise.Exercise {
+	e := exercise.NewMultipleChoiceExercise("1", time.Time{}, time.Time{}, feedback, question, choices, answer)
+	return &e
+}
+
+func newFillInTheBlank(question, answer string) exercise.Exercise {
+	e := exercise.NewFillInTheBlankExercise("1", time.Time{}, time.Time{}, nil, question, answer)
+	return &e
+}
+
+func newSentenceCorrection(sentence, correctedSentence string) exercise.Exercise {
+	e := exercise.NewSentenceCorrectionExercise("1", time.Time{}, time.Time{}, nil, sentence, correctedSentence)
+	return &e
+}
@@ -0,0 +1,136 @@
+package moodle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+)
+
+var (
+	aikenChoiceRegex = regexp.MustCompile(`^([A-Z])[.)]\s+(.*)$`)
+	aikenAnswerRegex = regexp.MustCompile(`^ANSWER:\s*([A-Z])\s*$`)
+)
+
+type aikenQuestion struct {
+	line     int
+	question []string
+	letters  []string
+	choices  []string
+}
+
+// ParseAiken reads an Aiken file. Every question becomes a multiple choice
+// exercise in the default section, since Aiken has no categories. It returns
+// Errors when one or more questions cannot be imported.
+func ParseAiken(r io.Reader, opts Options) (*quiz.CreateQuizCommand, error) {
+	errs := make(Errors, 0)
+	sections := newSectionBuilder()
+
+	var current *aikenQuestion
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" {
+			continue
+		}
+
+		if current == nil {
+			current = &aikenQuestion{line: lineNumber}
+		}
+
+		if match := aikenAnswerRegex.FindStringSubmatch(line); match != nil {
+			cmd, err := current.toCommand(match[1])
+			if err != nil {
+				errs = append(errs, Issue{Line: current.line, Message: err.Error()})
+			} else {
+				sections.add(opts.DefaultSection, current.line, cmd)
+			}
+			current = nil
+			continue
+		}
+
+		if match := aikenChoiceRegex.FindStringSubmatch(line); match != nil && len(current.question) > 0 {
+			current.letters = append(current.letters, match[1])
+			current.choices = append(current.choices, match[2])
+			continue
+		}
+
+		if len(current.choices) > 0 {
+			errs = append(errs, Issue{Line: lineNumber, Message: "expected a choice or ANSWER line"})
+			continue
+		}
+		current.question = append(current.question, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if current != nil {
+		errs = append(errs, Issue{Line: current.line, Message: "question has no ANSWER line"})
+	}
+
+	return sections.build(opts, errs)
+}
+
+func (q *aikenQuestion) toCommand(answerLetter string) (exercise.CreateExerciseCommand, error) {
+	if len(q.question) == 0 {
+		return nil, fmt.Errorf("ANSWER line without a question")
+	}
+
+	answer := ""
+	for i, letter := range q.letters {
+		if letter == answerLetter {
+			answer = q.choices[i]
+		}
+	}
+	if answer == "" {
+		return nil, fmt.Errorf("answer %s is not one of the choices", answerLetter)
+	}
+
+	cmd, err := exercise.NewCreateMultipleChoiceExerciseCommand(strings.Join(q.question, " "), q.choices, answer, nil)
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// WriteAiken writes the multiple choice exercises of a quiz in Aiken format.
+// Aiken only knows multiple choice questions without feedback, so it fails
+// when the quiz has other exercise types.
+func WriteAiken(w io.Writer, q quiz.Quiz) error {
+	unsupported := make([]string, 0)
+	var b strings.Builder
+	for i, s := range q.Sections {
+		for j, e := range s.Exercises {
+			mc, ok := e.(*exercise.MultipleChoiceExercise)
+			if !ok {
+				unsupported = append(unsupported, fmt.Sprintf("section %d exercise %d", i+1, j+1))
+				continue
+			}
+
+			b.WriteString(strings.ReplaceAll(mc.Question, "\n", " ") + "\n")
+			answerLetter := ""
+			for k, choice := range mc.Choices {
+				letter := string(rune('A' + k))
+				if choice == mc.Answer() {
+					answerLetter = letter
+				}
+				fmt.Fprintf(&b, "%s. %s\n", letter, strings.ReplaceAll(choice, "\n", " "))
+			}
+			fmt.Fprintf(&b, "ANSWER: %s\n\n", answerLetter)
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%w: aiken only supports multiple choice, found other types at %s", ErrUnsupportedExercise, strings.Join(unsupported, ", "))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
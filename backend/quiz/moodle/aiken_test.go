@@ -0,0 +1,151 @@
+package moodle
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+func TestParseAiken(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []exercise.CreateExerciseCommand
+	}{
+		{
+			name: "questions in the default section",
+			content: "\ufeffWhat is red?\n" +
+				"A. rot\n" +
+				"B. blau\n" +
+				"C. grün\n" +
+				"D. gelb\n" +
+				"ANSWER: A\n" +
+				"\n" +
+				"What is blue?\n" +
+				"A) rot\n" +
+				"B) blau\n" +
+				"C) grün\n" +
+				"D) gelb\n" +
+				"ANSWER:D\n",
+			want: []exercise.CreateExerciseCommand{
+				&exercise.CreateMultipleChoiceExerciseCommand{Question: "What is red?", Choices: []string{"rot", "blau", "grün", "gelb"}, Answer: "rot"},
+				&exercise.CreateMultipleChoiceExerciseCommand{Question: "What is blue?", Choices: []string{"rot", "blau", "grün", "gelb"}, Answer: "gelb"},
+			},
+		},
+		{
+			name: "question over several lines without blank lines between questions",
+			content: "Which colour\n" +
+				"is the sky?\n" +
+				"A. rot\n" +
+				"B. blau\n" +
+				"C. grün\n" +
+				"D. gelb\n" +
+				"ANSWER: B\n" +
+				"A. Is this a question?\n" +
+				"A. yes\n" +
+				"B. no\n" +
+				"C. maybe\n" +
+				"D. never\n" +
+				"ANSWER: C\n",
+			want: []exercise.CreateExerciseCommand{
+				&exercise.CreateMultipleChoiceExerciseCommand{Question: "Which colour is the sky?", Choices: []string{"rot", "blau", "grün", "gelb"}, Answer: "blau"},
+				&exercise.CreateMultipleChoiceExerciseCommand{Question: "A. Is this a question?", Choices: []string{"yes", "no", "maybe", "never"}, Answer: "maybe"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := ParseAiken(strings.NewReader(tt.content), NewOptions("Colours", language.German))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := quiz.NewCreateQuizCommand("Colours", language.German, []quiz.CreateSectionCommand{
+				{Name: "Colours", Exercises: tt.want},
+			})
+			if !reflect.DeepEqual(*cmd, want) {
+				t.Errorf("command = %+v, want %+v", *cmd, want)
+			}
+		})
+	}
+}
+
+func TestParseAikenErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "empty file",
+			content: "\n\n",
+			wantErr: "file has no questions",
+		},
+		{
+			name: "every invalid question",
+			content: "ANSWER: A\n" +
+				"What is red?\n" +
+				"A. rot\n" +
+				"B. blau\n" +
+				"ANSWER: C\n" +
+				"What is blue?\n" +
+				"A. rot\n" +
+				"and more\n" +
+				"B. blau\n" +
+				"C. grün\n" +
+				"D. gelb\n" +
+				"ANSWER: B\n" +
+				"What is green?\n" +
+				"ANSWER: a\n",
+			wantErr: "line 1: ANSWER line without a question; " +
+				"line 2: answer C is not one of the choices; " +
+				"line 8: expected a choice or ANSWER line; " +
+				"line 13: question has no ANSWER line",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAiken(strings.NewReader(tt.content), NewOptions("Colours", language.German))
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteAiken(t *testing.T) {
+	feedback := "Rot is red."
+	red := exercise.NewMultipleChoiceExercise("1", time.Time{}, time.Time{}, &feedback, "What is\nred?", []string{"rot", "blau", "grün", "gelb"}, "rot")
+	blue := exercise.NewMultipleChoiceExercise("2", time.Time{}, time.Time{}, nil, "What is blue?", []string{"rot", "grün", "gelb", "blau\nhell"}, "blau\nhell")
+	q := quiz.Quiz{
+		Name:        "Colours",
+		LanguageTag: language.German,
+		Sections:    []quiz.Section{quiz.NewSection("Colours", []exercise.Exercise{&red, &blue})},
+	}
+
+	var b strings.Builder
+	if err := WriteAiken(&b, q); err != nil {
+		t.Fatalf("failed to write aiken: %v", err)
+	}
+	want := "What is red?\nA. rot\nB. blau\nC. grün\nD. gelb\nANSWER: A\n\n" +
+		"What is blue?\nA. rot\nB. grün\nC. gelb\nD. blau hell\nANSWER: D\n\n"
+	if b.String() != want {
+		t.Errorf("aiken = %q, want %q", b.String(), want)
+	}
+
+	fillInTheBlank := exercise.NewFillInTheBlankExercise("3", time.Time{}, time.Time{}, nil, "Der Hund ______.", "bellt")
+	q.Sections = append(q.Sections, quiz.NewSection("Verbs", []exercise.Exercise{&fillInTheBlank}))
+	err := WriteAiken(&b, q)
+	wantErr := "unsupported exercise: aiken only supports multiple choice, found other types at section 2 exercise 1"
+	if !errors.Is(err, ErrUnsupportedExercise) || err.Error() != wantErr {
+		t.Errorf("error = %v, want %s", err, wantErr)
+	}
+}
@@ -0,0 +1,377 @@
+package moodle
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+)
+
+var (
+	giftFormatRegex    = regexp.MustCompile(`^\[(html|moodle|plain|markdown)\]`)
+	giftTrueFalseRegex = regexp.MustCompile(`^(?i)(T|F|TRUE|FALSE)\s*(#|$)`)
+	giftEscaper        = strings.NewReplacer(`\`, `\\`, `~`, `\~`, `=`, `\=`, `#`, `\#`, `{`, `\{`, `}`, `\}`, `:`, `\:`, "\n", `\n`)
+	giftUnescaper      = strings.NewReplacer(`\\`, `\`, `\~`, `~`, `\=`, `=`, `\#`, `#`, `\{`, `{`, `\}`, `}`, `\:`, `:`, `\n`, "\n")
+)
+
+// giftCategoryPrefixes are the question bank contexts Moodle puts in front of
+// category paths.
+var giftCategoryPrefixes = []string{"$course$/", "$system$/", "$module$/", "$cat1$/", "top/"}
+
+type giftQuestion struct {
+	line     int
+	category string
+	text     string
+}
+
+type giftAnswer struct {
+	correct  bool
+	text     string
+	feedback string
+}
+
+// ParseGIFT reads a GIFT file. Besides the command it returns warnings about
+// content that was dropped, such as feedback on wrong answers. It returns
+// Errors when one or more questions cannot be imported.
+func ParseGIFT(r io.Reader, opts Options) (*quiz.CreateQuizCommand, []Issue, error) {
+	questions, err := splitGIFTQuestions(r, opts.DefaultSection)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	errs := make(Errors, 0)
+	warnings := make([]Issue, 0)
+	sections := newSectionBuilder()
+	for _, q := range questions {
+		cmd, questionWarnings, err := parseGIFTQuestion(q.text)
+		for _, warning := range questionWarnings {
+			warnings = append(warnings, Issue{Line: q.line, Message: warning})
+		}
+		if err != nil {
+			errs = append(errs, Issue{Line: q.line, Message: err.Error()})
+			continue
+		}
+		sections.add(q.category, q.line, cmd)
+	}
+
+	cmd, err := sections.build(opts, errs)
+	return cmd, warnings, err
+}
+
+// splitGIFTQuestions groups the lines of a file into questions, which are
+// separated by blank lines, and tracks the current category.
+func splitGIFTQuestions(r io.Reader, defaultCategory string) ([]giftQuestion, error) {
+	questions := make([]giftQuestion, 0)
+	category := defaultCategory
+
+	var current *giftQuestion
+	flush := func() {
+		if current != nil {
+			questions = append(questions, *current)
+			current = nil
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "//"):
+			continue
+		case strings.HasPrefix(trimmed, "$CATEGORY:"):
+			flush()
+			category = giftCategoryName(strings.TrimPrefix(trimmed, "$CATEGORY:"))
+		case trimmed == "":
+			flush()
+		case current == nil:
+			current = &giftQuestion{line: lineNumber, category: category, text: line}
+		default:
+			current.text += "\n" + line
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	flush()
+	return questions, nil
+}
+
+func giftCategoryName(path string) string {
+	path = strings.TrimSpace(path)
+	for _, prefix := range giftCategoryPrefixes {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	return path
+}
+
+func parseGIFTQuestion(text string) (exercise.CreateExerciseCommand, []string, error) {
+	text = strings.TrimSpace(text)
+	warnings := make([]string, 0)
+
+	if strings.HasPrefix(text, "::") {
+		end := indexUnescaped(text[2:], "::")
+		if end < 0 {
+			return nil, nil, errors.New("question title is not closed with '::'")
+		}
+		text = strings.TrimSpace(text[2+end+2:])
+	}
+	text = giftFormatRegex.ReplaceAllString(text, "")
+
+	open := indexUnescaped(text, "{")
+	if open < 0 {
+		return nil, nil, errors.New("description items without an answer block are not supported")
+	}
+	closing := indexUnescaped(text[open:], "}")
+	if closing < 0 {
+		return nil, nil, errors.New("answer block is not closed with '}'")
+	}
+	closing += open
+
+	before := unescapeGIFT(text[:open])
+	after := unescapeGIFT(text[closing+1:])
+	block := strings.TrimSpace(text[open+1 : closing])
+	if indexUnescaped(text[closing+1:], "{") >= 0 {
+		return nil, nil, errors.New("questions with more than one answer block are not supported")
+	}
+
+	switch {
+	case block == "":
+		return nil, nil, errors.New("essay questions are not supported")
+	case strings.HasPrefix(block, "#"):
+		return nil, nil, errors.New("numerical questions are not supported")
+	case giftTrueFalseRegex.MatchString(block):
+		return nil, nil, errors.New("true/false questions are not supported")
+	}
+
+	generalFeedback := ""
+	if i := indexUnescaped(block, "####"); i >= 0 {
+		generalFeedback = strings.TrimSpace(unescapeGIFT(block[i+4:]))
+		block = block[:i]
+	}
+
+	answers, err := parseGIFTAnswers(block)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var correct *giftAnswer
+	hasWrongAnswers := false
+	for i := range answers {
+		answer := &answers[i]
+		if !answer.correct {
+			hasWrongAnswers = true
+			if answer.feedback != "" {
+				warnings = append(warnings, fmt.Sprintf("feedback on wrong answer %q was dropped", answer.text))
+			}
+			continue
+		}
+		if correct != nil {
+			if hasWrongAnswersIn(answers) {
+				return nil, nil, errors.New("multiple choice questions with more than one correct answer are not supported")
+			}
+			warnings = append(warnings, fmt.Sprintf("alternative answer %q was dropped", answer.text))
+			continue
+		}
+		correct = answer
+	}
+	if correct == nil {
+		return nil, nil, errors.New("question has no correct answer")
+	}
+
+	var feedback *string
+	switch {
+	case generalFeedback != "":
+		feedback = &generalFeedback
+		if correct.feedback != "" {
+			warnings = append(warnings, fmt.Sprintf("feedback on answer %q was dropped in favour of the general feedback", correct.text))
+		}
+	case correct.feedback != "":
+		feedback = &correct.feedback
+	}
+
+	var question string
+	if strings.TrimSpace(after) != "" {
+		question = strings.TrimSpace(before + blank + after)
+	} else if hasWrongAnswers {
+		question = strings.TrimSpace(before)
+	} else {
+		question = strings.TrimSpace(before) + " " + blank
+	}
+
+	if hasWrongAnswers {
+		choices := make([]string, 0)
+		for _, answer := range answers {
+			choices = append(choices, answer.text)
+		}
+		cmd, err := exercise.NewCreateMultipleChoiceExerciseCommand(question, choices, correct.text, feedback)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cmd, warnings, nil
+	}
+
+	cmd, err := exercise.NewCreateFillInTheBlankExerciseCommand(question, correct.text, feedback)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cmd, warnings, nil
+}
+
+func hasWrongAnswersIn(answers []giftAnswer) bool {
+	for _, answer := range answers {
+		if !answer.correct {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGIFTAnswers splits an answer block like "=right#well done ~wrong"
+// into its answers.
+func parseGIFTAnswers(block string) ([]giftAnswer, error) {
+	answers := make([]giftAnswer, 0)
+	start := -1
+	for i := 0; i <= len(block); i++ {
+		if i < len(block) && block[i] == '\\' {
+			i++
+			continue
+		}
+		if i < len(block) && block[i] != '=' && block[i] != '~' {
+			continue
+		}
+
+		if start < 0 {
+			if strings.TrimSpace(block[:i]) != "" {
+				return nil, fmt.Errorf("unexpected text before first answer: %q", strings.TrimSpace(block[:i]))
+			}
+		} else {
+			answer, err := parseGIFTAnswer(block[start], block[start+1:i])
+			if err != nil {
+				return nil, err
+			}
+			answers = append(answers, answer)
+		}
+		start = i
+	}
+	if len(answers) == 0 {
+		return nil, errors.New("answer block has no answers")
+	}
+	return answers, nil
+}
+
+func parseGIFTAnswer(marker byte, raw string) (giftAnswer, error) {
+	raw = strings.TrimSpace(raw)
+	if indexUnescaped(raw, "->") >= 0 {
+		return giftAnswer{}, errors.New("matching questions are not supported")
+	}
+	if strings.HasPrefix(raw, "%") {
+		return giftAnswer{}, errors.New("answer weights are not supported")
+	}
+
+	text, feedback := raw, ""
+	if i := indexUnescaped(raw, "#"); i >= 0 {
+		text, feedback = raw[:i], raw[i+1:]
+	}
+
+	text = strings.TrimSpace(unescapeGIFT(text))
+	if text == "" {
+		return giftAnswer{}, errors.New("answer is empty")
+	}
+	return giftAnswer{
+		correct:  marker == '=',
+		text:     text,
+		feedback: strings.TrimSpace(unescapeGIFT(feedback)),
+	}, nil
+}
+
+// indexUnescaped returns the index of the first occurrence of substr in s that
+// is not preceded by a backslash, or -1.
+func indexUnescaped(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeGIFT(s string) string {
+	return giftUnescaper.Replace(s)
+}
+
+func escapeGIFT(s string) string {
+	return giftEscaper.Replace(s)
+}
+
+// WriteGIFT writes a quiz as GIFT, one category per section. Sentence
+// correction exercises have no GIFT counterpart and are written as short
+// answer questions asking for the corrected sentence.
+func WriteGIFT(w io.Writer, q quiz.Quiz) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s (%s)\n", strings.ReplaceAll(q.Name, "\n", " "), q.LanguageTag)
+
+	for _, s := range q.Sections {
+		fmt.Fprintf(&b, "\n$CATEGORY: %s\n", s.Name)
+		for _, e := range s.Exercises {
+			question, err := formatGIFTQuestion(e)
+			if err != nil {
+				return err
+			}
+			b.WriteString("\n" + question + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatGIFTQuestion(e exercise.Exercise) (string, error) {
+	switch e := e.(type) {
+	case *exercise.MultipleChoiceExercise:
+		answers := make([]string, 0)
+		for _, choice := range e.Choices {
+			marker := "~"
+			if choice == e.Answer() {
+				marker = "="
+			}
+			answers = append(answers, marker+escapeGIFT(choice))
+		}
+		return formatGIFTBlock(e.Question, strings.Join(answers, " "), e.Feedback()), nil
+	case *exercise.FillInTheBlankExercise:
+		return formatGIFTBlock(e.Question, "="+escapeGIFT(e.Answer().(string)), e.Feedback()), nil
+	case *exercise.SentenceCorrectionExercise:
+		return formatGIFTBlock(e.Sentence, "="+escapeGIFT(e.CorrectedSentence), e.Feedback()), nil
+	default:
+		return "", fmt.Errorf("unknown exercise type: %T", e)
+	}
+}
+
+// formatGIFTBlock puts the answer block in place of the blank in the question,
+// or after the question when there is none.
+func formatGIFTBlock(question, answers string, feedback *string) string {
+	if feedback != nil && *feedback != "" {
+		answers += " ####" + escapeGIFT(*feedback)
+	}
+	block := "{" + answers + "}"
+
+	before, after, found := strings.Cut(question, blank)
+	if !found {
+		return escapeGIFT(question) + " " + block
+	}
+	return strings.TrimSpace(escapeGIFT(before) + block + escapeGIFT(after))
+}
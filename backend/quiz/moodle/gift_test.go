@@ -0,0 +1,198 @@
+package moodle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+func TestParseGIFT(t *testing.T) {
+	right := "Right!"
+	general := "Rot is red."
+
+	tests := []struct {
+		name         string
+		content      string
+		want         []quiz.CreateSectionCommand
+		wantWarnings []string
+	}{
+		{
+			name: "multiple choice in a category",
+			content: "// Colours\n" +
+				"$CATEGORY: $course$/top/Colours\n" +
+				"\n" +
+				"::Q1:: [html]What is red? {=rot#Right! ~blau ~grün ~gelb}\n",
+			want: []quiz.CreateSectionCommand{
+				{Name: "Colours", Exercises: []exercise.CreateExerciseCommand{
+					&exercise.CreateMultipleChoiceExerciseCommand{Question: "What is red?", Choices: []string{"rot", "blau", "grün", "gelb"}, Answer: "rot", Feedback: &right},
+				}},
+			},
+		},
+		{
+			name: "short answers with the blank where the block was",
+			content: "Der Hund {=bellt} laut.\n" +
+				"\n" +
+				"Die Katze\n" +
+				"{=miaut =schnurrt}\n",
+			want: []quiz.CreateSectionCommand{
+				{Name: "Animals", Exercises: []exercise.CreateExerciseCommand{
+					&exercise.CreateFillInTheBlankExerciseCommand{Question: "Der Hund ______ laut.", Answer: "bellt"},
+					&exercise.CreateFillInTheBlankExerciseCommand{Question: "Die Katze ______", Answer: "miaut"},
+				}},
+			},
+			wantWarnings: []string{`line 3: alternative answer "schnurrt" was dropped`},
+		},
+		{
+			name:    "escaped special characters",
+			content: `Is 1\=1 \{really\}\: yes\#? {=yes \~ indeed ~no \\ never ~maybe ~ask\# me}`,
+			want: []quiz.CreateSectionCommand{
+				{Name: "Animals", Exercises: []exercise.CreateExerciseCommand{
+					&exercise.CreateMultipleChoiceExerciseCommand{Question: "Is 1=1 {really}: yes#?", Choices: []string{"yes ~ indeed", `no \ never`, "maybe", "ask# me"}, Answer: "yes ~ indeed"},
+				}},
+			},
+		},
+		{
+			name:    "general feedback over answer feedback",
+			content: "What is red? {=rot#Right! ~blau#No, blue. ~grün ~gelb ####Rot is red.}",
+			want: []quiz.CreateSectionCommand{
+				{Name: "Animals", Exercises: []exercise.CreateExerciseCommand{
+					&exercise.CreateMultipleChoiceExerciseCommand{Question: "What is red?", Choices: []string{"rot", "blau", "grün", "gelb"}, Answer: "rot", Feedback: &general},
+				}},
+			},
+			wantWarnings: []string{
+				`line 1: feedback on wrong answer "blau" was dropped`,
+				`line 1: feedback on answer "rot" was dropped in favour of the general feedback`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, warnings, err := ParseGIFT(strings.NewReader(tt.content), NewOptions("Animals", language.German))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := quiz.NewCreateQuizCommand("Animals", language.German, tt.want)
+			if !reflect.DeepEqual(*cmd, want) {
+				t.Errorf("command = %+v, want %+v", *cmd, want)
+			}
+
+			gotWarnings := make([]string, 0)
+			for _, warning := range warnings {
+				gotWarnings = append(gotWarnings, warning.String())
+			}
+			if tt.wantWarnings == nil {
+				tt.wantWarnings = []string{}
+			}
+			if !reflect.DeepEqual(gotWarnings, tt.wantWarnings) {
+				t.Errorf("warnings = %q, want %q", gotWarnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestParseGIFTErrors(t *testing.T) {
+	content := "::Title Essay {}\n" +
+		"\n" +
+		"Describe your dog. {}\n" +
+		"\n" +
+		"How many legs? {#4}\n" +
+		"\n" +
+		"Dogs bark. {T}\n" +
+		"\n" +
+		"Match. {=Hund -> dog =Katze -> cat}\n" +
+		"\n" +
+		"What is red? {~%50%rot =rot ~blau}\n" +
+		"\n" +
+		"What is red? {~rot ~blau}\n" +
+		"\n" +
+		"What is red? {=rot =red ~blau}\n" +
+		"\n" +
+		"What is red? {=rot\n" +
+		"\n" +
+		"{=rot} and {=blau}\n" +
+		"\n" +
+		"No answer block.\n" +
+		"\n" +
+		"What is red? {=rot ~blau}\n"
+
+	_, _, err := ParseGIFT(strings.NewReader(content), NewOptions("Animals", language.German))
+	want := "line 1: question title is not closed with '::'; " +
+		"line 3: essay questions are not supported; " +
+		"line 5: numerical questions are not supported; " +
+		"line 7: true/false questions are not supported; " +
+		"line 9: matching questions are not supported; " +
+		"line 11: answer weights are not supported; " +
+		"line 13: question has no correct answer; " +
+		"line 15: multiple choice questions with more than one correct answer are not supported; " +
+		"line 17: answer block is not closed with '}'; " +
+		"line 19: questions with more than one answer block are not supported; " +
+		"line 21: description items without an answer block are not supported; " +
+		"line 23: expected 4 choices, found: 2"
+	if _, ok := err.(Errors); !ok || err.Error() != want {
+		t.Errorf("error = %v, want Errors %s", err, want)
+	}
+}
+
+func TestWriteGIFT(t *testing.T) {
+	feedback := "Rot {red}\nis red."
+	multipleChoice := exercise.NewMultipleChoiceExercise("1", time.Time{}, time.Time{}, &feedback, "Is 1=1? What # is: red~?", []string{"rot", "blau", "grün", `\gelb`}, "rot")
+	fillInTheBlank := exercise.NewFillInTheBlankExercise("2", time.Time{}, time.Time{}, nil, "Der Hund ______ laut.", "bellt")
+	sentenceCorrection := exercise.NewSentenceCorrectionExercise("3", time.Time{}, time.Time{}, nil, "Er gehen.", "Er geht.")
+	q := quiz.Quiz{
+		Name:        "German\nbasics",
+		LanguageTag: language.German,
+		Sections: []quiz.Section{
+			quiz.NewSection("Colours", []exercise.Exercise{&multipleChoice}),
+			quiz.NewSection("Verbs", []exercise.Exercise{&fillInTheBlank}),
+			quiz.NewSection("Sentences", []exercise.Exercise{&sentenceCorrection}),
+		},
+	}
+
+	var b strings.Builder
+	if err := WriteGIFT(&b, q); err != nil {
+		t.Fatalf("failed to write gift: %v", err)
+	}
+	want := "// German basics (de)\n" +
+		"\n" +
+		"$CATEGORY: Colours\n" +
+		"\n" +
+		`Is 1\=1? What \# is\: red\~? {=rot ~blau ~grün ~\\gelb ####Rot \{red\}\nis red.}` + "\n" +
+		"\n" +
+		"$CATEGORY: Verbs\n" +
+		"\n" +
+		"Der Hund {=bellt} laut.\n" +
+		"\n" +
+		"$CATEGORY: Sentences\n" +
+		"\n" +
+		"Er gehen. {=Er geht.}\n"
+	if b.String() != want {
+		t.Errorf("gift = %s, want %s", b.String(), want)
+	}
+
+	// The multiple choice and fill in the blank exercises read back the same.
+	cmd, _, err := ParseGIFT(strings.NewReader(b.String()), NewOptions("German basics", language.German))
+	if err != nil {
+		t.Fatalf("failed to parse written gift: %v", err)
+	}
+	wantSections := []quiz.CreateSectionCommand{
+		{Name: "Colours", Exercises: []exercise.CreateExerciseCommand{
+			&exercise.CreateMultipleChoiceExerciseCommand{Question: "Is 1=1? What # is: red~?", Choices: []string{"rot", "blau", "grün", `\gelb`}, Answer: "rot", Feedback: &feedback},
+		}},
+		{Name: "Verbs", Exercises: []exercise.CreateExerciseCommand{
+			&exercise.CreateFillInTheBlankExerciseCommand{Question: "Der Hund ______ laut.", Answer: "bellt"},
+		}},
+		{Name: "Sentences", Exercises: []exercise.CreateExerciseCommand{
+			&exercise.CreateFillInTheBlankExerciseCommand{Question: "Er gehen. ______", Answer: "Er geht."},
+		}},
+	}
+	if !reflect.DeepEqual(cmd.Sections, wantSections) {
+		t.Errorf("sections = %+v, want %+v", cmd.Sections, wantSections)
+	}
+}
@@ -0,0 +1,112 @@
+// Package moodle reads and writes the GIFT and Aiken question formats used by
+// Moodle question banks.
+//
+// GIFT multiple choice questions become multiple choice exercises. Short
+// answer questions and missing word questions with typed answers become fill
+// in the blank exercises, with the blank placed where the answer block was or
+// at the end of the question. Missing word questions with choices become
+// multiple choice exercises with a blank in the question. $CATEGORY lines
+// start a new section.
+//
+// True/false, matching, numerical, essay and partial credit questions have no
+// counterpart in a quiz and are reported as errors.
+package moodle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+const blank = "______"
+
+// ErrUnsupportedExercise is returned when a quiz cannot be written because a
+// format has no counterpart for one of its exercises.
+var ErrUnsupportedExercise = errors.New("unsupported exercise")
+
+type Options struct {
+	Name        string
+	LanguageTag language.Tag
+	// DefaultSection holds questions that are not in a category.
+	DefaultSection string
+}
+
+func NewOptions(name string, languageTag language.Tag) Options {
+	return Options{
+		Name:           name,
+		LanguageTag:    languageTag,
+		DefaultSection: name,
+	}
+}
+
+// Issue is an error or warning found at a line of the input.
+type Issue struct {
+	Line    int
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+}
+
+// Errors collects every error found in a file.
+type Errors []Issue
+
+func (e Errors) Error() string {
+	messages := make([]string, 0)
+	for _, issue := range e {
+		messages = append(messages, issue.String())
+	}
+	return strings.Join(messages, "; ")
+}
+
+type section struct {
+	name     string
+	line     int
+	commands []exercise.CreateExerciseCommand
+}
+
+type sectionBuilder struct {
+	sections []*section
+	byName   map[string]*section
+}
+
+func newSectionBuilder() *sectionBuilder {
+	return &sectionBuilder{byName: make(map[string]*section)}
+}
+
+func (b *sectionBuilder) add(name string, line int, cmd exercise.CreateExerciseCommand) {
+	s, ok := b.byName[name]
+	if !ok {
+		s = &section{name: name, line: line}
+		b.byName[name] = s
+		b.sections = append(b.sections, s)
+	}
+	s.commands = append(s.commands, cmd)
+}
+
+func (b *sectionBuilder) build(opts Options, errs Errors) (*quiz.CreateQuizCommand, error) {
+	createSectionCommands := make([]quiz.CreateSectionCommand, 0)
+	for _, s := range b.sections {
+		createSectionCommand, err := quiz.NewCreateSectionCommand(s.name, s.commands)
+		if err != nil {
+			errs = append(errs, Issue{Line: s.line, Message: fmt.Sprintf("section %q: %s", s.name, err)})
+			continue
+		}
+		createSectionCommands = append(createSectionCommands, *createSectionCommand)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if len(createSectionCommands) == 0 {
+		return nil, fmt.Errorf("file has no questions")
+	}
+
+	cmd := quiz.NewCreateQuizCommand(opts.Name, opts.LanguageTag, createSectionCommands)
+	return &cmd, nil
+}
@@ -0,0 +1,131 @@
+package portable
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("unsupported format: %q", s)
+	}
+}
+
+// migrations[n] upgrades a decoded document from version n to version n+1.
+// When the format changes, bump CurrentVersion and register a migration for
+// the previous version here.
+var migrations = map[int]func(doc map[string]any) error{}
+
+// Decode reads a document in the given format and upgrades it to the current
+// version.
+func Decode(r io.Reader, format Format) (*Document, error) {
+	var raw map[string]any
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("failed to decode json: %w", err)
+		}
+	case FormatYAML:
+		if err := yaml.NewDecoder(r).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("failed to decode yaml: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format: %q", format)
+	}
+
+	if err := migrate(raw, CurrentVersion); err != nil {
+		return nil, err
+	}
+
+	// Round-trip through JSON so both formats share the strict decoding below.
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-encode document: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	return &doc, nil
+}
+
+// migrate upgrades a decoded document to version to.
+func migrate(raw map[string]any, to int) error {
+	version, err := documentVersion(raw)
+	if err != nil {
+		return err
+	}
+	if version > to {
+		return fmt.Errorf("document version %d is newer than the supported version %d", version, to)
+	}
+
+	for ; version < to; version++ {
+		migration, ok := migrations[version]
+		if !ok {
+			return fmt.Errorf("no migration from document version %d", version)
+		}
+		if err := migration(raw); err != nil {
+			return fmt.Errorf("failed to migrate document from version %d: %w", version, err)
+		}
+		raw["version"] = version + 1
+	}
+	return nil
+}
+
+func documentVersion(raw map[string]any) (int, error) {
+	switch version := raw["version"].(type) {
+	case nil:
+		return 0, errors.New("field 'version' is missing")
+	case float64:
+		if version != float64(int(version)) || version < 1 {
+			return 0, fmt.Errorf("invalid document version: %v", version)
+		}
+		return int(version), nil
+	case int:
+		if version < 1 {
+			return 0, fmt.Errorf("invalid document version: %v", version)
+		}
+		return version, nil
+	default:
+		return 0, fmt.Errorf("invalid document version: %v", version)
+	}
+}
+
+// Encode writes the document in the given format.
+func Encode(w io.Writer, doc Document, format Format) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unsupported format: %q", format)
+	}
+}
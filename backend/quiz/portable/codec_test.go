@@ -0,0 +1,94 @@
+package portable
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	// Version 2 renames languageTag to language, and version 3 names
+	// untitled quizzes.
+	testMigrations := map[int]func(doc map[string]any) error{
+		1: func(doc map[string]any) error {
+			q := doc["quiz"].(map[string]any)
+			q["language"] = q["languageTag"]
+			delete(q, "languageTag")
+			return nil
+		},
+		2: func(doc map[string]any) error {
+			q := doc["quiz"].(map[string]any)
+			if q["name"] == nil {
+				q["name"] = "Untitled"
+			}
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name    string
+		raw     map[string]any
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "every step from json",
+			raw:  map[string]any{"version": 1.0, "quiz": map[string]any{"languageTag": "nl"}},
+			want: map[string]any{"version": 3, "quiz": map[string]any{"language": "nl", "name": "Untitled"}},
+		},
+		{
+			name: "remaining steps from yaml",
+			raw:  map[string]any{"version": 2, "quiz": map[string]any{"language": "nl", "name": "Dutch basics"}},
+			want: map[string]any{"version": 3, "quiz": map[string]any{"language": "nl", "name": "Dutch basics"}},
+		},
+		{
+			name: "current version untouched",
+			raw:  map[string]any{"version": 3, "quiz": map[string]any{}},
+			want: map[string]any{"version": 3, "quiz": map[string]any{}},
+		},
+		{
+			name:    "newer version",
+			raw:     map[string]any{"version": 4},
+			wantErr: "document version 4 is newer than the supported version 3",
+		},
+		{
+			name:    "missing version",
+			raw:     map[string]any{"quiz": map[string]any{}},
+			wantErr: "field 'version' is missing",
+		},
+		{
+			name:    "fractional version",
+			raw:     map[string]any{"version": 1.5},
+			wantErr: "invalid document version: 1.5",
+		},
+	}
+
+	saved := migrations
+	migrations = testMigrations
+	defer func() { migrations = saved }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := migrate(tt.raw, 3)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.raw, tt.want) {
+				t.Errorf("document = %v, want %v", tt.raw, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing migration", func(t *testing.T) {
+		migrations = map[int]func(doc map[string]any) error{1: testMigrations[1]}
+		err := migrate(map[string]any{"version": 1, "quiz": map[string]any{"languageTag": "nl"}}, 3)
+		if err == nil || err.Error() != "no migration from document version 2" {
+			t.Errorf("error = %v, want no migration from document version 2", err)
+		}
+	})
+}
@@ -0,0 +1,276 @@
+// Package portable implements the versioned interchange format used to back
+// up quizzes and move them between environments.
+//
+// A document is JSON or YAML with the same structure:
+//
+//	version: 1
+//	quiz:
+//	  name: Dutch basics
+//	  languageTag: nl
+//	  sections:
+//	    - name: Articles
+//	      exercises:
+//	        - type: multipleChoice
+//	          question: ___ huis
+//	          choices: [de, het, een, "-"]
+//	          answer: het
+//	          feedback: Huis is a neuter noun.
+//	        - type: fillInTheBlank
+//	          question: Ik ______ een appel.
+//	          answer: eet
+//	        - type: sentenceCorrection
+//	          sentence: Ik hebben een fiets.
+//	          correctedSentence: Ik heb een fiets.
+//
+// Exercise fields depend on the type: multipleChoice uses question, choices
+// and answer; fillInTheBlank uses question and answer; sentenceCorrection uses
+// sentence and correctedSentence. Feedback is optional for every type.
+//
+// The version is bumped whenever the structure changes. Older documents are
+// upgraded on decode by the migrations registered in this package, so a
+// document exported by any earlier release can still be imported.
+package portable
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+// CurrentVersion is the version written by Export.
+const CurrentVersion = 1
+
+var (
+	errRequired = errors.New("required field is missing")
+	errEmpty    = errors.New("field is empty")
+)
+
+type Document struct {
+	Version int  `json:"version" yaml:"version"`
+	Quiz    Quiz `json:"quiz" yaml:"quiz"`
+}
+
+type Quiz struct {
+	Name        string    `json:"name" yaml:"name"`
+	LanguageTag string    `json:"languageTag" yaml:"languageTag"`
+	Sections    []Section `json:"sections" yaml:"sections"`
+}
+
+type Section struct {
+	Name      string     `json:"name" yaml:"name"`
+	Exercises []Exercise `json:"exercises" yaml:"exercises"`
+}
+
+type Exercise struct {
+	Type              string   `json:"type" yaml:"type"`
+	Question          string   `json:"question,omitempty" yaml:"question,omitempty"`
+	Choices           []string `json:"choices,omitempty" yaml:"choices,omitempty"`
+	Answer            string   `json:"answer,omitempty" yaml:"answer,omitempty"`
+	Sentence          string   `json:"sentence,omitempty" yaml:"sentence,omitempty"`
+	CorrectedSentence string   `json:"correctedSentence,omitempty" yaml:"correctedSentence,omitempty"`
+	Feedback          *string  `json:"feedback,omitempty" yaml:"feedback,omitempty"`
+}
+
+// FieldError is a problem with a single field of a document. Pointer is a
+// JSON pointer into the document, e.g. /quiz/sections/0/exercises/3.
+type FieldError struct {
+	Pointer string
+	Err     error
+}
+
+func (e FieldError) Error() string {
+	return e.Pointer + ": " + e.Err.Error()
+}
+
+// Errors collects every field error found in a document.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0)
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Export converts a stored quiz to a document of the current version.
+func Export(q quiz.Quiz) (*Document, error) {
+	sections := make([]Section, 0)
+	for _, s := range q.Sections {
+		exercises := make([]Exercise, 0)
+		for _, e := range s.Exercises {
+			exported, err := exportExercise(e)
+			if err != nil {
+				return nil, err
+			}
+			exercises = append(exercises, exported)
+		}
+		sections = append(sections, Section{Name: s.Name, Exercises: exercises})
+	}
+
+	return &Document{
+		Version: CurrentVersion,
+		Quiz: Quiz{
+			Name:        q.Name,
+			LanguageTag: q.LanguageTag.String(),
+			Sections:    sections,
+		},
+	}, nil
+}
+
+func exportExercise(e exercise.Exercise) (Exercise, error) {
+	switch e := e.(type) {
+	case *exercise.MultipleChoiceExercise:
+		return Exercise{
+			Type:     exercise.TypeMultipleChoice,
+			Question: e.Question,
+			Choices:  e.Choices,
+			Answer:   e.Answer().(string),
+			Feedback: e.Feedback(),
+		}, nil
+	case *exercise.FillInTheBlankExercise:
+		return Exercise{
+			Type:     exercise.TypeFillInTheBlank,
+			Question: e.Question,
+			Answer:   e.Answer().(string),
+			Feedback: e.Feedback(),
+		}, nil
+	case *exercise.SentenceCorrectionExercise:
+		return Exercise{
+			Type:              exercise.TypeSentenceCorrection,
+			Sentence:          e.Sentence,
+			CorrectedSentence: e.CorrectedSentence,
+			Feedback:          e.Feedback(),
+		}, nil
+	default:
+		return Exercise{}, fmt.Errorf("unknown exercise type: %T", e)
+	}
+}
+
+// FromCommand converts a create command to a document of the current version.
+func FromCommand(cmd quiz.CreateQuizCommand) (*Document, error) {
+	sections := make([]Section, 0)
+	for _, s := range cmd.Sections {
+		exercises := make([]Exercise, 0)
+		for _, c := range s.Exercises {
+			switch c := c.(type) {
+			case *exercise.CreateMultipleChoiceExerciseCommand:
+				exercises = append(exercises, Exercise{
+					Type:     exercise.TypeMultipleChoice,
+					Question: c.Question,
+					Choices:  c.Choices,
+					Answer:   c.Answer,
+					Feedback: c.Feedback,
+				})
+			case *exercise.CreateFillInTheBlankExerciseCommand:
+				exercises = append(exercises, Exercise{
+					Type:     exercise.TypeFillInTheBlank,
+					Question: c.Question,
+					Answer:   c.Answer,
+					Feedback: c.Feedback,
+				})
+			case *exercise.CreateSentenceCorrectionExerciseCommand:
+				exercises = append(exercises, Exercise{
+					Type:              exercise.TypeSentenceCorrection,
+					Sentence:          c.Sentence,
+					CorrectedSentence: c.CorrectedSentence,
+					Feedback:          c.Feedback,
+				})
+			default:
+				return nil, fmt.Errorf("unknown exercise type: %T", c)
+			}
+		}
+		sections = append(sections, Section{Name: s.Name, Exercises: exercises})
+	}
+
+	return &Document{
+		Version: CurrentVersion,
+		Quiz: Quiz{
+			Name:        cmd.Name,
+			LanguageTag: cmd.LanguageTag.String(),
+			Sections:    sections,
+		},
+	}, nil
+}
+
+// ToCommand validates the document and converts it to a create command. It
+// returns Errors listing every invalid field.
+func (d *Document) ToCommand() (*quiz.CreateQuizCommand, error) {
+	errs := make(Errors, 0)
+	addError := func(pointer string, err error) {
+		errs = append(errs, FieldError{Pointer: pointer, Err: err})
+	}
+
+	if d.Quiz.Name == "" {
+		addError("/quiz/name", errRequired)
+	}
+	languageTag, err := language.Parse(d.Quiz.LanguageTag)
+	if err != nil {
+		addError("/quiz/languageTag", err)
+	}
+	if len(d.Quiz.Sections) == 0 {
+		addError("/quiz/sections", errEmpty)
+	}
+
+	createSectionCommands := make([]quiz.CreateSectionCommand, 0)
+	for i, s := range d.Quiz.Sections {
+		sectionPointer := fmt.Sprintf("/quiz/sections/%d", i)
+		if s.Name == "" {
+			addError(sectionPointer+"/name", errRequired)
+		}
+		if len(s.Exercises) == 0 {
+			addError(sectionPointer+"/exercises", errEmpty)
+		}
+
+		createExerciseCommands := make([]exercise.CreateExerciseCommand, 0)
+		for j, e := range s.Exercises {
+			cmd, err := e.toCommand()
+			if err != nil {
+				addError(fmt.Sprintf("%s/exercises/%d", sectionPointer, j), err)
+				continue
+			}
+			createExerciseCommands = append(createExerciseCommands, cmd)
+		}
+
+		createSectionCommand, err := quiz.NewCreateSectionCommand(s.Name, createExerciseCommands)
+		if err != nil {
+			addError(sectionPointer+"/exercises", err)
+			continue
+		}
+		createSectionCommands = append(createSectionCommands, *createSectionCommand)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	cmd := quiz.NewCreateQuizCommand(d.Quiz.Name, languageTag, createSectionCommands)
+	return &cmd, nil
+}
+
+func (e Exercise) toCommand() (exercise.CreateExerciseCommand, error) {
+	switch e.Type {
+	case exercise.TypeMultipleChoice:
+		if e.Question == "" || e.Answer == "" {
+			return nil, fmt.Errorf("%s exercise requires question, choices and answer", e.Type)
+		}
+		return exercise.NewCreateMultipleChoiceExerciseCommand(e.Question, e.Choices, e.Answer, e.Feedback)
+	case exercise.TypeFillInTheBlank:
+		if e.Question == "" || e.Answer == "" {
+			return nil, fmt.Errorf("%s exercise requires question and answer", e.Type)
+		}
+		return exercise.NewCreateFillInTheBlankExerciseCommand(e.Question, e.Answer, e.Feedback)
+	case exercise.TypeSentenceCorrection:
+		if e.Sentence == "" || e.CorrectedSentence == "" {
+			return nil, fmt.Errorf("%s exercise requires sentence and correctedSentence", e.Type)
+		}
+		return exercise.NewCreateSentenceCorrectionExerciseCommand(e.Sentence, e.CorrectedSentence, e.Feedback)
+	default:
+		return nil, fmt.Errorf("unsupported exercise type: %q", e.Type)
+	}
+}
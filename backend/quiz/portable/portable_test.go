@@ -0,0 +1,88 @@
+package portable
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+func TestRoundTrip(t *testing.T) {
+	feedback := "Huis is a neuter noun."
+	multipleChoice := exercise.NewMultipleChoiceExercise("1", time.Time{}, time.Time{}, &feedback, "___ huis", []string{"de", "het", "een", "-"}, "het")
+	fillInTheBlank := exercise.NewFillInTheBlankExercise("2", time.Time{}, time.Time{}, nil, "Ik ______ een appel.", "eet")
+	sentenceCorrection := exercise.NewSentenceCorrectionExercise("3", time.Time{}, time.Time{}, nil, "Ik hebben een fiets.", "Ik heb een fiets.")
+	q := quiz.Quiz{
+		ID:          "quiz-1",
+		Name:        "Dutch basics",
+		LanguageTag: language.Dutch,
+		Sections: []quiz.Section{
+			quiz.NewSection("Articles", []exercise.Exercise{&multipleChoice}),
+			quiz.NewSection("Verbs", []exercise.Exercise{&fillInTheBlank}),
+			quiz.NewSection("Sentences", []exercise.Exercise{&sentenceCorrection}),
+		},
+	}
+
+	for _, format := range []Format{FormatJSON, FormatYAML} {
+		t.Run(string(format), func(t *testing.T) {
+			exported, err := Export(q)
+			if err != nil {
+				t.Fatalf("failed to export quiz: %v", err)
+			}
+			var b bytes.Buffer
+			if err := Encode(&b, *exported, format); err != nil {
+				t.Fatalf("failed to encode document: %v", err)
+			}
+
+			imported, err := Decode(&b, format)
+			if err != nil {
+				t.Fatalf("failed to decode document: %v", err)
+			}
+			if !reflect.DeepEqual(imported, exported) {
+				t.Errorf("imported document = %+v, want %+v", imported, exported)
+			}
+
+			cmd, err := imported.ToCommand()
+			if err != nil {
+				t.Fatalf("failed to convert document: %v", err)
+			}
+			doc, err := FromCommand(*cmd)
+			if err != nil {
+				t.Fatalf("failed to convert command: %v", err)
+			}
+			if !reflect.DeepEqual(doc, exported) {
+				t.Errorf("document of the command = %+v, want %+v", doc, exported)
+			}
+		})
+	}
+}
+
+func TestToCommandErrors(t *testing.T) {
+	doc := Document{
+		Version: CurrentVersion,
+		Quiz: Quiz{
+			LanguageTag: "nl",
+			Sections: []Section{
+				{Name: "Articles", Exercises: []Exercise{
+					{Type: exercise.TypeFillInTheBlank, Question: "Ik ______ een appel.", Answer: "eet"},
+					{Type: exercise.TypeMultipleChoice, Question: "___ huis", Choices: []string{"de", "het"}, Answer: "het"},
+				}},
+				{Exercises: []Exercise{{Type: "essay"}}},
+			},
+		},
+	}
+
+	_, err := doc.ToCommand()
+	want := "/quiz/name: required field is missing; " +
+		"/quiz/sections/0/exercises/1: expected 4 choices, found: 2; " +
+		"/quiz/sections/1/name: required field is missing; " +
+		`/quiz/sections/1/exercises/0: unsupported exercise type: "essay"`
+	if _, ok := err.(Errors); !ok || err.Error() != want {
+		t.Errorf("error = %v, want Errors %s", err, want)
+	}
+}
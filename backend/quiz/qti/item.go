@@ -0,0 +1,167 @@
+package qti
+
+import (
+	"fmt"
+	"strings"
+
+	"languagequiz/quiz/exercise"
+)
+
+const blank = "______"
+
+type item struct {
+	identifier string
+	root       node
+}
+
+func itemIdentifier(e exercise.Exercise) string {
+	switch e := e.(type) {
+	case *exercise.MultipleChoiceExercise:
+		return "item-" + e.ID
+	case *exercise.FillInTheBlankExercise:
+		return "item-" + e.ID
+	case *exercise.SentenceCorrectionExercise:
+		return "item-" + e.ID
+	default:
+		return ""
+	}
+}
+
+func newItem(e exercise.Exercise, languageTag string, p profile) (item, error) {
+	identifier := itemIdentifier(e)
+
+	var title string
+	var declaration, body, processing node
+	switch e := e.(type) {
+	case *exercise.MultipleChoiceExercise:
+		title = e.Question
+		declaration, body, processing = multipleChoice(e)
+	case *exercise.FillInTheBlankExercise:
+		title = e.Question
+		declaration, body, processing = fillInTheBlank(e)
+	case *exercise.SentenceCorrectionExercise:
+		title = e.Sentence
+		declaration, body, processing = sentenceCorrection(e)
+	default:
+		return item{}, fmt.Errorf("unsupported exercise: %T", e)
+	}
+
+	children := []node{
+		declaration,
+		el("outcomeDeclaration", attrs("identifier", "SCORE", "cardinality", "single", "baseType", "float"),
+			el("defaultValue", nil, el("value", nil, text("0")))),
+	}
+	feedback := e.Feedback()
+	if feedback != nil {
+		// The feedback is shown once a response is submitted, whether it is
+		// correct or not, like it is in the app.
+		children = append(children, el("outcomeDeclaration", attrs("identifier", "FEEDBACK", "cardinality", "single", "baseType", "identifier")))
+		processing.children = append(processing.children,
+			el("setOutcomeValue", attrs("identifier", "FEEDBACK"),
+				el("baseValue", attrs("baseType", "identifier"), text("FEEDBACK"))))
+	}
+	children = append(children, body, processing)
+	if feedback != nil {
+		content := text(*feedback)
+		if p.version == Version30 {
+			content = el("contentBody", nil, content)
+		}
+		children = append(children,
+			el("modalFeedback", attrs("outcomeIdentifier", "FEEDBACK", "identifier", "FEEDBACK", "showHide", "show"),
+				content))
+	}
+
+	root := el("assessmentItem",
+		attrs(
+			"xmlns", p.namespace,
+			"xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance",
+			"xsi:schemaLocation", p.schemaLocation,
+			"identifier", identifier,
+			"title", title,
+			"adaptive", "false",
+			"timeDependent", "false",
+			"xml:lang", languageTag,
+		),
+		children...,
+	)
+	return item{identifier: identifier, root: root}, nil
+}
+
+func multipleChoice(e *exercise.MultipleChoiceExercise) (node, node, node) {
+	answer, _ := e.Answer().(string)
+	correct := ""
+	choices := make([]node, 0)
+	for i, choice := range e.Choices {
+		identifier := fmt.Sprintf("choice-%d", i+1)
+		if choice == answer {
+			correct = identifier
+		}
+		choices = append(choices, el("simpleChoice", attrs("identifier", identifier), text(choice)))
+	}
+
+	declaration := el("responseDeclaration",
+		attrs("identifier", "RESPONSE", "cardinality", "single", "baseType", "identifier"),
+		el("correctResponse", nil, el("value", nil, text(correct))))
+	interaction := el("choiceInteraction",
+		attrs("responseIdentifier", "RESPONSE", "shuffle", "false", "maxChoices", "1"),
+		append([]node{el("prompt", nil, text(e.Question))}, choices...)...)
+	body := el("itemBody", nil, interaction)
+	processing := scoreProcessing(el("match", nil,
+		el("variable", attrs("identifier", "RESPONSE")),
+		el("correct", attrs("identifier", "RESPONSE"))))
+	return declaration, body, processing
+}
+
+// fillInTheBlank puts a text entry interaction where the blank is.
+func fillInTheBlank(e *exercise.FillInTheBlankExercise) (node, node, node) {
+	answer, _ := e.Answer().(string)
+	before, after, _ := strings.Cut(e.Question, blank)
+
+	paragraph := htmlEl("p")
+	if before != "" {
+		paragraph.children = append(paragraph.children, text(before))
+	}
+	paragraph.children = append(paragraph.children, el("textEntryInteraction",
+		attrs("responseIdentifier", "RESPONSE", "expectedLength", fmt.Sprint(len([]rune(answer))))))
+	if after != "" {
+		paragraph.children = append(paragraph.children, text(after))
+	}
+
+	declaration := stringResponseDeclaration(answer)
+	body := el("itemBody", nil, paragraph)
+	return declaration, body, stringMatchProcessing()
+}
+
+func sentenceCorrection(e *exercise.SentenceCorrectionExercise) (node, node, node) {
+	declaration := stringResponseDeclaration(e.CorrectedSentence)
+	body := el("itemBody", nil,
+		el("extendedTextInteraction",
+			attrs("responseIdentifier", "RESPONSE", "expectedLines", "1"),
+			el("prompt", nil, text(e.Sentence))))
+	return declaration, body, stringMatchProcessing()
+}
+
+func stringResponseDeclaration(answer string) node {
+	return el("responseDeclaration",
+		attrs("identifier", "RESPONSE", "cardinality", "single", "baseType", "string"),
+		el("correctResponse", nil, el("value", nil, text(answer))))
+}
+
+// stringMatchProcessing scores a typed response without regard to case.
+func stringMatchProcessing() node {
+	return scoreProcessing(el("stringMatch", attrs("caseSensitive", "false"),
+		el("variable", attrs("identifier", "RESPONSE")),
+		el("correct", attrs("identifier", "RESPONSE"))))
+}
+
+// scoreProcessing sets SCORE to 1 when condition holds and to 0 otherwise.
+func scoreProcessing(condition node) node {
+	score := func(value string) node {
+		return el("setOutcomeValue", attrs("identifier", "SCORE"),
+			el("baseValue", attrs("baseType", "float"), text(value)))
+	}
+	return el("responseProcessing", nil,
+		el("responseCondition", nil,
+			el("responseIf", nil, condition, score("1")),
+			el("responseElse", nil, score("0"))))
+}
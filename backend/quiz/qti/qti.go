@@ -0,0 +1,180 @@
+// Package qti writes quizzes as IMS Question and Test Interoperability (QTI)
+// content packages that learning management systems can import.
+//
+// A package is a zip archive holding an imsmanifest.xml, one assessment test
+// with an assessment section per quiz section, and one assessment item per
+// exercise. Multiple choice exercises become choice interactions. Fill in the
+// blank exercises become a text entry interaction in place of the blank, and
+// sentence correction exercises an extended text interaction; both are scored
+// with a case insensitive string match, like answers are graded by the api.
+// Feedback is shown as modal feedback once a response is submitted.
+package qti
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+
+	"languagequiz/quiz"
+)
+
+type Version string
+
+const (
+	Version21 Version = "2.1"
+	Version30 Version = "3.0"
+)
+
+func ParseVersion(s string) (Version, error) {
+	switch Version(s) {
+	case Version21, Version30:
+		return Version(s), nil
+	default:
+		return "", fmt.Errorf("unsupported qti version: %q", s)
+	}
+}
+
+const (
+	manifestFile = "imsmanifest.xml"
+	testFile     = "assessment.xml"
+	itemDir      = "items/"
+)
+
+type profile struct {
+	version        Version
+	namespace      string
+	schemaLocation string
+	manifestNS     string
+	itemType       string
+	testType       string
+}
+
+var profiles = map[Version]profile{
+	Version21: {
+		version:        Version21,
+		namespace:      "http://www.imsglobal.org/xsd/imsqti_v2p1",
+		schemaLocation: "http://www.imsglobal.org/xsd/imsqti_v2p1 http://www.imsglobal.org/xsd/qti/qtiv2p1/imsqti_v2p1p2.xsd",
+		manifestNS:     "http://www.imsglobal.org/xsd/imscp_v1p1",
+		itemType:       "imsqti_item_xmlv2p1",
+		testType:       "imsqti_test_xmlv2p1",
+	},
+	Version30: {
+		version:        Version30,
+		namespace:      "http://www.imsglobal.org/xsd/imsqtiasi_v3p0",
+		schemaLocation: "http://www.imsglobal.org/xsd/imsqtiasi_v3p0 https://purl.imsglobal.org/spec/qti/v3p0/schema/xsd/imsqti_asiv3p0_v1p0.xsd",
+		manifestNS:     "http://www.imsglobal.org/xsd/qti/qtiv3p0/imscp_v1p1",
+		itemType:       "imsqti_item_xmlv3p0",
+		testType:       "imsqti_test_xmlv3p0",
+	},
+}
+
+// Export writes q as a zipped QTI content package.
+func Export(w io.Writer, q quiz.Quiz, v Version) error {
+	p, ok := profiles[v]
+	if !ok {
+		return fmt.Errorf("unsupported qti version: %q", v)
+	}
+	if len(q.GetExercises()) == 0 {
+		return errors.New("quiz has no exercises")
+	}
+
+	archive := zip.NewWriter(w)
+	items := make([]item, 0)
+	for _, s := range q.Sections {
+		for _, e := range s.Exercises {
+			i, err := newItem(e, q.LanguageTag.String(), p)
+			if err != nil {
+				return err
+			}
+			if err := writeFile(archive, itemDir+i.identifier+".xml", i.root, v); err != nil {
+				return err
+			}
+			items = append(items, i)
+		}
+	}
+
+	if err := writeFile(archive, testFile, newTest(q, p), v); err != nil {
+		return err
+	}
+	if err := writeFile(archive, manifestFile, newManifest(q, items, p), ""); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeFile(archive *zip.Writer, name string, root node, v Version) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	if err := writeDocument(f, root, v); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func newTest(q quiz.Quiz, p profile) node {
+	sections := make([]node, 0)
+	for i, s := range q.Sections {
+		refs := make([]node, 0)
+		for _, e := range s.Exercises {
+			identifier := itemIdentifier(e)
+			refs = append(refs, el("assessmentItemRef", attrs("identifier", identifier, "href", itemDir+identifier+".xml")))
+		}
+		sections = append(sections, el("assessmentSection",
+			attrs("identifier", fmt.Sprintf("section-%d", i+1), "title", s.Name, "visible", "true"),
+			refs...))
+	}
+
+	return el("assessmentTest",
+		attrs(
+			"xmlns", p.namespace,
+			"xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance",
+			"xsi:schemaLocation", p.schemaLocation,
+			"identifier", "quiz-"+q.ID,
+			"title", q.Name,
+		),
+		el("outcomeDeclaration", attrs("identifier", "SCORE", "cardinality", "single", "baseType", "float")),
+		el("testPart", attrs("identifier", "part-1", "navigationMode", "linear", "submissionMode", "individual"), sections...),
+		el("outcomeProcessing", nil,
+			el("setOutcomeValue", attrs("identifier", "SCORE"),
+				el("sum", nil, el("testVariables", attrs("variableIdentifier", "SCORE"))))),
+	)
+}
+
+// newManifest lists the test and its items as IMS content packaging resources.
+// The manifest is not QTI markup, so its names are the same for every version.
+func newManifest(q quiz.Quiz, items []item, p profile) node {
+	testDependencies := make([]node, 0)
+	resources := make([]node, 0)
+	for _, i := range items {
+		testDependencies = append(testDependencies, node{name: "dependency", attrs: attrs("identifierref", "resource-"+i.identifier)})
+	}
+	resources = append(resources, node{
+		name:     "resource",
+		attrs:    attrs("identifier", "resource-test", "type", p.testType, "href", testFile),
+		children: append([]node{{name: "file", attrs: attrs("href", testFile)}}, testDependencies...),
+	})
+	for _, i := range items {
+		href := itemDir + i.identifier + ".xml"
+		resources = append(resources, node{
+			name:     "resource",
+			attrs:    attrs("identifier", "resource-"+i.identifier, "type", p.itemType, "href", href),
+			children: []node{{name: "file", attrs: attrs("href", href)}},
+		})
+	}
+
+	return node{
+		name:  "manifest",
+		attrs: attrs("xmlns", p.manifestNS, "identifier", "manifest-"+q.ID),
+		children: []node{
+			{name: "metadata", children: []node{
+				{name: "schema", text: "QTI Package"},
+				{name: "schemaversion", text: string(p.version)},
+			}},
+			{name: "organizations"},
+			{name: "resources", children: resources},
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package qti
+
+import (
+	"archive/zip"
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"languagequiz/quiz"
+	"languagequiz/quiz/exercise"
+
+	"golang.org/x/text/language"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestExport compares every file of the package with the committed one in
+// testdata/<version>. Run the tests with -update after changing the markup.
+func TestExport(t *testing.T) {
+	feedback := "Eten is to eat."
+	multipleChoice := exercise.NewMultipleChoiceExercise("1", time.Time{}, time.Time{}, nil, "___ huis", []string{"de", "het", "een", "-"}, "het")
+	fillInTheBlank := exercise.NewFillInTheBlankExercise("2", time.Time{}, time.Time{}, &feedback, "Ik ______ een appel.", "eet")
+	sentenceCorrection := exercise.NewSentenceCorrectionExercise("3", time.Time{}, time.Time{}, nil, "Ik hebben een fiets.", "Ik heb een fiets.")
+	q := quiz.Quiz{
+		ID:          "quiz-1",
+		Name:        "Dutch basics",
+		LanguageTag: language.Dutch,
+		Sections: []quiz.Section{
+			quiz.NewSection("Articles", []exercise.Exercise{&multipleChoice}),
+			quiz.NewSection("Verbs", []exercise.Exercise{&fillInTheBlank, &sentenceCorrection}),
+		},
+	}
+	wantFiles := []string{"items/item-1.xml", "items/item-2.xml", "items/item-3.xml", testFile, manifestFile}
+
+	for _, v := range []Version{Version21, Version30} {
+		t.Run(string(v), func(t *testing.T) {
+			var b bytes.Buffer
+			if err := Export(&b, q, v); err != nil {
+				t.Fatalf("failed to export quiz: %v", err)
+			}
+			archive, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+			if err != nil {
+				t.Fatalf("failed to read package: %v", err)
+			}
+
+			names := make([]string, 0)
+			for _, f := range archive.File {
+				names = append(names, f.Name)
+
+				r, err := f.Open()
+				if err != nil {
+					t.Fatalf("failed to open %s: %v", f.Name, err)
+				}
+				got, err := io.ReadAll(r)
+				r.Close()
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", f.Name, err)
+				}
+
+				golden := filepath.Join("testdata", string(v), filepath.FromSlash(f.Name))
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+						t.Fatalf("failed to create %s: %v", filepath.Dir(golden), err)
+					}
+					if err := os.WriteFile(golden, got, 0o644); err != nil {
+						t.Fatalf("failed to write %s: %v", golden, err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", golden, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s differs from %s; run go test ./quiz/qti -update and review the diff", f.Name, golden)
+				}
+			}
+			if !reflect.DeepEqual(names, wantFiles) {
+				t.Errorf("files = %v, want %v", names, wantFiles)
+			}
+		})
+	}
+}
+
+func TestKebabCase(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"assessmentItem", "assessment-item"},
+		{"textEntryInteraction", "text-entry-interaction"},
+		{"timeDependent", "time-dependent"},
+		{"value", "value"},
+	}
+
+	for _, tt := range tests {
+		if got := kebabCase(tt.name); got != tt.want {
+			t.Errorf("kebabCase(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	q := quiz.Quiz{ID: "quiz-1", Name: "Empty", LanguageTag: language.Dutch}
+
+	tests := []struct {
+		name    string
+		version Version
+		wantErr string
+	}{
+		{name: "unsupported version", version: "1.2", wantErr: `unsupported qti version: "1.2"`},
+		{name: "no exercises", version: Version21, wantErr: "quiz has no exercises"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Export(io.Discard, q, tt.version)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
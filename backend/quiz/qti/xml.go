@@ -0,0 +1,104 @@
+package qti
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// node is an XML element written with QTI 2.1 names. When rendered for QTI 3.0
+// the names of QTI elements and attributes are converted to the qti-prefixed
+// kebab-case names of that version. HTML elements keep their names.
+type node struct {
+	name     string
+	html     bool
+	attrs    [][2]string
+	children []node
+	text     string
+}
+
+func el(name string, attrs [][2]string, children ...node) node {
+	return node{name: name, attrs: attrs, children: children}
+}
+
+func htmlEl(name string, children ...node) node {
+	return node{name: name, html: true, children: children}
+}
+
+func text(s string) node {
+	return node{text: s}
+}
+
+func attrs(pairs ...string) [][2]string {
+	result := make([][2]string, 0)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		result = append(result, [2]string{pairs[i], pairs[i+1]})
+	}
+	return result
+}
+
+func writeDocument(w io.Writer, root node, v Version) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encodeNode(encoder, root, v); err != nil {
+		return err
+	}
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func encodeNode(encoder *xml.Encoder, n node, v Version) error {
+	if n.name == "" {
+		return encoder.EncodeToken(xml.CharData(n.text))
+	}
+
+	name := n.name
+	if v == Version30 && !n.html {
+		name = "qti-" + kebabCase(name)
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	for _, attr := range n.attrs {
+		attrName := attr[0]
+		if v == Version30 && !n.html && !strings.Contains(attrName, ":") {
+			attrName = kebabCase(attrName)
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attrName}, Value: attr[1]})
+	}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, child := range n.children {
+		if err := encodeNode(encoder, child, v); err != nil {
+			return err
+		}
+	}
+	if n.text != "" {
+		if err := encoder.EncodeToken(xml.CharData(n.text)); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+func kebabCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
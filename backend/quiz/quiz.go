@@ -8,12 +8,18 @@ import (
 	"golang.org/x/text/language"
 )
 
+// Quiz is a quiz with its sections. Revision is the number of the revision
+// the sections come from, or 0 when they are the draft. ForkedFrom is the ID
+// of the quiz this one was cloned from, if any.
 type Quiz struct {
 	ID          string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	Name        string
 	LanguageTag language.Tag
+	Status      Status
+	Revision    int
+	ForkedFrom  string
 	Sections    []Section
 }
 
@@ -25,13 +31,14 @@ func (q *Quiz) GetExercises() []exercise.Exercise {
 	return exercises
 }
 
-func New(id string, createdAt, updatedAt time.Time, name string, languageTag language.Tag, sections []Section) Quiz {
+func New(id string, createdAt, updatedAt time.Time, name string, languageTag language.Tag, status Status, sections []Section) Quiz {
 	return Quiz{
 		ID:          id,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 		Name:        name,
 		LanguageTag: languageTag,
+		Status:      status,
 		Sections:    sections,
 	}
 }
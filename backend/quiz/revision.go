@@ -0,0 +1,48 @@
+package quiz
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrRevisionNotFound = errors.New("quiz revision not found")
+	// ErrNotPublished is returned when a quiz is rolled back while it is not
+	// published.
+	ErrNotPublished = errors.New("quiz is not published")
+)
+
+// Revision is a snapshot of a quiz taken when it is published. Revisions are
+// numbered from 1 per quiz and never change, so that answers can be graded
+// against the content the learner was shown.
+//
+// Learners are shown the current revision of a published or archived quiz,
+// while the sections and exercises of the quiz itself are the draft that the
+// next publish takes a snapshot of.
+type Revision struct {
+	Number    int
+	CreatedAt time.Time
+	// RolledBackFrom is the number of the revision that a rollback copied
+	// into this one, or 0 when the revision was published.
+	RolledBackFrom int
+	Quiz           Quiz
+}
+
+// Attempt is a set of answers to a quiz.
+type Attempt struct {
+	ID        string
+	QuizID    string
+	CreatedAt time.Time
+	// Revision is the number of the revision the answers were graded
+	// against, or 0 when they were given to a draft.
+	Revision int
+	Correct  int
+	Total    int
+}
+
+type CreateAttemptCommand struct {
+	QuizID   string
+	Revision int
+	Correct  int
+	Total    int
+}
@@ -0,0 +1,123 @@
+package quiz
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrNotFound = errors.New("quiz not found")
+
+// Status is where a quiz is in its lifecycle. Only published quizzes are
+// listed. Drafts are work in progress that only admins see. Archived quizzes
+// are retired, but can still be opened, answered and cloned by ID, so that
+// the links learners kept keep working.
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusPublished Status = "published"
+	StatusArchived  Status = "archived"
+)
+
+var Statuses = []Status{StatusDraft, StatusPublished, StatusArchived}
+
+// Public reports whether anyone with its ID can open a quiz with status s.
+func (s Status) Public() bool {
+	return s != StatusDraft
+}
+
+func (s Status) Valid() bool {
+	for _, status := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// transitions lists the statuses a quiz can move to from each status. An
+// archived quiz goes back to being a draft before it is published again.
+var transitions = map[Status][]Status{
+	StatusDraft:     {StatusPublished, StatusArchived},
+	StatusPublished: {StatusDraft, StatusArchived},
+	StatusArchived:  {StatusDraft},
+}
+
+func (s Status) CanMoveTo(to Status) bool {
+	for _, status := range transitions[s] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when a quiz cannot move from its status to
+// another.
+type TransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("a quiz that is %s cannot become %s", e.From, e.To)
+}
+
+// PublishProblem is a reason a quiz cannot be published. Section is the index
+// of the section concerned, or -1 when the problem is with the whole quiz.
+type PublishProblem struct {
+	Section int
+	Message string
+}
+
+// PublishError is returned when a quiz is not ready to be published.
+type PublishError struct {
+	Problems []PublishProblem
+}
+
+func (e *PublishError) Error() string {
+	messages := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		if p.Section < 0 {
+			messages = append(messages, p.Message)
+		} else {
+			messages = append(messages, fmt.Sprintf("section %d: %s", p.Section+1, p.Message))
+		}
+	}
+	return "quiz cannot be published: " + strings.Join(messages, "; ")
+}
+
+// CheckPublishable returns a *PublishError when q has no sections or has a
+// section without exercises.
+func (q *Quiz) CheckPublishable() error {
+	problems := make([]PublishProblem, 0)
+	if len(q.Sections) == 0 {
+		problems = append(problems, PublishProblem{Section: -1, Message: "quiz has no sections"})
+	}
+	for i, s := range q.Sections {
+		if len(s.Exercises) == 0 {
+			problems = append(problems, PublishProblem{Section: i, Message: "section has no exercises"})
+		}
+	}
+	if len(problems) > 0 {
+		return &PublishError{Problems: problems}
+	}
+	return nil
+}
+
+// SetStatus moves q to status. It returns a *TransitionError when q cannot
+// move there from its status, and a *PublishError when it is published
+// without being ready.
+func (q *Quiz) SetStatus(status Status) error {
+	if !q.Status.CanMoveTo(status) {
+		return &TransitionError{From: q.Status, To: status}
+	}
+	if status == StatusPublished {
+		if err := q.CheckPublishable(); err != nil {
+			return err
+		}
+	}
+	q.Status = status
+	return nil
+}
@@ -1,7 +1,46 @@
 package quiz
 
+import "context"
+
 type Storage interface {
-	FindByID(id string) (*Quiz, error)
-	FindQuizzes() ([]Quiz, error)
-	CreateQuiz(cmd CreateQuizCommand) (*Quiz, error)
+	FindByID(ctx context.Context, id string) (*Quiz, error)
+	FindQuizzes(ctx context.Context, filter Filter) ([]Quiz, error)
+	// CreateQuiz creates a draft quiz and moves it to status in the same
+	// transaction, see SetStatus.
+	CreateQuiz(ctx context.Context, cmd CreateQuizCommand, status Status) (*Quiz, error)
+	// CloneQuiz copies the sections and exercises that a quiz shows to
+	// learners into a new quiz with status. Drafts cannot be cloned and are
+	// not found.
+	CloneQuiz(ctx context.Context, cmd CloneQuizCommand, status Status) (*Quiz, error)
+	// SetStatus moves the quiz with id to status, see Quiz.SetStatus.
+	// Publishing a quiz creates a revision.
+	SetStatus(ctx context.Context, id string, status Status) (*Quiz, error)
+	// FindRevisions returns the revisions of a quiz, newest first.
+	FindRevisions(ctx context.Context, quizID string) ([]Revision, error)
+	FindRevision(ctx context.Context, quizID string, number int) (*Revision, error)
+	// Rollback makes a copy of a revision of a published quiz its current
+	// revision. The draft of the quiz is left as it is.
+	Rollback(ctx context.Context, quizID string, number int) (*Quiz, error)
+	CreateAttempt(ctx context.Context, cmd CreateAttemptCommand) (*Attempt, error)
+}
+
+// Filter selects quizzes. An empty Status matches every quiz.
+type Filter struct {
+	Status Status
+}
+
+// StorageError is returned by a Storage when reading or writing fails. It
+// names the quiz and exercise concerned, when they are known.
+type StorageError struct {
+	QuizID     string
+	ExerciseID string
+	Err        error
+}
+
+func (e *StorageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StorageError) Unwrap() error {
+	return e.Err
 }
@@ -0,0 +1,121 @@
+// Package ratelimit limits how often clients may call an operation, with a
+// token bucket per client and rule. A bucket holds up to Burst tokens and
+// refills evenly, so that a full bucket refills in Period. Every request
+// takes a token and is refused when the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Burst requests at once and Burst requests per Period on
+// average. The zero Limit allows everything.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit parses a limit written as burst/period, such as 10/1m. An empty
+// string is the zero Limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+	burst, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q is not a limit like 10/1m", s)
+	}
+	n, err := strconv.Atoi(burst)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("%q is not a limit like 10/1m: the number of requests must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%q is not a limit like 10/1m: the period must be a positive duration", s)
+	}
+	return Limit{Burst: n, Period: d}, nil
+}
+
+func (l Limit) Enabled() bool {
+	return l.Burst > 0
+}
+
+// Rate returns the number of tokens added to a bucket per second.
+func (l Limit) Rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// RetryAfter returns how long a bucket with tokens takes to refill to one
+// token.
+func (l Limit) RetryAfter(tokens float64) time.Duration {
+	return time.Duration((1 - tokens) / l.Rate() * float64(time.Second))
+}
+
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d/%s", l.Burst, l.Period)
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// RetryAfter is how long until the bucket has a token again, when the
+	// request was not allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets.
+type Store interface {
+	// Take takes a token from the bucket with key, which refills at limit.
+	// A bucket that does not exist yet is full.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Rule limits an operation per client IP address.
+type Rule struct {
+	Name  string
+	PerIP Limit
+}
+
+type Limiter struct {
+	store Store
+	rules map[string]Rule
+}
+
+func NewLimiter(store Store, rules ...Rule) *Limiter {
+	l := &Limiter{store: store, rules: make(map[string]Rule)}
+	for _, rule := range rules {
+		l.rules[rule.Name] = rule
+	}
+	return l
+}
+
+// Allow checks a request of the client at ip against the rule with name.
+// Remaining is -1 when the rule does not limit the request.
+//
+// Allow fails open: when the store fails, the request is allowed and the
+// error is logged, so that the limiter does not take the service down with
+// it.
+func (l *Limiter) Allow(ctx context.Context, name, ip string) Result {
+	unlimited := Result{Allowed: true, Remaining: -1}
+	rule, ok := l.rules[name]
+	if !ok || ip == "" || !rule.PerIP.Enabled() {
+		return unlimited
+	}
+
+	result, err := l.store.Take(ctx, rule.Name+":ip:"+ip, rule.PerIP)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to check rate limit", "rule", rule.Name, "error", err.Error())
+		return unlimited
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+// Package report collects problems learners report on single exercises, such
+// as a wrong answer or a typo. Reports are grouped by exercise in an inbox for
+// authors, and an exercise is flagged once it has enough open reports.
+package report
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a report refers to an exercise that does
+	// not exist or is not part of the given quiz.
+	ErrNotFound = errors.New("exercise not found")
+)
+
+// Reason is the kind of problem a learner reports.
+type Reason string
+
+const (
+	ReasonWrongAnswer Reason = "wrongAnswer"
+	ReasonTypo        Reason = "typo"
+	ReasonAmbiguous   Reason = "ambiguous"
+	ReasonOffensive   Reason = "offensive"
+)
+
+// Reasons lists every reason, in the order they are offered to learners.
+var Reasons = []Reason{ReasonWrongAnswer, ReasonTypo, ReasonAmbiguous, ReasonOffensive}
+
+func (r Reason) Valid() bool {
+	for _, reason := range Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+type Status string
+
+const (
+	StatusOpen     Status = "open"
+	StatusResolved Status = "resolved"
+)
+
+type Report struct {
+	ID         string
+	QuizID     string
+	ExerciseID string
+	Reason     Reason
+	Comment    string
+	UserAgent  string
+	Status     Status
+	CreatedAt  time.Time
+	ResolvedAt *time.Time
+}
+
+// ExerciseReports are the reports on one exercise, newest first.
+type ExerciseReports struct {
+	QuizID     string
+	ExerciseID string
+	// FlaggedAt is when the exercise reached the flag threshold, or nil when
+	// it has not since its reports were last resolved.
+	FlaggedAt *time.Time
+	Reports   []Report
+}
+
+// OpenCount returns the number of reports that are not resolved.
+func (e ExerciseReports) OpenCount() int {
+	count := 0
+	for _, r := range e.Reports {
+		if r.Status == StatusOpen {
+			count++
+		}
+	}
+	return count
+}
+
+type Storage interface {
+	// CreateReport stores a report and flags the exercise when it has at
+	// least flagThreshold open reports. flagged is true when this report
+	// flagged the exercise.
+	CreateReport(ctx context.Context, cmd CreateReportCommand, flagThreshold int) (r *Report, flagged bool, err error)
+	// FindInbox lists the exercises with reports matching filter, the one
+	// with the most recent report first.
+	FindInbox(ctx context.Context, filter Filter) ([]ExerciseReports, error)
+	// ResolveReports resolves the open reports on an exercise and clears its
+	// flag.
+	ResolveReports(ctx context.Context, exerciseID string) (*ExerciseReports, error)
+}
+
+type CreateReportCommand struct {
+	QuizID     string
+	ExerciseID string
+	Reason     Reason
+	Comment    string
+	UserAgent  string
+}
+
+// Filter selects the exercises in the inbox. Empty fields match everything.
+// Only reports with Status are included, and only exercises that have one.
+type Filter struct {
+	Status      Status
+	QuizID      string
+	FlaggedOnly bool
+	Limit       int
+	Offset      int
+}